@@ -0,0 +1,87 @@
+package main
+
+import (
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/services"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAdOwnership(t *testing.T) {
+	srv := newTestServer(t)
+	seller := srv.signUp(t, "Seller")
+	stranger := srv.signUp(t, "Stranger")
+	guest := srv.newClient(t)
+
+	adID := seller.createAd("Дом в Лос-Сантосе")
+	adPath := fmt.Sprintf("/api/ads/%d", adID)
+
+	tests := []struct {
+		name   string
+		as     *client
+		method string
+		path   string
+		want   int
+	}{
+		{"guest cannot edit", guest, http.MethodPut, adPath, http.StatusUnauthorized},
+		{"stranger cannot edit", stranger, http.MethodPut, adPath, http.StatusForbidden},
+		{"owner edits", seller, http.MethodPut, adPath, http.StatusOK},
+		{"edit missing ad", seller, http.MethodPut, "/api/ads/999999", http.StatusNotFound},
+		{"stranger cannot mark sold", stranger, http.MethodPost, adPath + "/sold", http.StatusForbidden},
+		{"owner marks sold", seller, http.MethodPost, adPath + "/sold", http.StatusOK},
+		{"stranger cannot delete", stranger, http.MethodDelete, adPath, http.StatusForbidden},
+		{"owner deletes", seller, http.MethodDelete, adPath, http.StatusOK},
+		{"archived ad cannot be renewed", seller, http.MethodPost, adPath + "/renew", http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.as.sendForm(tt.method, tt.path, map[string]string{"title": "Новый заголовок"}, nil)
+			expectStatus(t, rec, tt.want)
+		})
+	}
+
+	var mine struct {
+		Ads []struct {
+			Title string `json:"title"`
+		} `json:"ads"`
+	}
+	decode(t, seller.do(http.MethodGet, "/api/ads/my?status=archived", nil, ""), &mine)
+	if len(mine.Ads) != 1 || mine.Ads[0].Title != "Новый заголовок" {
+		t.Fatalf("archived ads = %+v, want the edited ad", mine.Ads)
+	}
+}
+
+// Правка, начатая до истечения объявления, не должна вернуть его в active
+func TestEditKeepsConcurrentStatusChange(t *testing.T) {
+	srv := newTestServer(t)
+	seller := srv.signUp(t, "Seller")
+	adID := seller.createAd("Дом у моря")
+
+	stale, err := services.Ads.GetOwned(adID, "Seller")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.clock.Advance(models.AdLifetime + time.Minute)
+	if err := services.Ads.ExpireOld(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stale.Title = "Новый заголовок"
+	if err := services.Ads.Edit(stale); !errors.Is(err, services.ErrAdChanged) {
+		t.Fatalf("edit of a stale ad = %v, want ErrAdChanged", err)
+	}
+
+	ad, err := services.Ads.Get(adID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ad.Status != models.AdStatusExpired || ad.Title != "Дом у моря" {
+		t.Fatalf("ad after stale edit: status %q, title %q", ad.Status, ad.Title)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/utils"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthFlow(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.newClient(t)
+	const email = "buyer@example.com"
+
+	var staleRefresh string
+
+	steps := []struct {
+		name string
+		do   func() *httptest.ResponseRecorder
+		want int
+	}{
+		{"register", func() *httptest.ResponseRecorder {
+			return c.postJSON("/api/register", gin.H{"nickname": "Buyer", "email": email, "password": "Secret123"})
+		}, http.StatusOK},
+		{"verify with wrong code", func() *httptest.ResponseRecorder {
+			return c.postJSON("/api/verify-email", gin.H{"email": email, "code": "000000"})
+		}, http.StatusBadRequest},
+		{"verify", func() *httptest.ResponseRecorder {
+			return c.postJSON("/api/verify-email", gin.H{"email": email, "code": srv.lastCode(t, email)})
+		}, http.StatusOK},
+		{"me after verify", func() *httptest.ResponseRecorder {
+			return c.do(http.MethodGet, "/api/me", nil, "")
+		}, http.StatusOK},
+		{"logout", func() *httptest.ResponseRecorder {
+			return c.postJSON("/api/logout", nil)
+		}, http.StatusOK},
+		{"me after logout", func() *httptest.ResponseRecorder {
+			return c.do(http.MethodGet, "/api/me", nil, "")
+		}, http.StatusUnauthorized},
+		{"login with wrong password", func() *httptest.ResponseRecorder {
+			return c.postJSON("/api/login", gin.H{"nickname": "Buyer", "password": "Wrong123"})
+		}, http.StatusUnauthorized},
+		{"login", func() *httptest.ResponseRecorder {
+			return c.postJSON("/api/login", gin.H{"nickname": "Buyer", "password": "Secret123"})
+		}, http.StatusOK},
+		{"refresh", func() *httptest.ResponseRecorder {
+			staleRefresh = c.cookies["refresh_token"]
+			return c.postJSON("/api/refresh", nil)
+		}, http.StatusOK},
+		{"rotated token within grace", func() *httptest.ResponseRecorder {
+			racer := srv.newClient(t)
+			racer.cookies["refresh_token"] = staleRefresh
+			return racer.postJSON("/api/refresh", nil)
+		}, http.StatusOK},
+		{"rotated token after grace", func() *httptest.ResponseRecorder {
+			srv.clock.Advance(models.RefreshReuseGrace + time.Second)
+			thief := srv.newClient(t)
+			thief.cookies["refresh_token"] = staleRefresh
+			return thief.postJSON("/api/refresh", nil)
+		}, http.StatusUnauthorized},
+		{"refresh after reuse revoked the session", func() *httptest.ResponseRecorder {
+			return c.postJSON("/api/refresh", nil)
+		}, http.StatusUnauthorized},
+		{"login again", func() *httptest.ResponseRecorder {
+			return c.postJSON("/api/login", gin.H{"nickname": "Buyer", "password": "Secret123"})
+		}, http.StatusOK},
+		{"logout again", func() *httptest.ResponseRecorder {
+			staleRefresh = c.cookies["refresh_token"]
+			return c.postJSON("/api/logout", nil)
+		}, http.StatusOK},
+		{"refresh after logout", func() *httptest.ResponseRecorder {
+			c.cookies["refresh_token"] = staleRefresh
+			return c.postJSON("/api/refresh", nil)
+		}, http.StatusUnauthorized},
+	}
+
+	for _, step := range steps {
+		if !t.Run(step.name, func(t *testing.T) {
+			expectStatus(t, step.do(), step.want)
+		}) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestVerificationCodeExpires(t *testing.T) {
+	tests := []struct {
+		name    string
+		advance time.Duration
+		want    int
+	}{
+		{"fresh code", 9 * time.Minute, http.StatusOK},
+		{"expired code", 11 * time.Minute, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			c := srv.newClient(t)
+			const email = "late@example.com"
+
+			rec := c.postJSON("/api/register", gin.H{"nickname": "Late", "email": email, "password": "Secret123"})
+			expectStatus(t, rec, http.StatusOK)
+
+			srv.clock.Advance(tt.advance)
+			rec = c.postJSON("/api/verify-email", gin.H{"email": email, "code": srv.lastCode(t, email)})
+			expectStatus(t, rec, tt.want)
+		})
+	}
+}
+
+func TestPasswordResetCodeExpires(t *testing.T) {
+	tests := []struct {
+		name    string
+		advance time.Duration
+		want    int
+	}{
+		{"fresh code", 9 * time.Minute, http.StatusOK},
+		{"expired code", 11 * time.Minute, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			c := srv.signUp(t, "Walker")
+			const email = "Walker@example.com"
+
+			expectStatus(t, c.postJSON("/api/password-reset/request", gin.H{"email": email}), http.StatusOK)
+			code := srv.lastCode(t, email)
+
+			srv.clock.Advance(tt.advance)
+			rec := c.postJSON("/api/password-reset/confirm", gin.H{
+				"email":            email,
+				"code":             code,
+				"new_password":     "Secret456",
+				"confirm_password": "Secret456",
+			})
+			expectStatus(t, rec, tt.want)
+		})
+	}
+}
+
+func TestPasswordResetCooldown(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.signUp(t, "Walker")
+	const email = "Walker@example.com"
+
+	sent := func() int {
+		srv.flushMail(t)
+		count := 0
+		for _, message := range srv.mail.Messages() {
+			if message.To == email {
+				count++
+			}
+		}
+		return count
+	}
+	before := sent()
+
+	expectStatus(t, c.postJSON("/api/password-reset/request", gin.H{"email": email}), http.StatusOK)
+	srv.clock.Advance(30 * time.Second)
+	expectStatus(t, c.postJSON("/api/password-reset/request", gin.H{"email": email}), http.StatusOK)
+	if got := sent() - before; got != 1 {
+		t.Fatalf("mails within cooldown = %d, want 1", got)
+	}
+
+	srv.clock.Advance(models.PasswordResetCooldown)
+	expectStatus(t, c.postJSON("/api/password-reset/request", gin.H{"email": email}), http.StatusOK)
+	if got := sent() - before; got != 2 {
+		t.Fatalf("mails after cooldown = %d, want 2", got)
+	}
+}
+
+func TestTwoFactorChallengeBurnsAfterFailedCodes(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.signUp(t, "Guarded")
+
+	totp := func(secret string) string {
+		code, err := utils.GenerateTOTP(secret, srv.clock.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	var setup struct {
+		Secret string `json:"secret"`
+	}
+	decode(t, c.postJSON("/api/2fa/setup", nil), &setup)
+	expectStatus(t, c.postJSON("/api/2fa/confirm", gin.H{"code": totp(setup.Secret)}), http.StatusOK)
+
+	login := func() string {
+		var challenge struct {
+			Status         string `json:"status"`
+			ChallengeToken string `json:"challenge_token"`
+		}
+		decode(t, c.postJSON("/api/login", gin.H{"nickname": "Guarded", "password": "Secret123"}), &challenge)
+		if challenge.Status != "2fa_required" || challenge.ChallengeToken == "" {
+			t.Fatalf("login = %+v, want a 2FA challenge", challenge)
+		}
+		return challenge.ChallengeToken
+	}
+
+	// Код из следующего окна: код подтверждения уже использован и повторно не примется
+	srv.clock.Advance(30 * time.Second)
+	valid := totp(setup.Secret)
+	wrong := fmt.Sprintf("%06d", (mustAtoi(t, valid)+1)%1000000)
+
+	// Лимитер входа считает по IP, поэтому каждый код шлем с нового адреса: проверяется именно лимит challenge
+	attempt := func(token string, code string) *httptest.ResponseRecorder {
+		return srv.newClient(t).postJSON("/api/login/2fa", gin.H{"challenge_token": token, "code": code})
+	}
+
+	burned := login()
+	for want := models.TwoFactorMaxAttempts - 1; want > 0; want-- {
+		rec := attempt(burned, wrong)
+		expectStatus(t, rec, http.StatusUnauthorized)
+		var body struct {
+			AttemptsLeft int `json:"attempts_left"`
+		}
+		decodeBody(t, rec, &body)
+		if body.AttemptsLeft != want {
+			t.Fatalf("attempts left = %d, want %d", body.AttemptsLeft, want)
+		}
+	}
+
+	tooMany := func(rec *httptest.ResponseRecorder) {
+		t.Helper()
+		expectStatus(t, rec, http.StatusUnauthorized)
+		var body struct {
+			Error string `json:"error"`
+		}
+		decodeBody(t, rec, &body)
+		if body.Error != "Слишком много неверных кодов, войдите заново" {
+			t.Fatalf("error = %q", body.Error)
+		}
+	}
+	tooMany(attempt(burned, wrong))
+
+	// Сгоревший challenge не принимается даже с верным кодом: до проверки кода дело не доходит
+	tooMany(attempt(burned, valid))
+
+	// Новый вход с паролем дает новый challenge со всеми попытками
+	expectStatus(t, c.postJSON("/api/login/2fa", gin.H{"challenge_token": login(), "code": valid}), http.StatusOK)
+	expectStatus(t, c.do(http.MethodGet, "/api/me", nil, ""), http.StatusOK)
+}
+
+func mustAtoi(t *testing.T, s string) int {
+	t.Helper()
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
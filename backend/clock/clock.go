@@ -0,0 +1,44 @@
+// Package clock - откуда сервисы берут текущее время. В main это системные часы,
+// в тестах - Fake, который стоит на месте, пока его не подвинут
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// System - обычные часы
+var System Clock = systemClock{}
+
+// Fake - часы, которые показывают заданное время. Безопасны для конкурентного использования
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance переводит часы вперед на d
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.mu.Unlock()
+}
@@ -0,0 +1,398 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+
+	// Секреты, с которыми можно поднять проект локально. В production с ними не стартуем
+	DevJWTSecret        = "your-secret-key-change-in-production"
+	DevJWTRefreshSecret = "your-refresh-secret-key-change-in-production"
+
+	minSecretLength = 32
+)
+
+// Значения-заглушки из .env.example и старых версий кода
+var insecureSecrets = map[string]bool{
+	DevJWTSecret:        true,
+	DevJWTRefreshSecret: true,
+	"your-super-secret-jwt-key-change-this-in-production":         true,
+	"your-super-secret-refresh-jwt-key-change-this-in-production": true,
+}
+
+// Config - вся конфигурация бэкенда. Читается один раз при старте через Load
+// и дальше явно передается подсистемам
+type Config struct {
+	Env       string          `yaml:"env"`
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	Storage   StorageConfig   `yaml:"storage"`
+	SMTP      SMTPConfig      `yaml:"smtp"`
+	Recaptcha RecaptchaConfig `yaml:"recaptcha"`
+	Log       LogConfig       `yaml:"log"`
+}
+
+type ServerConfig struct {
+	Port          string   `yaml:"port"`
+	AppURL        string   `yaml:"app_url"` // публичный адрес бэкенда, из него собираются ссылки в письмах
+	CORSOrigins   []string `yaml:"cors_origins"`
+	SecureCookies *bool    `yaml:"secure_cookies"` // по умолчанию true в production
+	// MetricsToken закрывает /metrics: Prometheus шлет его как Bearer токен. Пустой - без проверки
+	MetricsToken string `yaml:"metrics_token"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+}
+
+type JWTConfig struct {
+	Secret        string `yaml:"secret"`
+	RefreshSecret string `yaml:"refresh_secret"`
+}
+
+type StorageConfig struct {
+	Backend   string   `yaml:"backend"` // local или s3. Не задан - s3 при наличии ключей AWS, иначе local
+	LocalDir  string   `yaml:"local_dir"`
+	PublicURL string   `yaml:"public_url"`
+	S3        S3Config `yaml:"s3"`
+}
+
+type S3Config struct {
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	Endpoint        string `yaml:"endpoint"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+	FromName string `yaml:"from_name"`
+	TestMode bool   `yaml:"test_mode"` // письма не отправляются, а печатаются в лог
+	Dir      string `yaml:"dir"`       // письма складываются файлами в эту папку вместо SMTP
+}
+
+type RecaptchaConfig struct {
+	SecretKey string `yaml:"secret_key"` // пусто - проверка reCAPTCHA выключена
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn, error
+	Format string `yaml:"format"` // text или json, по умолчанию json в production
+	// UserContent - писать в лог то, что прислал пользователь (заголовки и тексты объявлений).
+	// Только для отладки, по умолчанию выключено
+	UserContent bool `yaml:"user_content"`
+}
+
+// Load собирает конфигурацию: значения по умолчанию, потом YAML файл (CONFIG_FILE или
+// config.yaml, если он есть), потом переменные окружения и .env. Окружение важнее файла
+func Load() (*Config, error) {
+	if err := godotenv.Load(".env"); err != nil {
+		if err := godotenv.Load("../.env"); err != nil {
+			slog.Warn("Файл .env не найден, берем переменные окружения")
+		}
+	}
+
+	cfg := defaults()
+
+	path, required := os.Getenv("CONFIG_FILE"), true
+	if path == "" {
+		path, required = "config.yaml", false
+	}
+	if err := cfg.loadFile(path, required); err != nil {
+		return nil, err
+	}
+
+	cfg.loadEnv()
+	cfg.applyFallbacks()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func defaults() *Config {
+	return &Config{
+		Env: EnvDevelopment,
+		Server: ServerConfig{
+			Port:   "8080",
+			AppURL: "http://localhost:8080",
+			CORSOrigins: []string{
+				"http://localhost:5173", "http://localhost:3000", "http://localhost:3001", "http://localhost:3002",
+				"http://127.0.0.1:5173", "http://127.0.0.1:3000", "http://127.0.0.1:3001", "http://127.0.0.1:3002",
+			},
+		},
+		Database: DatabaseConfig{
+			Host:    "localhost",
+			Port:    "5432",
+			SSLMode: "disable",
+		},
+		Storage: StorageConfig{
+			LocalDir: "./uploads",
+		},
+		SMTP: SMTPConfig{
+			FromName: "Arizona Games Store",
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+	}
+}
+
+func (c *Config) loadFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !required {
+			return nil
+		}
+		return fmt.Errorf("read config file %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	slog.Info("Конфигурация загружена", "file", path)
+	return nil
+}
+
+func (c *Config) loadEnv() {
+	vars := map[string]*string{
+		"APP_ENV":               &c.Env,
+		"PORT":                  &c.Server.Port,
+		"APP_URL":               &c.Server.AppURL,
+		"METRICS_TOKEN":         &c.Server.MetricsToken,
+		"DB_HOST":               &c.Database.Host,
+		"DB_PORT":               &c.Database.Port,
+		"DB_USER":               &c.Database.User,
+		"DB_PASSWORD":           &c.Database.Password,
+		"DB_NAME":               &c.Database.Name,
+		"DB_SSLMODE":            &c.Database.SSLMode,
+		"JWT_SECRET":            &c.JWT.Secret,
+		"JWT_REFRESH_SECRET":    &c.JWT.RefreshSecret,
+		"STORAGE_BACKEND":       &c.Storage.Backend,
+		"STORAGE_LOCAL_DIR":     &c.Storage.LocalDir,
+		"STORAGE_PUBLIC_URL":    &c.Storage.PublicURL,
+		"AWS_ACCESS_KEY_ID":     &c.Storage.S3.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY": &c.Storage.S3.SecretAccessKey,
+		"AWS_REGION":            &c.Storage.S3.Region,
+		"AWS_S3_BUCKET":         &c.Storage.S3.Bucket,
+		"AWS_ENDPOINT_URL":      &c.Storage.S3.Endpoint,
+		"SMTP_HOST":             &c.SMTP.Host,
+		"SMTP_PORT":             &c.SMTP.Port,
+		"SMTP_USERNAME":         &c.SMTP.Username,
+		"SMTP_PASSWORD":         &c.SMTP.Password,
+		"SMTP_FROM":             &c.SMTP.From,
+		"SMTP_FROM_NAME":        &c.SMTP.FromName,
+		"EMAIL_DIR":             &c.SMTP.Dir,
+		"RECAPTCHA_SECRET_KEY":  &c.Recaptcha.SecretKey,
+		"LOG_LEVEL":             &c.Log.Level,
+		"LOG_FORMAT":            &c.Log.Format,
+	}
+	for name, dst := range vars {
+		if value, ok := os.LookupEnv(name); ok && value != "" {
+			*dst = value
+		}
+	}
+
+	if value := os.Getenv("CORS_ORIGINS"); value != "" {
+		c.Server.CORSOrigins = splitList(value)
+	}
+	if value, err := strconv.ParseBool(os.Getenv("COOKIE_SECURE")); err == nil {
+		c.Server.SecureCookies = &value
+	}
+	if value, err := strconv.ParseBool(os.Getenv("EMAIL_TEST_MODE")); err == nil {
+		c.SMTP.TestMode = value
+	}
+	if value, err := strconv.ParseBool(os.Getenv("LOG_USER_CONTENT")); err == nil {
+		c.Log.UserContent = value
+	}
+}
+
+// applyFallbacks заполняет то, что зависит от других полей
+func (c *Config) applyFallbacks() {
+	c.Env = strings.ToLower(strings.TrimSpace(c.Env))
+	c.Server.Port = strings.TrimPrefix(c.Server.Port, ":")
+
+	c.Log.Level = strings.ToLower(strings.TrimSpace(c.Log.Level))
+	c.Log.Format = strings.ToLower(strings.TrimSpace(c.Log.Format))
+	if c.Log.Format == "" {
+		c.Log.Format = "text"
+		if c.IsProduction() {
+			c.Log.Format = "json"
+		}
+	}
+
+	if c.Server.SecureCookies == nil {
+		secure := c.IsProduction()
+		c.Server.SecureCookies = &secure
+	}
+
+	if c.Storage.Backend == "" {
+		if c.Storage.S3.AccessKeyID != "" && c.Storage.S3.Bucket != "" {
+			c.Storage.Backend = "s3"
+		} else {
+			c.Storage.Backend = "local"
+		}
+	}
+	// Для S3 пустой адрес значит "ссылки прямо на бакет", для диска раздаем сами через /uploads
+	if c.Storage.PublicURL == "" && c.Storage.Backend == "local" {
+		c.Storage.PublicURL = strings.TrimRight(c.Server.AppURL, "/") + "/uploads"
+	}
+
+	if c.SMTP.Username == "" {
+		c.SMTP.Username = c.SMTP.From
+	}
+
+	// Локально можно без секретов, в production Validate такое не пропустит
+	if !c.IsProduction() {
+		if c.JWT.Secret == "" {
+			slog.Warn("JWT_SECRET не задан, используется секрет для разработки")
+			c.JWT.Secret = DevJWTSecret
+		}
+		if c.JWT.RefreshSecret == "" {
+			slog.Warn("JWT_REFRESH_SECRET не задан, используется секрет для разработки")
+			c.JWT.RefreshSecret = DevJWTRefreshSecret
+		}
+	}
+}
+
+// Validate проверяет обязательные ключи. В production дополнительно не пускает
+// дефолтные и слишком короткие секреты
+func (c *Config) Validate() error {
+	var problems []string
+
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		problems = append(problems, fmt.Sprintf("APP_ENV must be %q or %q, got %q", EnvDevelopment, EnvProduction, c.Env))
+	}
+	if _, err := strconv.ParseUint(c.Server.Port, 10, 16); err != nil {
+		problems = append(problems, fmt.Sprintf("PORT %q is not a valid port", c.Server.Port))
+	}
+	if _, err := url.ParseRequestURI(c.Server.AppURL); err != nil {
+		problems = append(problems, fmt.Sprintf("APP_URL %q is not a valid URL", c.Server.AppURL))
+	}
+
+	for _, required := range []struct{ name, value string }{
+		{"DB_HOST", c.Database.Host},
+		{"DB_PORT", c.Database.Port},
+		{"DB_USER", c.Database.User},
+		{"DB_NAME", c.Database.Name},
+	} {
+		if required.value == "" {
+			problems = append(problems, required.name+" is required")
+		}
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL must be debug, info, warn or error, got %q", c.Log.Level))
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		problems = append(problems, fmt.Sprintf("LOG_FORMAT must be text or json, got %q", c.Log.Format))
+	}
+
+	switch c.Storage.Backend {
+	case "local":
+	case "s3":
+		if c.Storage.S3.Bucket == "" {
+			problems = append(problems, "AWS_S3_BUCKET is required for STORAGE_BACKEND=s3")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unknown STORAGE_BACKEND %q", c.Storage.Backend))
+	}
+
+	if c.IsProduction() {
+		for _, secret := range []struct{ name, value string }{
+			{"JWT_SECRET", c.JWT.Secret},
+			{"JWT_REFRESH_SECRET", c.JWT.RefreshSecret},
+		} {
+			switch {
+			case secret.value == "":
+				problems = append(problems, secret.name+" is required in production")
+			case insecureSecrets[secret.value]:
+				problems = append(problems, secret.name+" is a default value, set a real secret in production")
+			case len(secret.value) < minSecretLength:
+				problems = append(problems, fmt.Sprintf("%s must be at least %d characters in production", secret.name, minSecretLength))
+			}
+		}
+		if c.JWT.Secret != "" && c.JWT.Secret == c.JWT.RefreshSecret {
+			problems = append(problems, "JWT_SECRET and JWT_REFRESH_SECRET must differ in production")
+		}
+		if c.SMTP.TestMode {
+			problems = append(problems, "EMAIL_TEST_MODE must be off in production")
+		}
+		if c.SMTP.Dir != "" {
+			problems = append(problems, "EMAIL_DIR must be empty in production, emails would never leave the server")
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+func (c *Config) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// Addr - адрес для http.Server, например ":8080"
+func (s ServerConfig) Addr() string {
+	return ":" + s.Port
+}
+
+// DSN - строка подключения для gorm
+func (d DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+		dsnValue(d.Host), dsnValue(d.User), dsnValue(d.Password), dsnValue(d.Name), dsnValue(d.Port), dsnValue(d.SSLMode))
+}
+
+// dsnValue берет значение в кавычки, иначе пароль с пробелом ломает строку подключения
+func dsnValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// URL - то же подключение в виде URL, его ждет golang-migrate
+func (d DatabaseConfig) URL() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     d.Host + ":" + d.Port,
+		Path:     "/" + d.Name,
+		RawQuery: "sslmode=" + url.QueryEscape(d.SSLMode),
+	}
+	return u.String()
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"arizonagamesstore/backend/config"
+	"arizonagamesstore/backend/migrations"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+const migrateUsage = `usage: migrate <command>
+  up [N]          применить все новые миграции или только N следующих
+  down [N|all]    откатить N последних миграций (по умолчанию одну) или все
+  status          текущая версия и список миграций
+  force VERSION   записать версию без выполнения SQL (после упавшей миграции)`
+
+// RunMigrations накатывает новые миграции при старте сервера. Если прошлая миграция
+// упала посередине, сервер не стартует: сначала надо поправить базу и сделать migrate force
+func RunMigrations(cfg config.DatabaseConfig) {
+	m, _, err := newMigrate(cfg)
+	if err != nil {
+		slog.Error("Не удалось подготовить миграции", "error", err)
+		os.Exit(1)
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			slog.Info("Новых миграций нет")
+			return
+		}
+		var dirty migrate.ErrDirty
+		if errors.As(err, &dirty) {
+			slog.Error("База в состоянии dirty: поправь схему руками и выполни `go run . migrate force <version>`", "version", dirty.Version)
+			os.Exit(1)
+		}
+		slog.Error("Миграция не применилась", "error", err)
+		os.Exit(1)
+	}
+
+	version, _, _ := m.Version()
+	slog.Info("Миграции применены", "version", version)
+}
+
+// RunMigrateCommand - подкоманда `migrate`: работает со схемой без запуска HTTP сервера.
+// args - все, что после слова migrate
+func RunMigrateCommand(cfg config.DatabaseConfig, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	m, src, err := newMigrate(cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	command, rest := args[0], args[1:]
+	switch command {
+	case "up":
+		if len(rest) == 0 {
+			err = m.Up()
+		} else {
+			n, convErr := strconv.Atoi(rest[0])
+			if convErr != nil || n <= 0 {
+				return fmt.Errorf("migrate up: N must be a positive number, got %q", rest[0])
+			}
+			err = m.Steps(n)
+		}
+	case "down":
+		switch {
+		case len(rest) == 0:
+			err = m.Steps(-1)
+		case rest[0] == "all":
+			err = m.Down()
+		default:
+			n, convErr := strconv.Atoi(rest[0])
+			if convErr != nil || n <= 0 {
+				return fmt.Errorf("migrate down: N must be a positive number or all, got %q", rest[0])
+			}
+			err = m.Steps(-n)
+		}
+	case "force":
+		if len(rest) != 1 {
+			return errors.New("migrate force: VERSION is required")
+		}
+		version, convErr := strconv.Atoi(rest[0])
+		if convErr != nil {
+			return fmt.Errorf("migrate force: invalid version %q", rest[0])
+		}
+		err = m.Force(version)
+	case "status":
+		return printMigrationStatus(m, src)
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", command, migrateUsage)
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("Нечего применять, схема актуальна")
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	return printMigrationStatus(m, src)
+}
+
+func newMigrate(cfg config.DatabaseConfig) (*migrate.Migrate, source.Driver, error) {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, nil, fmt.Errorf("read embedded migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, cfg.URL())
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect to database: %w", err)
+	}
+	return m, src, nil
+}
+
+// printMigrationStatus выводит текущую версию и все миграции с отметкой, применена ли она
+func printMigrationStatus(m *migrate.Migrate, src source.Driver) error {
+	current, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, migrate.ErrNilVersion):
+		fmt.Println("Версия схемы: нет (миграции еще не применялись)")
+	case dirty:
+		fmt.Printf("Версия схемы: %d (dirty - миграция упала, нужен migrate force)\n", current)
+	default:
+		fmt.Printf("Версия схемы: %d\n", current)
+	}
+
+	version, err := src.First()
+	for err == nil {
+		_, identifier, readErr := src.ReadUp(version)
+		if readErr != nil {
+			return readErr
+		}
+
+		mark := " "
+		if current > 0 && version <= current {
+			mark = "x"
+		}
+		fmt.Printf("  [%s] %06d %s\n", mark, version, identifier)
+
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
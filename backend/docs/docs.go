@@ -22,110 +22,43 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/ads": {
-            "get": {
-                "description": "Возвращает список объявлений с фильтрацией и сортировкой. По умолчанию возвращает 20 штук, можно подгружать дальше через offset",
+        "/2fa/backup-codes": {
+            "post": {
+                "description": "Выдает новые 10 резервных кодов, старые сразу перестают работать. Нужен код из приложения",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Объявления"
+                    "2FA"
                 ],
-                "summary": "Список объявлений",
+                "summary": "Новые резервные коды",
                 "parameters": [
                     {
-                        "enum": [
-                            "house",
-                            "business",
-                            "vehicle",
-                            "security",
-                            "accs",
-                            "others"
-                        ],
-                        "type": "string",
-                        "description": "Категория",
-                        "name": "category",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Фильтр по серверу",
-                        "name": "server",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Сколько объявлений вернуть (по умолчанию 20)",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Сколько пропустить для пагинации (по умолчанию 0)",
-                        "name": "offset",
-                        "in": "query"
-                    },
-                    {
-                        "enum": [
-                            "date_desc",
-                            "date_asc",
-                            "price_desc",
-                            "price_asc",
-                            "views_desc"
-                        ],
-                        "type": "string",
-                        "description": "Сортировка",
-                        "name": "sort",
-                        "in": "query"
-                    },
-                    {
-                        "enum": [
-                            "Продать",
-                            "Купить",
-                            "Сдать в аренду"
-                        ],
-                        "type": "string",
-                        "description": "Фильтр по типу",
-                        "name": "type",
-                        "in": "query"
-                    },
-                    {
-                        "enum": [
-                            "VC",
-                            "$",
-                            "BTC",
-                            "EURO",
-                            "Договорная"
-                        ],
-                        "type": "string",
-                        "description": "Фильтр по валюте",
-                        "name": "currency",
-                        "in": "query"
-                    },
-                    {
-                        "type": "number",
-                        "description": "Минимальная цена",
-                        "name": "price_min",
-                        "in": "query"
-                    },
-                    {
-                        "type": "number",
-                        "description": "Максимальная цена",
-                        "name": "price_max",
-                        "in": "query"
+                        "description": "Код из приложения",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Список объявлений",
+                        "description": "Новые резервные коды",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Не указана категория",
+                        "description": "Неверный код или 2FA не включена",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -133,38 +66,26 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Ошибка БД",
+                    "401": {
+                        "description": "Не авторизован",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
-            }
-        },
-        "/ads/random": {
-            "get": {
-                "description": "Возвращает 8 случайных объявлений для главной страницы. Каждый раз разные!",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Объявления"
-                ],
-                "summary": "Случайные объявления",
-                "responses": {
-                    "200": {
-                        "description": "Массив случайных объявлений",
+                    },
+                    "429": {
+                        "description": "Слишком много попыток",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
-                        "description": "Что-то пошло не так",
+                        "description": "Ошибка БД",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -172,17 +93,17 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            }
-        },
-        "/ads/{id}": {
-            "put": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Обновляет данные объявления. Доступно только автору объявления",
+                ]
+            }
+        },
+        "/2fa/confirm": {
+            "post": {
+                "description": "Вводишь код из приложения, и 2FA включается. В ответе 10 резервных кодов - они показываются один раз, сохрани их. Каждый код одноразовый",
                 "consumes": [
                     "application/json"
                 ],
@@ -190,38 +111,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Объявления"
+                    "2FA"
                 ],
-                "summary": "Обновить объявление",
+                "summary": "Подтвердить подключение 2FA",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID объявления",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Новые данные",
+                        "description": "Код из приложения",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Объявление обновлено!",
+                        "description": "2FA включена, резервные коды",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Некорректные данные",
+                        "description": "Неверный код или настройка не начата",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -238,8 +154,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "Это не твое объявление!",
+                    "409": {
+                        "description": "2FA уже включена",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -247,8 +163,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "Объявление не найдено",
+                    "429": {
+                        "description": "Слишком много попыток",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -257,7 +173,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Ошибка обновления",
+                        "description": "Ошибка БД",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -265,34 +181,44 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            },
-            "delete": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
+                ]
+            }
+        },
+        "/2fa/disable": {
+            "post": {
+                "description": "Выключает двухфакторку. Нужны пароль и код из приложения (или резервный код), чтобы угонщик с одной только сессией не мог ее снять",
+                "consumes": [
+                    "application/json"
                 ],
-                "description": "Удаляет объявление. Доступно только автору",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Объявления"
+                    "2FA"
                 ],
-                "summary": "Удалить объявление",
+                "summary": "Выключить 2FA",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID объявления",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Пароль и код",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Объявление удалено!",
+                        "description": "2FA выключена",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -300,8 +226,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Не авторизован",
+                    "400": {
+                        "description": "Неверный код или 2FA не включена",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -309,8 +235,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "Это не твое объявление!",
+                    "401": {
+                        "description": "Неверный пароль",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -318,8 +244,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "Объявление не найдено",
+                    "429": {
+                        "description": "Слишком много попыток",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -328,7 +254,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Ошибка удаления",
+                        "description": "Ошибка БД",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -336,31 +262,33 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/ads/{id}/view": {
+        "/2fa/setup": {
             "post": {
-                "description": "Увеличивает счетчик просмотров объявления. Вызывай когда пользователь открывает карточку объявления",
+                "description": "Выдает секрет и otpauth:// ссылку для QR кода. Сканируешь в Google Authenticator, Aegis или любом другом приложении, потом подтверждаешь кодом через /2fa/confirm. До подтверждения вход работает как раньше",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Объявления"
-                ],
-                "summary": "Записать просмотр",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID объявления",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "2FA"
                 ],
+                "summary": "Подключить 2FA",
                 "responses": {
                     "200": {
-                        "description": "Просмотр засчитан!",
+                        "description": "Секрет и ссылка для QR",
+                        "schema": {
+                            "$ref": "#/definitions/services.TwoFactorSetup"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -368,8 +296,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "Объявление не найдено",
+                    "409": {
+                        "description": "2FA уже включена",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -378,7 +306,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Ошибка обновления",
+                        "description": "Ошибка БД",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -386,110 +314,2936 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/createnewads": {
-            "post": {
-                "description": "Создает новое объявление с картинкой. Картинка загружается на AWS S3. После создания объявление автоматически удалится через 48 часов (можно продлить). Между созданиями объявлений нужно ждать 60 секунд",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+        "/2fa/status": {
+            "get": {
+                "description": "Включена ли двухфакторка и сколько резервных кодов осталось",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Объявления"
+                    "2FA"
                 ],
-                "summary": "Создать объявление",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Сервер (ViceCity, Phoenix, и т.д.)",
-                        "name": "server",
-                        "in": "formData",
-                        "required": true
+                "summary": "Статус 2FA",
+                "responses": {
+                    "200": {
+                        "description": "Статус 2FA",
+                        "schema": {
+                            "$ref": "#/definitions/services.TwoFactorStatus"
+                        }
                     },
-                    {
-                        "type": "string",
-                        "description": "Название (макс. 25 символов)",
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/admin/ads/{id}": {
+            "delete": {
+                "description": "Убирает объявление в архив и закрывает все открытые жалобы на него. Из базы объявление не удаляется, чтобы не пропали отзывы о продавце. Вернуть его продавец не сможет. Решение остается в журнале модерации",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Модерация"
+                ],
+                "summary": "Удалить объявление (модерация)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Комментарий модератора",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Объявление удалено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/admin/ads/{id}/hide": {
+            "post": {
+                "description": "Скрывает объявление из ленты и закрывает все открытые жалобы на него",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Модерация"
+                ],
+                "summary": "Скрыть объявление",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Комментарий модератора",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Объявление скрыто",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/admin/ads/{id}/reports": {
+            "get": {
+                "description": "Возвращает все жалобы на конкретное объявление и историю решений по нему",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Модерация"
+                ],
+                "summary": "Жалобы на объявление",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Жалобы и решения",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/admin/ads/{id}/warn": {
+            "post": {
+                "description": "Выносит предупреждение автору объявления и закрывает все открытые жалобы на него. Объявление остается в ленте",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Модерация"
+                ],
+                "summary": "Предупредить автора",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Комментарий модератора",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Предупреждение вынесено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/admin/decisions": {
+            "get": {
+                "description": "Возвращает историю решений модераторов: кто, что и когда сделал",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Модерация"
+                ],
+                "summary": "Журнал модерации",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Фильтр по объявлению",
+                        "name": "ad_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по модератору",
+                        "name": "moderator",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько вернуть (по умолчанию 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько пропустить (по умолчанию 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список решений",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/admin/jobs": {
+            "get": {
+                "description": "Состояние задач планировщика: расписание, когда запускались последний раз, сколько длились, последняя ошибка и когда следующий запуск. Только для админов",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Модерация"
+                ],
+                "summary": "Фоновые задачи",
+                "responses": {
+                    "200": {
+                        "description": "Список задач",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/admin/reports": {
+            "get": {
+                "description": "Возвращает жалобы, сгруппированные по объявлениям: сколько жалоб и с какими причинами. Сверху самые \"горячие\" объявления. Только для модераторов и админов",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Модерация"
+                ],
+                "summary": "Очередь жалоб",
+                "parameters": [
+                    {
+                        "enum": [
+                            "pending",
+                            "dismissed",
+                            "resolved"
+                        ],
+                        "type": "string",
+                        "description": "Статус жалоб (по умолчанию pending)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько объявлений вернуть (по умолчанию 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько пропустить (по умолчанию 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Очередь модерации",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/admin/reports/{id}/conversations": {
+            "get": {
+                "description": "Возвращает всю переписку покупателей с продавцом по объявлению, на которое пожаловались. Нужна для разбора спорных ситуаций",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Модерация"
+                ],
+                "summary": "Переписка по жалобе",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID жалобы",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Жалоба и переписки",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Жалоба не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/admin/reports/{id}/dismiss": {
+            "post": {
+                "description": "Отклоняет жалобу как необоснованную. Объявление не трогается",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Модерация"
+                ],
+                "summary": "Отклонить жалобу",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID жалобы",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Комментарий модератора",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Жалоба отклонена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Жалоба не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Жалоба уже рассмотрена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/admin/users/{nickname}/role": {
+            "put": {
+                "description": "Назначает пользователю роль User, Moderator или Admin. Только для админов. Новая роль начнет действовать после обновления access токена (до 3 минут)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Модерация"
+                ],
+                "summary": "Изменить роль пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Никнейм пользователя",
+                        "name": "nickname",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новая роль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Роль обновлена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неизвестная роль или попытка поменять роль самому себе",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка обновления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/ads": {
+            "get": {
+                "description": "Возвращает список активных объявлений с фильтрацией и сортировкой. По умолчанию возвращает 20 штук. Следующая страница - по page.next_cursor, предыдущая - по page.prev_cursor, новые объявления страницы не сдвигают. Общее количество в page.total и заголовках X-Total-Count/Content-Range",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Объявления"
+                ],
+                "summary": "Список объявлений",
+                "parameters": [
+                    {
+                        "enum": [
+                            "house",
+                            "business",
+                            "vehicle",
+                            "security",
+                            "accs",
+                            "others"
+                        ],
+                        "type": "string",
+                        "description": "Категория",
+                        "name": "category",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по серверу",
+                        "name": "server",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько объявлений вернуть (по умолчанию 20, максимум 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор из page.next_cursor или page.prev_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Устарело, используй cursor. Сколько пропустить (по умолчанию 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "date_desc",
+                            "date_asc",
+                            "price_desc",
+                            "price_asc",
+                            "views_desc"
+                        ],
+                        "type": "string",
+                        "description": "Сортировка",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "Продать",
+                            "Купить",
+                            "Сдать в аренду"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по типу",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "VC",
+                            "$",
+                            "BTC",
+                            "EURO",
+                            "Договорная"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по валюте",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Минимальная цена",
+                        "name": "price_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Максимальная цена",
+                        "name": "price_max",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список объявлений и курсоры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Не указана категория или битый курсор",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ads/my": {
+            "get": {
+                "description": "Возвращает все объявления текущего пользователя, включая черновики, просроченные и скрытые модерацией. Архив показывается только при status=archived",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Объявления"
+                ],
+                "summary": "Мои объявления",
+                "parameters": [
+                    {
+                        "enum": [
+                            "draft",
+                            "active",
+                            "expired",
+                            "sold",
+                            "hidden",
+                            "archived"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по статусу",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список объявлений",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/ads/random": {
+            "get": {
+                "description": "Возвращает активные объявления для главной страницы в случайном порядке. Порядок задает page.seed: дальше листай по page.next_cursor и объявления не повторятся. Тот же seed в параметре вернет ту же ленту, без него каждый раз новая",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Объявления"
+                ],
+                "summary": "Случайные объявления",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Сколько объявлений вернуть (по умолчанию 15, максимум 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор из page.next_cursor или page.prev_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Seed перемешивания из page.seed",
+                        "name": "seed",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Массив случайных объявлений",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Битый курсор",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Что-то пошло не так",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ads/search": {
+            "get": {
+                "description": "Полнотекстовый поиск по названию и описанию активных объявлений во всех категориях. Понимает русскую морфологию (\"дома\" найдет \"дом\"), кавычки для точной фразы, OR и минус для исключения слов. Совпадения подсвечиваются тегом \u003cmark\u003e, остальной текст экранирован",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Объявления"
+                ],
+                "summary": "Поиск объявлений",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Что ищем (минимум 2 символа)",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "house",
+                            "business",
+                            "vehicle",
+                            "security",
+                            "accs",
+                            "others"
+                        ],
+                        "type": "string",
+                        "description": "Ограничить категорией",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по серверу",
+                        "name": "server",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько объявлений вернуть (по умолчанию 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько пропустить для пагинации (по умолчанию 0)",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "relevance",
+                            "date_desc",
+                            "date_asc",
+                            "price_desc",
+                            "price_asc",
+                            "views_desc"
+                        ],
+                        "type": "string",
+                        "description": "Сортировка (по умолчанию по релевантности)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "Продать",
+                            "Купить",
+                            "Сдать в аренду"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по типу",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "VC",
+                            "$",
+                            "BTC",
+                            "EURO",
+                            "Договорная"
+                        ],
+                        "type": "string",
+                        "description": "Фильтр по валюте",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Минимальная цена",
+                        "name": "price_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Максимальная цена",
+                        "name": "price_max",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Найденные объявления и общее количество",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Слишком короткий запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ads/{id}": {
+            "put": {
+                "description": "Обновляет данные объявления. Доступно только автору объявления",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Объявления"
+                ],
+                "summary": "Обновить объявление",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новые данные",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Объявление обновлено!",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные данные",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Это не твое объявление!",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Статус объявления поменялся во время правки",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка обновления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "delete": {
+                "description": "Убирает объявление в архив. Из ленты и профиля оно пропадает, но отзывы по нему сохраняются. Доступно только автору",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Объявления"
+                ],
+                "summary": "Удалить объявление",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Объявление удалено!",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Это не твое объявление!",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Объявление скрыто модерацией или уже в архиве",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка удаления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/ads/{id}/conversations": {
+            "post": {
+                "description": "Открывает переписку с продавцом по объявлению. Если переписка уже есть, вернется она же. Можно сразу передать первое сообщение",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Сообщения"
+                ],
+                "summary": "Написать продавцу",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Первое сообщение",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Переписка уже была",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "201": {
+                        "description": "Переписка создана",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Свое объявление или пустое сообщение",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Продавец ограничил сообщения",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Объявление неактивно",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много сообщений",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/ads/{id}/renew": {
+            "post": {
+                "description": "Продлевает просроченное объявление еще на 48 часов и поднимает его наверх ленты. Этим же запросом публикуется черновик. Доступно только автору",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Объявления"
+                ],
+                "summary": "Продлить объявление",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Объявление снова в ленте",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Это не твое объявление!",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Продлить можно только просроченное объявление или черновик",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка продления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/ads/{id}/sold": {
+            "post": {
+                "description": "Помечает объявление проданным. Из ленты оно пропадает, но остается в профиле продавца. Доступно только автору",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Объявления"
+                ],
+                "summary": "Отметить как проданное",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поздравляем с продажей!",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Это не твое объявление!",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Продать можно только активное или просроченное объявление",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка обновления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/ads/{id}/view": {
+            "post": {
+                "description": "Увеличивает счетчик просмотров объявления. Вызывай когда пользователь открывает карточку объявления",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Объявления"
+                ],
+                "summary": "Записать просмотр",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID объявления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Просмотр засчитан!",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка обновления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/blocks": {
+            "get": {
+                "description": "Возвращает пользователей, которым запрещено писать тебе",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Сообщения"
+                ],
+                "summary": "Черный список",
+                "responses": {
+                    "200": {
+                        "description": "Черный список",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/blocks/{nickname}": {
+            "post": {
+                "description": "Запрещает пользователю писать тебе. Ты ему писать тоже не сможешь, пока не разблокируешь",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Сообщения"
+                ],
+                "summary": "Заблокировать пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Никнейм пользователя",
+                        "name": "nickname",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пользователь заблокирован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Нельзя заблокировать себя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Уже заблокирован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "delete": {
+                "description": "Убирает пользователя из черного списка",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Сообщения"
+                ],
+                "summary": "Разблокировать пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Никнейм пользователя",
+                        "name": "nickname",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пользователь разблокирован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не в черном списке",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/conversations": {
+            "get": {
+                "description": "Возвращает все переписки пользователя (и как покупателя, и как продавца) с последним сообщением и количеством непрочитанных",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Сообщения"
+                ],
+                "summary": "Мои переписки",
+                "responses": {
+                    "200": {
+                        "description": "Список переписок и общее число непрочитанных",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/conversations/{id}/messages": {
+            "get": {
+                "description": "Возвращает сообщения переписки от старых к новым. Для подгрузки истории передай before_id = ID самого старого загруженного сообщения",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Сообщения"
+                ],
+                "summary": "Сообщения переписки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID переписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько сообщений вернуть (по умолчанию 50, максимум 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Вернуть сообщения старше этого ID",
+                        "name": "before_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Переписка и сообщения",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Переписка не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "post": {
+                "description": "Отправляет сообщение в переписку. Не больше 20 сообщений в минуту",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Сообщения"
+                ],
+                "summary": "Отправить сообщение",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID переписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Текст сообщения",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Сообщение отправлено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Пустое или слишком длинное сообщение",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Собеседник ограничил сообщения",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Переписка не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много сообщений",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/conversations/{id}/read": {
+            "post": {
+                "description": "Помечает прочитанными все входящие сообщения в переписке",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Сообщения"
+                ],
+                "summary": "Прочитать переписку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID переписки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сколько сообщений помечено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Переписка не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/createnewads": {
+            "post": {
+                "description": "Создает новое объявление с картинкой. Картинка проверяется по содержимому (JPEG/PNG/GIF/WebP, от 300x200 до 1920x1080), перекодируется в JPEG без EXIF и GPS и загружается в файловое хранилище вместе с превью image_thumb и image_medium. Через 48 часов объявление уходит в статус expired и пропадает из ленты, его можно продлить через /ads/{id}/renew. С draft=true объявление сохраняется черновиком и публикуется тем же renew. Между созданиями объявлений нужно ждать 60 секунд",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Объявления"
+                ],
+                "summary": "Создать объявление",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Сервер (ViceCity, Phoenix, и т.д.)",
+                        "name": "server",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название (макс. 25 символов)",
                         "name": "title",
                         "in": "formData",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Описание (макс. 500 символов)",
-                        "name": "description",
-                        "in": "formData",
+                        "description": "Описание (макс. 500 символов)",
+                        "name": "description",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тип (Продать/Купить/Сдать в аренду)",
+                        "name": "type",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Валюта (VC/$/BTC/EURO/Договорная)",
+                        "name": "currency",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Цена",
+                        "name": "price",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Категория (house/business/vehicle/security/accs/others)",
+                        "name": "category",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Никнейм автора",
+                        "name": "nickname",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Изображение (макс. 10MB, разрешение 300x200 - 1920x1080)",
+                        "name": "image",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Лимит часов аренды (1-180)",
+                        "name": "rentalHoursLimit",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Сохранить как черновик, не публикуя",
+                        "name": "draft",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Объявление создано! ID: 42",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Не хватает данных, файл не картинка или не то разрешение",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Картинка слишком большая (макс. 10MB)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Подожди 60 секунд перед созданием нового объявления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка загрузки картинки или БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/email-change/undo": {
+            "get": {
+                "description": "Ссылка из письма, которое приходит на старый адрес после смены. Возвращает старый email и завершает все сессии: если email менял не ты, угонщика выкинет",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Профиль"
+                ],
+                "summary": "Отменить смену email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен из ссылки",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Старый email возвращен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Ссылка неверная или истекла",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Старый email уже занят другим аккаунтом",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка обновления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/events": {
+            "get": {
+                "description": "Server-Sent Events для залогиненного пользователя: новый отзыв ждет подтверждения, отзыв подтвержден, решение по жалобе на объявление, объявление скоро истечет, новое сообщение. Подключаться через EventSource с withCredentials. Раз в 3 минуты сервер присылает reconnect и закрывает поток - EventSource переподключится сам",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "События"
+                ],
+                "summary": "Поток событий",
+                "responses": {
+                    "200": {
+                        "description": "Поток событий",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/favorites": {
+            "get": {
+                "description": "Возвращает избранные объявления пользователя. Если цена изменилась после добавления, в price_change будут старая и новая цена, а dropped=true значит, что стало дешевле",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Избранное"
+                ],
+                "summary": "Избранное",
+                "responses": {
+                    "200": {
+                        "description": "Список избранного",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка загрузки",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            },
+            "post": {
+                "description": "Добавляет объявление в избранное. Запоминается текущая цена, чтобы потом показать \"цена снизилась с X до Y\"",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Избранное"
+                ],
+                "summary": "Добавить в избранное",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID объявления",
+                        "name": "ad_id",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Добавлено в избранное",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Не указан ID или это свое объявление",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нужна авторизация",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Уже в избранном",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сохранения",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/favorites/{ad_id}": {
+            "delete": {
+                "description": "Удаляет объявление из избранного",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Избранное"
+                ],
+                "summary": "Убрать из избранного",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID объявления",
+                        "name": "ad_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Убрано из избранного",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нужна авторизация",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Этого объявления нет в избранном",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка удаления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/feedback": {
+            "post": {
+                "description": "Оставляет отзыв о продавце. Рейтинг от 1 до 5 звезд. Отзыв нужно подтвердить продавцу, чтобы он отобразился",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Отзывы"
+                ],
+                "summary": "Оставить отзыв",
+                "parameters": [
+                    {
+                        "description": "Данные отзыва",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Отзыв отправлен! Ждем подтверждения продавца",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный рейтинг или комментарий",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нужна авторизация",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Ты уже оставлял отзыв этому продавцу",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка сохранения",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/feedback/{id}/confirm": {
+            "put": {
+                "description": "Подтверждает отзыв. Только продавец может подтвердить отзыв о себе. Рейтинг и число успешных сделок пересчитываются в той же транзакции",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Отзывы"
+                ],
+                "summary": "Подтвердить отзыв",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID отзыва",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Отзыв подтвержден! Твой рейтинг обновлен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Ты не можешь подтвердить этот отзыв",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Отзыв не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Отзыв уже подтвержден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка подтверждения",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/feedback/{nickname}": {
+            "get": {
+                "description": "Возвращает подтвержденные отзывы о продавце, сверху новые. По 20 штук, дальше по курсору из page",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Отзывы"
+                ],
+                "summary": "Отзывы продавца",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Никнейм продавца",
+                        "name": "nickname",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Тип (Продать/Купить/Сдать в аренду)",
-                        "name": "type",
-                        "in": "formData",
-                        "required": true
+                        "type": "integer",
+                        "description": "Сколько отзывов вернуть (по умолчанию 20, максимум 100)",
+                        "name": "limit",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Валюта (VC/$/BTC/EURO/Договорная)",
-                        "name": "currency",
-                        "in": "formData",
-                        "required": true
+                        "description": "Курсор из page.next_cursor или page.prev_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список отзывов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Битый курсор",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     },
+                    "500": {
+                        "description": "Ошибка загрузки",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/feedback/{nickname}/reputation": {
+            "get": {
+                "description": "Рейтинг продавца и из чего он складывается. Рейтинг - взвешенное среднее: к отзывам подмешано\nнесколько оценок 4.0, старые отзывы со временем весят меньше, а отзывы опытных продавцов больше.\nall_time и last_30_days - сколько подтвержденных отзывов с каждой оценкой и их обычное среднее",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Отзывы"
+                ],
+                "summary": "Репутация продавца",
+                "parameters": [
                     {
-                        "type": "number",
-                        "description": "Цена",
-                        "name": "price",
-                        "in": "formData",
+                        "type": "string",
+                        "description": "Никнейм продавца",
+                        "name": "nickname",
+                        "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Репутация",
+                        "schema": {
+                            "$ref": "#/definitions/services.ReputationBreakdown"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     },
+                    "500": {
+                        "description": "Ошибка загрузки",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/getadcount": {
+            "get": {
+                "description": "Возвращает количество объявлений в категории. Нужно для отображения \"Всего объявлений: 420\"",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Объявления"
+                ],
+                "summary": "Счетчик объявлений",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Категория (house/business/vehicle/security/accs/others)",
-                        "name": "category",
-                        "in": "formData",
+                        "description": "Название категории",
+                        "name": "CategoryName",
+                        "in": "query",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Количество объявлений",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
                     },
+                    "500": {
+                        "description": "Ошибка подсчета",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/listings/user/{nickname}": {
+            "get": {
+                "description": "Возвращает объявления конкретного пользователя, по 20 штук. По умолчанию активные и проданные. Листается курсорами из page, как и общая лента",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Объявления"
+                ],
+                "summary": "Объявления по нику",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Никнейм автора",
+                        "description": "Никнейм пользователя",
                         "name": "nickname",
-                        "in": "formData",
+                        "in": "path",
                         "required": true
                     },
                     {
+                        "enum": [
+                            "active",
+                            "sold"
+                        ],
                         "type": "string",
-                        "description": "Путь для сохранения картинки",
-                        "name": "imagePath",
-                        "in": "formData",
-                        "required": true
+                        "description": "Фильтр по статусу",
+                        "name": "status",
+                        "in": "query"
                     },
                     {
-                        "type": "file",
-                        "description": "Изображение (макс. 10MB, разрешение 300x200 - 1920x1080)",
-                        "name": "image",
-                        "in": "formData",
-                        "required": true
+                        "type": "integer",
+                        "description": "Сколько объявлений вернуть (по умолчанию 20, максимум 100)",
+                        "name": "limit",
+                        "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Лимит часов аренды (1-180)",
-                        "name": "rentalHoursLimit",
-                        "in": "formData"
+                        "type": "string",
+                        "description": "Курсор из page.next_cursor или page.prev_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список объявлений пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Недопустимый статус или битый курсор",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Авторизация пользователя. Возвращает JWT токены (access для запросов + refresh для продления сессии). Токены сохраняются в HTTP-only cookies для безопасности. Если у аккаунта включена 2FA, cookies не ставятся: в ответе status=2fa_required и challenge_token для /login/2fa",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
+                ],
+                "summary": "Вход в аккаунт",
+                "parameters": [
+                    {
+                        "description": "Никнейм и пароль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/services.LoginRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Объявление создано! ID: 42",
+                        "description": "Авторизация успешна! Добро пожаловать обратно",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Не хватает данных или картинка кривая",
+                        "description": "Не хватает данных (никнейм или пароль)",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -497,8 +3251,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "413": {
-                        "description": "Картинка слишком большая (макс. 10MB)",
+                    "401": {
+                        "description": "Неверный никнейм или пароль, попробуй еще раз",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -507,7 +3261,7 @@ const docTemplate = `{
                         }
                     },
                     "429": {
-                        "description": "Подожди 60 секунд перед созданием нового объявления",
+                        "description": "Слишком много попыток входа, подожди немного",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -516,7 +3270,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Ошибка загрузки на S3 или БД",
+                        "description": "Ошибка сервера при генерации токенов",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -527,14 +3281,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/feedback": {
+        "/login/2fa": {
             "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Оставляет отзыв о продавце. Рейтинг от 1 до 5 звезд. Отзыв нужно подтвердить продавцу, чтобы он отобразился",
+                "description": "Если /login ответил status=2fa_required, сюда отправляешь challenge_token оттуда и код из приложения (6 цифр) или один из резервных кодов. Challenge живет 5 минут, после 5 неверных кодов сгорает и нужно снова войти с паролем. После успеха ставятся cookies, как при обычном входе",
                 "consumes": [
                     "application/json"
                 ],
@@ -542,31 +3291,30 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Отзывы"
+                    "Аутентификация"
                 ],
-                "summary": "Оставить отзыв",
+                "summary": "Второй шаг входа (2FA)",
                 "parameters": [
                     {
-                        "description": "Данные отзыва",
+                        "description": "Challenge токен и код",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/services.LoginTwoFactorRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Отзыв отправлен! Ждем подтверждения продавца",
+                        "description": "Авторизация успешна",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Некорректный рейтинг или комментарий",
+                        "description": "Не хватает данных",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -575,16 +3323,14 @@ const docTemplate = `{
                         }
                     },
                     "401": {
-                        "description": "Нужна авторизация",
+                        "description": "Неверный код (attempts_left - сколько попыток осталось), challenge истек или сгорел",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
-                    "409": {
-                        "description": "Ты уже оставлял отзыв этому продавцу",
+                    "429": {
+                        "description": "Слишком много попыток",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -593,7 +3339,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Ошибка сохранения",
+                        "description": "Ошибка сервера",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -604,38 +3350,65 @@ const docTemplate = `{
                 }
             }
         },
-        "/feedback/{id}/confirm": {
-            "put": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
+        "/logout": {
+            "post": {
+                "description": "Выход из аккаунта. Завершает текущую сессию (все ее refresh токены) и чистит cookies. После этого все запросы будут отклонены, пока не залогинишься заново",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Аутентификация"
                 ],
-                "description": "Подтверждает отзыв. Только продавец может подтвердить отзыв о себе. После подтверждения рейтинг пересчитывается",
+                "summary": "Выход",
+                "responses": {
+                    "200": {
+                        "description": "Успешный выход! До встречи",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications": {
+            "get": {
+                "description": "Возвращает уведомления пользователя, сверху новые: отзывы, решения по жалобам, истекающие объявления и т.д. Общее количество в заголовке X-Total-Count и в поле total",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Отзывы"
+                    "Уведомления"
                 ],
-                "summary": "Подтвердить отзыв",
+                "summary": "Уведомления",
                 "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Только непрочитанные",
+                        "name": "unread",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "ID отзыва",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Сколько вернуть (по умолчанию 20, максимум 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Сколько пропустить (по умолчанию 0)",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Отзыв подтвержден! Твой рейтинг обновлен",
+                        "description": "Список уведомлений",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     },
                     "401": {
@@ -647,17 +3420,43 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "Ты не можешь подтвердить этот отзыв",
+                    "500": {
+                        "description": "Ошибка БД",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/notifications/read-all": {
+            "post": {
+                "description": "Помечает прочитанными все уведомления пользователя",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Прочитать все уведомления",
+                "responses": {
+                    "200": {
+                        "description": "Сколько уведомлений помечено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
                     },
-                    "404": {
-                        "description": "Отзыв не найден",
+                    "401": {
+                        "description": "Не авторизован",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -666,7 +3465,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Ошибка подтверждения",
+                        "description": "Ошибка БД",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -674,38 +3473,45 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/feedback/{nickname}": {
+        "/notifications/unread-count": {
             "get": {
-                "description": "Возвращает все отзывы о продавце",
+                "description": "Возвращает количество непрочитанных уведомлений - для красной точки на колокольчике",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Отзывы"
-                ],
-                "summary": "Отзывы продавца",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Никнейм продавца",
-                        "name": "nickname",
-                        "in": "path",
-                        "required": true
-                    }
+                    "Уведомления"
                 ],
+                "summary": "Счетчик непрочитанных уведомлений",
                 "responses": {
                     "200": {
-                        "description": "Список отзывов",
+                        "description": "Количество непрочитанных",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
-                        "description": "Ошибка загрузки",
+                        "description": "Ошибка БД",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -713,40 +3519,63 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/getadcount": {
-            "get": {
-                "description": "Возвращает количество объявлений в категории. Нужно для отображения \"Всего объявлений: 420\"",
+        "/notifications/{id}/read": {
+            "post": {
+                "description": "Помечает одно уведомление прочитанным",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Объявления"
+                    "Уведомления"
                 ],
-                "summary": "Счетчик объявлений",
+                "summary": "Прочитать уведомление",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Название категории",
-                        "name": "CategoryName",
-                        "in": "query",
+                        "type": "integer",
+                        "description": "ID уведомления",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Количество объявлений",
+                        "description": "Уведомление прочитано",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "integer"
+                                "type": "string"
                             }
                         }
                     },
-                    "500": {
-                        "description": "Ошибка подсчета",
+                    "400": {
+                        "description": "Неверный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Уведомление не найдено",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -754,38 +3583,68 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/listings/user/{nickname}": {
-            "get": {
-                "description": "Возвращает все объявления конкретного пользователя",
+        "/password-reset/confirm": {
+            "post": {
+                "description": "Меняет пароль по коду из письма. На один код дается 5 попыток, потом нужно запросить новый. После сброса все сессии завершаются, войти придется заново",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Объявления"
+                    "Аутентификация"
                 ],
-                "summary": "Объявления по нику",
+                "summary": "Сбросить пароль по коду",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Никнейм пользователя",
-                        "name": "nickname",
-                        "in": "path",
-                        "required": true
+                        "description": "Email, код и новый пароль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/services.PasswordResetConfirmRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Список объявлений пользователя",
+                        "description": "Пароль изменен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный код, он истек или пароль не подходит",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много попыток",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
-                        "description": "Ошибка БД",
+                        "description": "Ошибка смены пароля",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -796,9 +3655,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/login": {
+        "/password-reset/request": {
             "post": {
-                "description": "Авторизация пользователя. Возвращает JWT токены (access для запросов + refresh для продления сессии). Токены сохраняются в HTTP-only cookies для безопасности",
+                "description": "Отправляет на почту код для сброса пароля. Ответ всегда одинаковый, чтобы нельзя было проверить, есть ли такой email у нас. Код живет 10 минут, новый можно запросить раз в минуту",
                 "consumes": [
                     "application/json"
                 ],
@@ -808,28 +3667,21 @@ const docTemplate = `{
                 "tags": [
                     "Аутентификация"
                 ],
-                "summary": "Вход в аккаунт",
+                "summary": "Забыл пароль",
                 "parameters": [
                     {
-                        "description": "Никнейм и пароль",
+                        "description": "Email аккаунта",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/services.LoginRequest"
+                            "$ref": "#/definitions/services.PasswordResetRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Авторизация успешна! Добро пожаловать обратно",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "400": {
-                        "description": "Не хватает данных (никнейм или пароль)",
+                        "description": "Если такой email есть, код уже в пути",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -837,8 +3689,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Неверный никнейм или пароль, попробуй еще раз",
+                    "400": {
+                        "description": "Неверный формат email",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -847,7 +3699,7 @@ const docTemplate = `{
                         }
                     },
                     "429": {
-                        "description": "Слишком много попыток входа, подожди немного",
+                        "description": "Слишком много попыток",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -856,7 +3708,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Ошибка сервера при генерации токенов",
+                        "description": "Ошибка отправки письма",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -867,19 +3719,72 @@ const docTemplate = `{
                 }
             }
         },
-        "/logout": {
+        "/profile/confirm-email": {
             "post": {
-                "description": "Выход из аккаунта. Удаляет refresh токен из БД и чистит cookies. После этого все запросы будут отклонены, пока не залогинишься заново",
+                "description": "Вводишь код, который пришел на новый адрес, и email меняется. На старый адрес уходит письмо со ссылкой отмены, она работает 7 дней. На код 5 попыток",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Аутентификация"
+                    "Профиль"
+                ],
+                "summary": "Подтвердить новый email",
+                "parameters": [
+                    {
+                        "description": "Код из письма",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
                 ],
-                "summary": "Выход",
                 "responses": {
                     "200": {
-                        "description": "Успешный выход! До встречи",
+                        "description": "Email изменен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный код, он истек или email уже занят",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много попыток",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка обновления",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -887,16 +3792,16 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            }
-        },
-        "/profile/delete-background": {
-            "delete": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
+                ]
+            }
+        },
+        "/profile/delete-background": {
+            "delete": {
                 "description": "Удаляет фон профиля",
                 "produces": [
                     "application/json"
@@ -933,17 +3838,17 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            }
-        },
-        "/profile/update-avatar": {
-            "post": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Загружает новый аватар на S3. Макс. размер 5MB",
+                ]
+            }
+        },
+        "/profile/update-avatar": {
+            "post": {
+                "description": "Загружает новый аватар в файловое хранилище. Макс. размер 5MB",
                 "consumes": [
                     "multipart/form-data"
                 ],
@@ -1000,17 +3905,17 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            }
-        },
-        "/profile/update-background": {
-            "post": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Загружает новый фон для профиля на S3. Макс. размер 10MB",
+                ]
+            }
+        },
+        "/profile/update-background": {
+            "post": {
+                "description": "Загружает новый фон для профиля в файловое хранилище. Макс. размер 10MB",
                 "consumes": [
                     "multipart/form-data"
                 ],
@@ -1059,7 +3964,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Ошибка загрузки на S3",
+                        "description": "Ошибка загрузки в хранилище",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1067,16 +3972,16 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            }
-        },
-        "/profile/update-description": {
-            "put": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
+                ]
+            }
+        },
+        "/profile/update-description": {
+            "put": {
                 "description": "Обновляет описание профиля. Макс. 500 символов",
                 "consumes": [
                     "application/json"
@@ -1139,17 +4044,17 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            }
-        },
-        "/profile/update-email": {
-            "put": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Изменяет email пользователя. После изменения нужно будет заново подтвердить email",
+                ]
+            }
+        },
+        "/profile/update-email": {
+            "put": {
+                "description": "Отправляет код на новый email. Адрес поменяется только после подтверждения кода через /profile/confirm-email, код живет 10 минут",
                 "consumes": [
                     "application/json"
                 ],
@@ -1176,7 +4081,7 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Email обновлен! Проверь почту для подтверждения",
+                        "description": "Код отправлен на новый email",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1220,16 +4125,16 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            }
-        },
-        "/profile/update-nickname": {
-            "put": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
+                ]
+            }
+        },
+        "/profile/update-nickname": {
+            "put": {
                 "description": "Изменяет никнейм пользователя. Макс. 20 символов",
                 "consumes": [
                     "application/json"
@@ -1301,17 +4206,17 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            }
-        },
-        "/profile/update-password": {
-            "put": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Изменяет пароль пользователя. Нужно ввести старый пароль для подтверждения",
+                ]
+            }
+        },
+        "/profile/update-password": {
+            "put": {
+                "description": "Изменяет пароль пользователя. Нужно ввести старый пароль для подтверждения. Все сессии, кроме текущей, завершаются",
                 "consumes": [
                     "application/json"
                 ],
@@ -1373,16 +4278,16 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            }
-        },
-        "/profile/update-telegram": {
-            "put": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
+                ]
+            }
+        },
+        "/profile/update-telegram": {
+            "put": {
                 "description": "Обновляет Telegram контакт. Макс. 50 символов",
                 "consumes": [
                     "application/json"
@@ -1445,16 +4350,16 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            }
-        },
-        "/profile/update-theme": {
-            "put": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
+                ]
+            }
+        },
+        "/profile/update-theme": {
+            "put": {
                 "description": "Меняет тему оформления (светлая/темная)",
                 "consumes": [
                     "application/json"
@@ -1508,12 +4413,17 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
         "/refresh": {
             "post": {
-                "description": "Обновляет access токен используя refresh токен. Вызывай этот эндпоинт когда access токен истек (обычно через 3 минуты). Refresh токен живет 30 дней",
+                "description": "Обновляет access токен используя refresh токен. Вызывай этот эндпоинт когда access токен истек (обычно через 3 минуты). Refresh токен каждый раз меняется на новый и живет 30 дней с последнего обновления. Если предъявить уже замененный токен, сессия считается украденной и завершается целиком",
                 "produces": [
                     "application/json"
                 ],
@@ -1602,8 +4512,82 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "429": {
-                        "description": "Слишком много попыток, подожди немного",
+                    "429": {
+                        "description": "Слишком много попыток, подожди немного",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Что-то сломалось на сервере, пишите в поддержку",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/reports": {
+            "post": {
+                "description": "Отправляет жалобу на объявление. Причины: Мошенничество, Спам, Порнография, и т.д. Жалобы проверяются модераторами",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Жалобы"
+                ],
+                "summary": "Пожаловаться на объявление",
+                "parameters": [
+                    {
+                        "description": "Данные жалобы",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Жалоба отправлена! Мы её рассмотрим",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Не указана причина или описание слишком короткое",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Залогинься чтобы жаловаться",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Объявление не найдено",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1612,7 +4596,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Что-то сломалось на сервере, пишите в поддержку",
+                        "description": "Ошибка создания жалобы",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1620,17 +4604,17 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            }
-        },
-        "/reports": {
-            "post": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
-                "description": "Отправляет жалобу на объявление. Причины: Мошенничество, Спам, Порнография, и т.д. Жалобы проверяются модераторами",
+                ]
+            }
+        },
+        "/resend-code": {
+            "post": {
+                "description": "Отправляет новый код подтверждения на email. Нужно если предыдущий код истек (они живут 10 минут) или потерялся. Генерирует новый код, письмо уходит в течение нескольких секунд",
                 "consumes": [
                     "application/json"
                 ],
@@ -1638,24 +4622,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Жалобы"
+                    "Аутентификация"
                 ],
-                "summary": "Пожаловаться на объявление",
+                "summary": "Отправить код повторно",
                 "parameters": [
                     {
-                        "description": "Данные жалобы",
+                        "description": "Email для отправки нового кода",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/services.ResendCodeRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Жалоба отправлена! Мы её рассмотрим",
+                        "description": "Новый код отправлен! Проверь почту (и спам тоже)",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1664,7 +4647,7 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Не указана причина или описание слишком короткое",
+                        "description": "Email не найден или уже подтвержден",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1672,17 +4655,38 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Залогинься чтобы жаловаться",
+                    "500": {
+                        "description": "Ошибка отправки email",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
+                    }
+                }
+            }
+        },
+        "/sessions": {
+            "get": {
+                "description": "Возвращает все устройства, где выполнен вход: браузер, IP, когда заходили и когда последний раз пользовались. Текущее устройство помечено current",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Сессии"
+                ],
+                "summary": "Активные сессии",
+                "responses": {
+                    "200": {
+                        "description": "Список сессий",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
                     },
-                    "404": {
-                        "description": "Объявление не найдено",
+                    "401": {
+                        "description": "Не авторизован",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1691,7 +4695,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Ошибка создания жалобы",
+                        "description": "Ошибка БД",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1699,36 +4703,80 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
-        "/resend-code": {
+        "/sessions/revoke-all": {
             "post": {
-                "description": "Отправляет новый код подтверждения на email. Нужно если предыдущий код истек (они живут 10 минут) или потерялся. Генерирует новый код и сразу отправляет на почту",
-                "consumes": [
+                "description": "Завершает все сессии пользователя, включая текущую, и чистит cookies. Пригодится если потерял телефон или заходил с чужого компа",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "Сессии"
+                ],
+                "summary": "Выйти везде",
+                "responses": {
+                    "200": {
+                        "description": "Сколько сессий завершено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка БД",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
+            }
+        },
+        "/sessions/{id}": {
+            "delete": {
+                "description": "Выходит из аккаунта на выбранном устройстве. Там уже выданный access токен доживет свои 3 минуты, дальше попросит войти заново. Если завершить текущую сессию - это обычный выход",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Аутентификация"
+                    "Сессии"
                 ],
-                "summary": "Отправить код повторно",
+                "summary": "Завершить сессию",
                 "parameters": [
                     {
-                        "description": "Email для отправки нового кода",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/services.ResendCodeRequest"
-                        }
+                        "type": "string",
+                        "description": "ID сессии из списка сессий",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Новый код отправлен! Проверь почту (и спам тоже)",
+                        "description": "Сессия завершена",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1737,7 +4785,25 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Email не найден или уже подтвержден",
+                        "description": "Неверный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия не найдена",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1746,7 +4812,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Ошибка отправки email",
+                        "description": "Ошибка БД",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -1754,7 +4820,12 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         },
         "/verify-email": {
@@ -1821,12 +4892,7 @@ const docTemplate = `{
         },
         "/viewed-ads": {
             "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Возвращает историю просмотренных объявлений пользователя",
+                "description": "Возвращает историю просмотренных объявлений пользователя, сверху последние. По 20 штук, дальше по курсору из page",
                 "produces": [
                     "application/json"
                 ],
@@ -1834,6 +4900,20 @@ const docTemplate = `{
                     "Просмотренное"
                 ],
                 "summary": "История просмотров",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Сколько вернуть (по умолчанию 20, максимум 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор из page.next_cursor или page.prev_cursor",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "Список просмотренных объявлений",
@@ -1842,6 +4922,15 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "400": {
+                        "description": "Битый курсор",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "401": {
                         "description": "Не авторизован",
                         "schema": {
@@ -1860,14 +4949,14 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
-            },
-            "post": {
+                },
                 "security": [
                     {
                         "BearerAuth": []
                     }
-                ],
+                ]
+            },
+            "post": {
                 "description": "Добавляет объявление в историю просмотров пользователя",
                 "consumes": [
                     "application/json"
@@ -1930,7 +5019,12 @@ const docTemplate = `{
                             }
                         }
                     }
-                }
+                },
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ]
             }
         }
     },
@@ -1956,6 +5050,58 @@ const docTemplate = `{
                 }
             }
         },
+        "services.LoginTwoFactorRequest": {
+            "type": "object",
+            "required": [
+                "challenge_token",
+                "code"
+            ],
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
+                },
+                "client_ip": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.PasswordResetConfirmRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "confirm_password",
+                "email",
+                "new_password"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "confirm_password": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "new_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.PasswordResetRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
         "services.RegisterRequest": {
             "type": "object",
             "required": [
@@ -1979,6 +5125,43 @@ const docTemplate = `{
                 }
             }
         },
+        "services.ReputationBreakdown": {
+            "type": "object",
+            "properties": {
+                "all_time": {
+                    "$ref": "#/definitions/services.ReputationStars"
+                },
+                "last_30_days": {
+                    "$ref": "#/definitions/services.ReputationStars"
+                },
+                "nickname": {
+                    "type": "string"
+                },
+                "rating": {
+                    "type": "number"
+                },
+                "success_transactions": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.ReputationStars": {
+            "type": "object",
+            "properties": {
+                "average": {
+                    "type": "number"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "stars": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
         "services.ResendCodeRequest": {
             "type": "object",
             "required": [
@@ -1990,6 +5173,34 @@ const docTemplate = `{
                 }
             }
         },
+        "services.TwoFactorSetup": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "type": "string"
+                },
+                "uri": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.TwoFactorStatus": {
+            "type": "object",
+            "properties": {
+                "backup_codes_left": {
+                    "type": "integer"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "enabled_at": {
+                    "type": "string"
+                },
+                "setup_in_progress": {
+                    "type": "boolean"
+                }
+            }
+        },
         "services.VerifyEmailRequest": {
             "type": "object",
             "required": [
@@ -2026,7 +5237,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/api",
 	Schemes:          []string{"http", "https"},
 	Title:            "Arizona Games Store API",
-	Description:      "API для игрового маркетплейса Arizona RP. Здесь можно купить/продать/арендовать дома, бизнесы, транспорт и всякую другую всячину. Работает на 33 серверах, поддерживает несколько валют и умеет в рейтинги продавцов.\n\nОсновные фишки:\n- JWT авторизация (access + refresh токены)\n- Rate limiting чтобы боты не спамили\n- Email верификация\n- Загрузка картинок в AWS S3\n- Система отзывов и рейтингов\n- Жалобы на объявления\n- Автоудаление старых объявлений через 48 часов\n\nСделано с душой и большим количеством кофе ☕",
+	Description:      "API для игрового маркетплейса Arizona RP. Здесь можно купить/продать/арендовать дома, бизнесы, транспорт и всякую другую всячину. Работает на 33 серверах, поддерживает несколько валют и умеет в рейтинги продавцов.\n\nОсновные фишки:\n- JWT авторизация (access + refresh токены) и роли User/Moderator/Admin\n- Rate limiting чтобы боты не спамили\n- Email верификация\n- Полнотекстовый поиск по объявлениям с русской морфологией\n- Загрузка картинок в S3 или на локальный диск (STORAGE_BACKEND)\n- Система отзывов и рейтингов\n- Переписка покупателя с продавцом прямо на сайте\n- Центр уведомлений + push через Server-Sent Events (/api/events)\n- Жалобы на объявления и очередь модерации\n- Объявления живут 48 часов, потом уходят в просроченные (можно продлить)\n\nСделано с душой и большим количеством кофе ☕",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Типы событий, которые уходят пользователю в /api/events
+const (
+	TypeFeedbackPending   = "feedback.pending"
+	TypeFeedbackConfirmed = "feedback.confirmed"
+	TypeReportVerdict     = "report.verdict"
+	TypeReportResolved    = "report.resolved"
+	TypeAdExpiring        = "ad.expiring"
+	TypeAdExpired         = "ad.expired"
+	TypeMessageNew        = "message.new"
+)
+
+type Event struct {
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Broker раздает события подписчикам. Сейчас это Hub в памяти процесса, но
+// интерфейс рассчитан на то, что Publish уйдет в Postgres NOTIFY, а каждый
+// инстанс будет слушать LISTEN и раздавать события своим подключениям
+type Broker interface {
+	Publish(nickname string, event Event) error
+	Subscribe(nickname string) (<-chan Event, func())
+}
+
+var (
+	brokerMu sync.RWMutex
+	broker   Broker = NewHub()
+)
+
+// SetBroker подменяет брокер по умолчанию
+func SetBroker(b Broker) {
+	brokerMu.Lock()
+	defer brokerMu.Unlock()
+	broker = b
+}
+
+func currentBroker() Broker {
+	brokerMu.RLock()
+	defer brokerMu.RUnlock()
+	return broker
+}
+
+// Publish отправляет событие пользователю. Ошибка только логируется:
+// событие - это подсказка фронту, основное действие уже выполнено
+func Publish(nickname string, eventType string, data interface{}) {
+	event := Event{
+		Type:      eventType,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+
+	if err := currentBroker().Publish(nickname, event); err != nil {
+		slog.Error("Ошибка отправки события", "type", eventType, "user", nickname, "error", err)
+	}
+}
+
+// Subscribe подписывает на события пользователя. Вторым значением
+// возвращается функция отписки, ее нужно вызвать при закрытии соединения
+func Subscribe(nickname string) (<-chan Event, func()) {
+	return currentBroker().Subscribe(nickname)
+}
@@ -0,0 +1,61 @@
+package events
+
+import "sync"
+
+// subscriberBuffer - сколько событий может ждать отправки в одно соединение.
+// Если клиент не успевает читать, лишние события выбрасываются
+const subscriberBuffer = 16
+
+// Hub - брокер в памяти процесса. У одного пользователя может быть
+// несколько вкладок, поэтому подписчиков на ник может быть несколько
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+func (h *Hub) Publish(nickname string, event Event) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subscribers[nickname] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (h *Hub) Subscribe(nickname string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[nickname] == nil {
+		h.subscribers[nickname] = make(map[chan Event]struct{})
+	}
+	h.subscribers[nickname][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+
+			delete(h.subscribers[nickname], ch)
+			if len(h.subscribers[nickname]) == 0 {
+				delete(h.subscribers, nickname)
+			}
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
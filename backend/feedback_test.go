@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFeedbackFlow(t *testing.T) {
+	srv := newTestServer(t)
+	seller := srv.signUp(t, "Seller")
+	buyer := srv.signUp(t, "Buyer")
+	adID := seller.createAd("Бизнес на Арзамасе")
+
+	var feedbackID uint
+
+	review := func(as *client, adID uint, rating int) func() *httptest.ResponseRecorder {
+		return func() *httptest.ResponseRecorder {
+			rec, id := as.sendFeedback(adID, rating)
+			if id != 0 {
+				feedbackID = id
+			}
+			return rec
+		}
+	}
+	confirm := func(as *client) func() *httptest.ResponseRecorder {
+		return func() *httptest.ResponseRecorder {
+			return as.confirmFeedback(feedbackID)
+		}
+	}
+
+	steps := []struct {
+		name string
+		do   func() *httptest.ResponseRecorder
+		want int
+	}{
+		{"seller reviews own ad", review(seller, adID, 5), http.StatusBadRequest},
+		{"review of missing ad", review(buyer, 999999, 5), http.StatusNotFound},
+		{"rating out of range", review(buyer, adID, 6), http.StatusBadRequest},
+		{"buyer reviews", review(buyer, adID, 4), http.StatusCreated},
+		{"buyer reviews twice", review(buyer, adID, 5), http.StatusBadRequest},
+		{"reviewer cannot confirm", confirm(buyer), http.StatusForbidden},
+		{"seller confirms", confirm(seller), http.StatusOK},
+	}
+
+	for _, step := range steps {
+		if !t.Run(step.name, func(t *testing.T) {
+			expectStatus(t, step.do(), step.want)
+		}) {
+			t.FailNow()
+		}
+	}
+
+	var me struct {
+		Rating       float64 `json:"rating"`
+		ReviewsCount int64   `json:"reviews_count"`
+	}
+	decode(t, seller.do(http.MethodGet, "/api/me", nil, ""), &me)
+	if me.Rating != 4 || me.ReviewsCount != 1 {
+		t.Fatalf("seller rating = %v from %d reviews, want 4 from 1", me.Rating, me.ReviewsCount)
+	}
+
+	var public struct {
+		Feedbacks []struct {
+			ID               uint   `json:"id"`
+			ReviewerNickname string `json:"reviewer_nickname"`
+		} `json:"feedbacks"`
+	}
+	decode(t, srv.newClient(t).do(http.MethodGet, "/api/feedback/Seller", nil, ""), &public)
+	if len(public.Feedbacks) != 1 || public.Feedbacks[0].ID != feedbackID || public.Feedbacks[0].ReviewerNickname != "Buyer" {
+		t.Fatalf("public feedback = %+v", public.Feedbacks)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"arizonagamesstore/backend/scheduler"
+	"arizonagamesstore/backend/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateUserRole godoc
+// @Summary Изменить роль пользователя
+// @Description Назначает пользователю роль User, Moderator или Admin. Только для админов. Новая роль начнет действовать после обновления access токена (до 3 минут)
+// @Tags Модерация
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param nickname path string true "Никнейм пользователя"
+// @Param request body map[string]string true "Новая роль" example(role="Moderator")
+// @Success 200 {object} map[string]string "Роль обновлена"
+// @Failure 400 {object} map[string]string "Неизвестная роль или попытка поменять роль самому себе"
+// @Failure 403 {object} map[string]string "Недостаточно прав"
+// @Failure 404 {object} map[string]string "Пользователь не найден"
+// @Failure 500 {object} map[string]string "Ошибка обновления"
+// @Router /admin/users/{nickname}/role [put]
+func UpdateUserRole(c *gin.Context) {
+	adminNickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат данных"})
+		return
+	}
+
+	if !models.IsValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Допустимые роли: User, Moderator, Admin"})
+		return
+	}
+
+	nickname := c.Param("nickname")
+	if nickname == adminNickname.(string) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя изменить роль самому себе"})
+		return
+	}
+
+	if err := services.Accounts.UpdateRole(nickname, req.Role); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления роли"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Роль обновлена",
+		"nickname": nickname,
+		"role":     req.Role,
+	})
+}
+
+// GetJobsStatus godoc
+// @Summary Фоновые задачи
+// @Description Состояние задач планировщика: расписание, когда запускались последний раз, сколько длились, последняя ошибка и когда следующий запуск. Только для админов
+// @Tags Модерация
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Список задач"
+// @Failure 403 {object} map[string]string "Недостаточно прав"
+// @Router /admin/jobs [get]
+func GetJobsStatus(jobs *scheduler.Scheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"jobs": jobs.Status()})
+	}
+}
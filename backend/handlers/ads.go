@@ -1,15 +1,17 @@
 package handlers
 
 import (
-	"arizonagamesstore/backend/database"
+	"arizonagamesstore/backend/imaging"
+	"arizonagamesstore/backend/logging"
 	"arizonagamesstore/backend/models"
 	"arizonagamesstore/backend/services"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
-	"time"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,12 +27,12 @@ type NewAddsRequest struct {
 	RentalHoursLimit *int    `form:"rentalHoursLimit"`
 	Category         string  `form:"category" binding:"required"`
 	Nickname         string  `form:"nickname" binding:"required"`
-	ImagePath        string  `form:"imagePath" binding:"required"`
+	Draft            bool    `form:"draft"`
 }
 
 // CreateNewAds godoc
 // @Summary Создать объявление
-// @Description Создает новое объявление с картинкой. Картинка загружается на AWS S3. После создания объявление автоматически удалится через 48 часов (можно продлить). Между созданиями объявлений нужно ждать 60 секунд
+// @Description Создает новое объявление с картинкой. Картинка проверяется по содержимому (JPEG/PNG/GIF/WebP, от 300x200 до 1920x1080), перекодируется в JPEG без EXIF и GPS и загружается в файловое хранилище вместе с превью image_thumb и image_medium. Через 48 часов объявление уходит в статус expired и пропадает из ленты, его можно продлить через /ads/{id}/renew. С draft=true объявление сохраняется черновиком и публикуется тем же renew. Между созданиями объявлений нужно ждать 60 секунд
 // @Tags Объявления
 // @Accept multipart/form-data
 // @Produce json
@@ -42,14 +44,14 @@ type NewAddsRequest struct {
 // @Param price formData number true "Цена"
 // @Param category formData string true "Категория (house/business/vehicle/security/accs/others)"
 // @Param nickname formData string true "Никнейм автора"
-// @Param imagePath formData string true "Путь для сохранения картинки"
 // @Param image formData file true "Изображение (макс. 10MB, разрешение 300x200 - 1920x1080)"
 // @Param rentalHoursLimit formData int false "Лимит часов аренды (1-180)"
+// @Param draft formData bool false "Сохранить как черновик, не публикуя"
 // @Success 200 {object} map[string]interface{} "Объявление создано! ID: 42"
-// @Failure 400 {object} map[string]string "Не хватает данных или картинка кривая"
+// @Failure 400 {object} map[string]string "Не хватает данных, файл не картинка или не то разрешение"
 // @Failure 413 {object} map[string]string "Картинка слишком большая (макс. 10MB)"
 // @Failure 429 {object} map[string]string "Подожди 60 секунд перед созданием нового объявления"
-// @Failure 500 {object} map[string]string "Ошибка загрузки на S3 или БД"
+// @Failure 500 {object} map[string]string "Ошибка загрузки картинки или БД"
 // @Router /createnewads [post]
 func CreateNewAds(c *gin.Context) {
 	var req NewAddsRequest
@@ -83,48 +85,39 @@ func CreateNewAds(c *gin.Context) {
 		Category:         req.Category,
 		Nickname:         req.Nickname,
 	}
-
-	fmt.Println("Server:", req.Server)
-	fmt.Println("Title:", req.Title)
-	fmt.Println("Description:", req.Description)
-	fmt.Println("Type:", req.Types)
-	fmt.Println("Category:", req.Category)
-	fmt.Println("Nickname:", req.Nickname)
-	fmt.Println("Image filename:", file.Filename)
-	fmt.Println("Image path:", req.ImagePath)
-	fmt.Println("Image size:", file.Size)
-
-	// Создаем временную директорию если её нет
-	tempDir := "./temp_uploads"
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания временной директории"})
-		return
+	if req.Draft {
+		dto.Status = models.AdStatusDraft
 	}
 
-	// Сохраняем файл во временную директорию
-	tempFilePath := filepath.Join(tempDir, file.Filename)
-	if err := c.SaveUploadedFile(file, tempFilePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения файла"})
-		return
+	ctx := c.Request.Context()
+	slog.InfoContext(ctx, "Создание объявления",
+		"author", req.Nickname,
+		"server", req.Server,
+		"category", req.Category,
+		"type", req.Types,
+		"image_size", file.Size,
+	)
+	if logging.UserContent() {
+		slog.InfoContext(ctx, "Содержимое объявления", "title", req.Title, "description", req.Description, "image_filename", file.Filename)
 	}
-	defer os.Remove(tempFilePath) // Удаляем временный файл после загрузки
 
-	fmt.Println("Отправляем в S3 хранилище изображение")
-	publicURL, errS3 := UploadFileToS3(tempFilePath, req.ImagePath)
-	if errS3 != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка при загрузке изображения: %v", errS3)})
-		return
-	}
-	fmt.Println("Изображение успешно загружено в S3")
-	result, errDB := services.CreateNewAd(dto, publicURL)
-	if result != true {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка при создании объявления: %v", errDB)})
+	// Имя файла генерируем на сервере, чтобы клиент не мог перезаписать чужой файл
+	image, errUpload := uploadImage(c.Request.Context(), file, "ads", imaging.AdImage)
+	if errUpload != nil {
+		if msg := imageErrorMessage(errUpload, imaging.AdImage); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка при загрузке изображения: %v", errUpload)})
 		return
 	}
+	slog.DebugContext(ctx, "Изображение объявления загружено", "url", image.URL)
 
-	errUpdate := services.AddAdCount(dto.Category)
-	if errUpdate != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка при обновлении статистики: %v", errUpdate)})
+	dto.ImageThumb = image.Variants[imaging.VariantThumb]
+	dto.ImageMedium = image.Variants[imaging.VariantMedium]
+	if _, errDB := services.Ads.Create(dto, image.URL); errDB != nil {
+		deleteStoredFiles(c.Request.Context(), image.URLs()...)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка при создании объявления: %v", errDB)})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Объявление успешно создано"})
@@ -132,44 +125,53 @@ func CreateNewAds(c *gin.Context) {
 
 // GetAdsByCategory godoc
 // @Summary Список объявлений
-// @Description Возвращает список объявлений с фильтрацией и сортировкой. По умолчанию возвращает 20 штук, можно подгружать дальше через offset
+// @Description Возвращает список активных объявлений с фильтрацией и сортировкой. По умолчанию возвращает 20 штук. Следующая страница - по page.next_cursor, предыдущая - по page.prev_cursor, новые объявления страницы не сдвигают. Общее количество в page.total и заголовках X-Total-Count/Content-Range
 // @Tags Объявления
 // @Produce json
 // @Param category query string true "Категория" Enums(house, business, vehicle, security, accs, others)
 // @Param server query string false "Фильтр по серверу"
-// @Param limit query int false "Сколько объявлений вернуть (по умолчанию 20)"
-// @Param offset query int false "Сколько пропустить для пагинации (по умолчанию 0)"
+// @Param limit query int false "Сколько объявлений вернуть (по умолчанию 20, максимум 100)"
+// @Param cursor query string false "Курсор из page.next_cursor или page.prev_cursor"
+// @Param offset query int false "Устарело, используй cursor. Сколько пропустить (по умолчанию 0)"
 // @Param sort query string false "Сортировка" Enums(date_desc, date_asc, price_desc, price_asc, views_desc)
 // @Param type query string false "Фильтр по типу" Enums(Продать, Купить, Сдать в аренду)
 // @Param currency query string false "Фильтр по валюте" Enums(VC, $, BTC, EURO, Договорная)
 // @Param price_min query number false "Минимальная цена"
 // @Param price_max query number false "Максимальная цена"
-// @Success 200 {object} map[string]interface{} "Список объявлений"
-// @Failure 400 {object} map[string]string "Не указана категория"
+// @Success 200 {object} map[string]interface{} "Список объявлений и курсоры"
+// @Failure 400 {object} map[string]string "Не указана категория или битый курсор"
 // @Failure 500 {object} map[string]string "Ошибка БД"
 // @Router /ads [get]
 func GetAdsByCategory(c *gin.Context) {
 	category := c.Query("category")
 	server := c.Query("server")
-	limitStr := c.DefaultQuery("limit", "20")
-	offsetStr := c.DefaultQuery("offset", "0")
 
 	if category == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Категория обязательна"})
 		return
 	}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 20
-	}
+	filters := parseAdFilters(c)
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	ads, page, err := services.Ads.ListByCategory(category, server, parsePageRequest(c, services.DefaultPageSize), filters)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный курсор, начни с первой страницы"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения объявлений: %v", err)})
+		return
 	}
 
-	// Парсим параметры фильтрации и сортировки
+	setPageHeaders(c, "ads", page)
+	c.JSON(http.StatusOK, gin.H{
+		"ads":  ads,
+		"page": page,
+	})
+}
+
+// parseAdFilters парсит общие для лент и поиска параметры фильтрации и сортировки
+func parseAdFilters(c *gin.Context) *services.AdFilters {
 	filters := &services.AdFilters{
 		Sort:     c.Query("sort"),
 		Type:     c.Query("type"),
@@ -188,22 +190,73 @@ func GetAdsByCategory(c *gin.Context) {
 		}
 	}
 
-	ads, err := services.GetAdsByCategory(category, server, limit, offset, filters)
+	return filters
+}
+
+// SearchAds godoc
+// @Summary Поиск объявлений
+// @Description Полнотекстовый поиск по названию и описанию активных объявлений во всех категориях. Понимает русскую морфологию ("дома" найдет "дом"), кавычки для точной фразы, OR и минус для исключения слов. Совпадения подсвечиваются тегом <mark>, остальной текст экранирован
+// @Tags Объявления
+// @Produce json
+// @Param q query string true "Что ищем (минимум 2 символа)"
+// @Param category query string false "Ограничить категорией" Enums(house, business, vehicle, security, accs, others)
+// @Param server query string false "Фильтр по серверу"
+// @Param limit query int false "Сколько объявлений вернуть (по умолчанию 20)"
+// @Param offset query int false "Сколько пропустить для пагинации (по умолчанию 0)"
+// @Param sort query string false "Сортировка (по умолчанию по релевантности)" Enums(relevance, date_desc, date_asc, price_desc, price_asc, views_desc)
+// @Param type query string false "Фильтр по типу" Enums(Продать, Купить, Сдать в аренду)
+// @Param currency query string false "Фильтр по валюте" Enums(VC, $, BTC, EURO, Договорная)
+// @Param price_min query number false "Минимальная цена"
+// @Param price_max query number false "Максимальная цена"
+// @Success 200 {object} map[string]interface{} "Найденные объявления и общее количество"
+// @Failure 400 {object} map[string]string "Слишком короткий запрос"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /ads/search [get]
+func SearchAds(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if utf8.RuneCountInString(text) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Поисковый запрос должен содержать минимум 2 символа"})
+		return
+	}
+	if utf8.RuneCountInString(text) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Поисковый запрос не должен превышать 100 символов"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	ads, total, err := services.Ads.Search(text, c.Query("category"), c.Query("server"), limit, offset, parseAdFilters(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения объявлений: %v", err)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка поиска объявлений: %v", err)})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"ads": ads})
+	setPageHeaders(c, "ads", &services.Page{Total: total, Start: int64(offset), Count: len(ads)})
+	c.JSON(http.StatusOK, gin.H{
+		"ads":   ads,
+		"total": total,
+	})
 }
 
 // GetAdsByNickname godoc
 // @Summary Объявления по нику
-// @Description Возвращает все объявления конкретного пользователя
+// @Description Возвращает объявления конкретного пользователя, по 20 штук. По умолчанию активные и проданные. Листается курсорами из page, как и общая лента
 // @Tags Объявления
 // @Produce json
 // @Param nickname path string true "Никнейм пользователя"
+// @Param status query string false "Фильтр по статусу" Enums(active, sold)
+// @Param limit query int false "Сколько объявлений вернуть (по умолчанию 20, максимум 100)"
+// @Param cursor query string false "Курсор из page.next_cursor или page.prev_cursor"
 // @Success 200 {object} map[string]interface{} "Список объявлений пользователя"
+// @Failure 400 {object} map[string]string "Недопустимый статус или битый курсор"
 // @Failure 500 {object} map[string]string "Ошибка БД"
 // @Router /listings/user/{nickname} [get]
 func GetAdsByNickname(c *gin.Context) {
@@ -214,15 +267,31 @@ func GetAdsByNickname(c *gin.Context) {
 		return
 	}
 
-	ads, err := services.GetAdsByNickname(nickname)
+	statuses := []string{models.AdStatusActive, models.AdStatusSold}
+	switch status := c.Query("status"); status {
+	case "":
+	case models.AdStatusActive, models.AdStatusSold:
+		statuses = []string{status}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Можно смотреть только активные или проданные объявления"})
+		return
+	}
+
+	ads, page, err := services.Ads.ListByNickname(nickname, statuses, parsePageRequest(c, services.DefaultPageSize))
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный курсор, начни с первой страницы"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения объявлений: %v", err)})
 		return
 	}
 
+	setPageHeaders(c, "ads", page)
 	c.JSON(http.StatusOK, gin.H{
 		"success":  true,
 		"listings": ads,
+		"page":     page,
 	})
 }
 
@@ -260,7 +329,11 @@ func CreateReport(c *gin.Context) {
 		return
 	}
 
-	err := services.CreateReport(req.AdID, nickname.(string), req.Reason, req.Description)
+	err := services.Reports.Create(req.AdID, nickname.(string), req.Reason, req.Description)
+	if errors.Is(err, services.ErrAdNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Объявление не найдено"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка создания жалобы: %v", err)})
 		return
@@ -271,33 +344,32 @@ func CreateReport(c *gin.Context) {
 
 // GetRandomAds godoc
 // @Summary Случайные объявления
-// @Description Возвращает 8 случайных объявлений для главной страницы. Каждый раз разные!
+// @Description Возвращает активные объявления для главной страницы в случайном порядке. Порядок задает page.seed: дальше листай по page.next_cursor и объявления не повторятся. Тот же seed в параметре вернет ту же ленту, без него каждый раз новая
 // @Tags Объявления
 // @Produce json
+// @Param limit query int false "Сколько объявлений вернуть (по умолчанию 15, максимум 100)"
+// @Param cursor query string false "Курсор из page.next_cursor или page.prev_cursor"
+// @Param seed query string false "Seed перемешивания из page.seed"
 // @Success 200 {object} map[string]interface{} "Массив случайных объявлений"
+// @Failure 400 {object} map[string]string "Битый курсор"
 // @Failure 500 {object} map[string]string "Что-то пошло не так"
 // @Router /ads/random [get]
 func GetRandomAds(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "15")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 15
-	}
-
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	ads, page, err := services.Ads.ListRandom(parsePageRequest(c, 15))
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный курсор, начни с первой страницы"})
+		return
 	}
-
-	ads, err := services.GetRandomAds(limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения объявлений: %v", err)})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"ads": ads})
+	setPageHeaders(c, "ads", page)
+	c.JSON(http.StatusOK, gin.H{
+		"ads":  ads,
+		"page": page,
+	})
 }
 
 // UpdateAd godoc
@@ -314,6 +386,7 @@ func GetRandomAds(c *gin.Context) {
 // @Failure 401 {object} map[string]string "Не авторизован"
 // @Failure 403 {object} map[string]string "Это не твое объявление!"
 // @Failure 404 {object} map[string]string "Объявление не найдено"
+// @Failure 409 {object} map[string]string "Статус объявления поменялся во время правки"
 // @Failure 500 {object} map[string]string "Ошибка обновления"
 // @Router /ads/{id} [put]
 func UpdateAd(c *gin.Context) {
@@ -330,17 +403,20 @@ func UpdateAd(c *gin.Context) {
 	}
 
 	// Получить объявление и проверить владельца
-	var ad models.Ad
-	if err := database.DB.Where("id = ?", adID).First(&ad).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Объявление не найдено"})
+	ad, err := services.Ads.GetOwned(uint(adID), nickname.(string))
+	if errors.Is(err, services.ErrAdNotOwned) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не можете редактировать это объявление"})
 		return
 	}
-
-	if ad.Nickname != nickname.(string) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не можете редактировать это объявление"})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Объявление не найдено"})
 		return
 	}
 
+	oldPrice := ad.Price
+	oldImages := []string{ad.Image, ad.ImageThumb, ad.ImageMedium}
+	var newImages []string
+
 	// Обновление полей
 	if title := c.PostForm("title"); title != "" {
 		ad.Title = title
@@ -367,30 +443,42 @@ func UpdateAd(c *gin.Context) {
 			return
 		}
 
-		// Удаление старого изображения
-		if ad.Image != "" {
-			os.Remove(ad.Image)
-		}
-
 		// Сохранение нового изображения
-		uploadsDir := "./uploads/ads"
-		ext := filepath.Ext(file.Filename)
-		filename := fmt.Sprintf("%s_%d%s", nickname.(string), time.Now().Unix(), ext)
-		imagePath := filepath.Join(uploadsDir, filename)
-
-		if err := c.SaveUploadedFile(file, imagePath); err != nil {
+		image, err := uploadImage(c.Request.Context(), file, "ads", imaging.AdImage)
+		if err != nil {
+			if msg := imageErrorMessage(err, imaging.AdImage); msg != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения изображения"})
 			return
 		}
 
-		ad.Image = imagePath
+		newImages = image.URLs()
+		ad.Image = image.URL
+		ad.ImageThumb = image.Variants[imaging.VariantThumb]
+		ad.ImageMedium = image.Variants[imaging.VariantMedium]
 	}
 
-	// Сохранение изменений
-	if err := database.DB.Save(&ad).Error; err != nil {
+	// Сохранение изменений. Старую картинку удаляем только после записи, а если
+	// записать не вышло, удаляем новую
+	if err := services.Ads.Edit(ad); err != nil {
+		deleteStoredFiles(c.Request.Context(), newImages...)
+		if errors.Is(err, services.ErrAdChanged) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Пока вы редактировали, объявление истекло или его скрыла модерация. Обновите страницу"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления объявления"})
 		return
 	}
+	if newImages != nil {
+		deleteStoredFiles(c.Request.Context(), oldImages...)
+	}
+
+	// Запоминаем изменение цены для тех, у кого объявление в избранном
+	if err := services.Favorites.RecordPriceChange(ad.ID, oldPrice, ad.Price); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Ошибка сохранения изменения цены", "ad_id", ad.ID, "error", err)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Объявление успешно обновлено",
@@ -400,7 +488,7 @@ func UpdateAd(c *gin.Context) {
 
 // DeleteAd godoc
 // @Summary Удалить объявление
-// @Description Удаляет объявление. Доступно только автору
+// @Description Убирает объявление в архив. Из ленты и профиля оно пропадает, но отзывы по нему сохраняются. Доступно только автору
 // @Tags Объявления
 // @Security BearerAuth
 // @Produce json
@@ -409,45 +497,119 @@ func UpdateAd(c *gin.Context) {
 // @Failure 401 {object} map[string]string "Не авторизован"
 // @Failure 403 {object} map[string]string "Это не твое объявление!"
 // @Failure 404 {object} map[string]string "Объявление не найдено"
+// @Failure 409 {object} map[string]string "Объявление скрыто модерацией или уже в архиве"
 // @Failure 500 {object} map[string]string "Ошибка удаления"
 // @Router /ads/{id} [delete]
 func DeleteAd(c *gin.Context) {
+	if _, ok := changeOwnAdStatus(c, models.AdStatusArchived); ok {
+		c.JSON(http.StatusOK, gin.H{"message": "Объявление успешно удалено"})
+	}
+}
+
+// RenewAd godoc
+// @Summary Продлить объявление
+// @Description Продлевает просроченное объявление еще на 48 часов и поднимает его наверх ленты. Этим же запросом публикуется черновик. Доступно только автору
+// @Tags Объявления
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "ID объявления"
+// @Success 200 {object} map[string]interface{} "Объявление снова в ленте"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 403 {object} map[string]string "Это не твое объявление!"
+// @Failure 404 {object} map[string]string "Объявление не найдено"
+// @Failure 409 {object} map[string]string "Продлить можно только просроченное объявление или черновик"
+// @Failure 500 {object} map[string]string "Ошибка продления"
+// @Router /ads/{id}/renew [post]
+func RenewAd(c *gin.Context) {
+	if ad, ok := changeOwnAdStatus(c, models.AdStatusActive); ok {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Объявление продлено",
+			"ad":      ad,
+		})
+	}
+}
+
+// MarkAdSold godoc
+// @Summary Отметить как проданное
+// @Description Помечает объявление проданным. Из ленты оно пропадает, но остается в профиле продавца. Доступно только автору
+// @Tags Объявления
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "ID объявления"
+// @Success 200 {object} map[string]interface{} "Поздравляем с продажей!"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 403 {object} map[string]string "Это не твое объявление!"
+// @Failure 404 {object} map[string]string "Объявление не найдено"
+// @Failure 409 {object} map[string]string "Продать можно только активное или просроченное объявление"
+// @Failure 500 {object} map[string]string "Ошибка обновления"
+// @Router /ads/{id}/sold [post]
+func MarkAdSold(c *gin.Context) {
+	if ad, ok := changeOwnAdStatus(c, models.AdStatusSold); ok {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Объявление отмечено как проданное",
+			"ad":      ad,
+		})
+	}
+}
+
+// GetMyAds godoc
+// @Summary Мои объявления
+// @Description Возвращает все объявления текущего пользователя, включая черновики, просроченные и скрытые модерацией. Архив показывается только при status=archived
+// @Tags Объявления
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Фильтр по статусу" Enums(draft, active, expired, sold, hidden, archived)
+// @Success 200 {object} map[string]interface{} "Список объявлений"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /ads/my [get]
+func GetMyAds(c *gin.Context) {
 	nickname, exists := c.Get("nickname")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
 		return
 	}
 
-	adID, err := strconv.Atoi(c.Param("id"))
+	ads, err := services.Ads.ListMine(nickname.(string), c.Query("status"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID объявления"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения объявлений: %v", err)})
 		return
 	}
 
-	// Получить объявление и проверить владельца
-	var ad models.Ad
-	if err := database.DB.Where("id = ?", adID).First(&ad).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Объявление не найдено"})
-		return
-	}
+	c.JSON(http.StatusOK, gin.H{"ads": ads})
+}
 
-	if ad.Nickname != nickname.(string) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Вы не можете удалить это объявление"})
-		return
+// changeOwnAdStatus переводит объявление текущего пользователя в новый статус.
+// Ошибки сам отдает клиенту, ok=false значит ответ уже отправлен
+func changeOwnAdStatus(c *gin.Context, status string) (*models.Ad, bool) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return nil, false
 	}
 
-	// Удаление изображения
-	if ad.Image != "" {
-		os.Remove(ad.Image)
+	adID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || adID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID объявления"})
+		return nil, false
 	}
 
-	// Удаление объявления
-	if err := database.DB.Delete(&ad).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления объявления"})
-		return
+	ad, err := services.Ads.ChangeStatusByOwner(uint(adID), nickname.(string), status)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAdNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Объявление не найдено"})
+		case errors.Is(err, services.ErrAdNotOwned):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Это не ваше объявление"})
+		case errors.Is(err, services.ErrAdStatusTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": "Нельзя перевести объявление в этот статус"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка обновления объявления: %v", err)})
+		}
+		return nil, false
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Объявление успешно удалено"})
+	return ad, true
 }
 
 // IncrementAdViews godoc
@@ -468,7 +630,12 @@ func IncrementAdViews(c *gin.Context) {
 	}
 
 	// Увеличение счетчика просмотров
-	if err := database.DB.Model(&models.Ad{}).Where("id = ?", adID).UpdateColumn("views", database.DB.Raw("views + 1")).Error; err != nil {
+	err = services.Ads.IncrementViews(uint(adID))
+	if errors.Is(err, services.ErrAdNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Объявление не найдено"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления просмотров"})
 		return
 	}
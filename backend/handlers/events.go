@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"arizonagamesstore/backend/events"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	eventsHeartbeat = 25 * time.Second
+	// Соединение живет не дольше access токена. Браузерный EventSource сам
+	// переподключится, и AuthRequired заново проверит (или обновит) токен,
+	// так что после logout или бана события перестают приходить
+	eventsStreamLifetime = 3 * time.Minute
+)
+
+// streamsClosing закрывается при остановке сервера. Иначе открытые потоки
+// держали бы graceful shutdown до истечения eventsStreamLifetime
+var (
+	streamsClosing   = make(chan struct{})
+	closeStreamsOnce sync.Once
+)
+
+// CloseEventStreams просит все открытые потоки событий отправить reconnect и завершиться
+func CloseEventStreams() {
+	closeStreamsOnce.Do(func() { close(streamsClosing) })
+}
+
+// StreamEvents godoc
+// @Summary Поток событий
+// @Description Server-Sent Events для залогиненного пользователя: новый отзыв ждет подтверждения, отзыв подтвержден, решение по жалобе на объявление, объявление скоро истечет, новое сообщение. Подключаться через EventSource с withCredentials. Раз в 3 минуты сервер присылает reconnect и закрывает поток - EventSource переподключится сам
+// @Tags События
+// @Security BearerAuth
+// @Produce text/event-stream
+// @Success 200 {string} string "Поток событий"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Router /events [get]
+func StreamEvents(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	stream, unsubscribe := events.Subscribe(nickname.(string))
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	lifetime := time.NewTimer(eventsStreamLifetime)
+	defer lifetime.Stop()
+
+	c.SSEvent("ready", gin.H{"nickname": nickname})
+	c.Writer.Flush()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-lifetime.C:
+			c.SSEvent("reconnect", gin.H{})
+			c.Writer.Flush()
+			return
+		case <-streamsClosing:
+			c.SSEvent("reconnect", gin.H{})
+			c.Writer.Flush()
+			return
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"time": time.Now()})
+			c.Writer.Flush()
+		case event, ok := <-stream:
+			if !ok {
+				return
+			}
+			c.SSEvent(event.Type, event)
+			c.Writer.Flush()
+		}
+	}
+}
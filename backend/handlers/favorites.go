@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"arizonagamesstore/backend/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddFavoriteAd godoc
+// @Summary Добавить в избранное
+// @Description Добавляет объявление в избранное. Запоминается текущая цена, чтобы потом показать "цена снизилась с X до Y"
+// @Tags Избранное
+// @Security BearerAuth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param ad_id formData int true "ID объявления"
+// @Success 200 {object} map[string]interface{} "Добавлено в избранное"
+// @Failure 400 {object} map[string]string "Не указан ID или это свое объявление"
+// @Failure 401 {object} map[string]string "Нужна авторизация"
+// @Failure 404 {object} map[string]string "Объявление не найдено"
+// @Failure 409 {object} map[string]string "Уже в избранном"
+// @Failure 500 {object} map[string]string "Ошибка сохранения"
+// @Router /favorites [post]
+func AddFavoriteAd(c *gin.Context) {
+	userNickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	adID, err := strconv.Atoi(c.PostForm("ad_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID объявления"})
+		return
+	}
+
+	// Проверить существование объявления
+	ad, err := services.Ads.Get(uint(adID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Объявление не найдено"})
+		return
+	}
+
+	if ad.Nickname == userNickname.(string) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя добавить в избранное свое объявление"})
+		return
+	}
+
+	favorite, err := services.Favorites.Add(userNickname.(string), ad)
+	if err != nil {
+		if errors.Is(err, services.ErrAlreadyFavorite) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Объявление уже в избранном"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка добавления в избранное"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Объявление добавлено в избранное",
+		"favorite": favorite,
+	})
+}
+
+// RemoveFavoriteAd godoc
+// @Summary Убрать из избранного
+// @Description Удаляет объявление из избранного
+// @Tags Избранное
+// @Security BearerAuth
+// @Produce json
+// @Param ad_id path int true "ID объявления"
+// @Success 200 {object} map[string]string "Убрано из избранного"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 401 {object} map[string]string "Нужна авторизация"
+// @Failure 404 {object} map[string]string "Этого объявления нет в избранном"
+// @Failure 500 {object} map[string]string "Ошибка удаления"
+// @Router /favorites/{ad_id} [delete]
+func RemoveFavoriteAd(c *gin.Context) {
+	userNickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	adID, err := strconv.Atoi(c.Param("ad_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID объявления"})
+		return
+	}
+
+	removed, err := services.Favorites.Remove(userNickname.(string), adID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления из избранного"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Этого объявления нет в избранном"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Объявление убрано из избранного"})
+}
+
+// GetFavoriteAds godoc
+// @Summary Избранное
+// @Description Возвращает избранные объявления пользователя. Если цена изменилась после добавления, в price_change будут старая и новая цена, а dropped=true значит, что стало дешевле
+// @Tags Избранное
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Список избранного"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 500 {object} map[string]string "Ошибка загрузки"
+// @Router /favorites [get]
+func GetFavoriteAds(c *gin.Context) {
+	userNickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	favorites, err := services.Favorites.List(userNickname.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения избранного: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"favorites": favorites})
+}
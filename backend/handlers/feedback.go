@@ -1,14 +1,14 @@
 package handlers
 
 import (
-	"arizonagamesstore/backend/database"
+	"arizonagamesstore/backend/events"
+	"arizonagamesstore/backend/imaging"
 	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/services"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -48,7 +48,7 @@ func CreateFeedback(c *gin.Context) {
 	}
 
 	adID, err := strconv.Atoi(c.PostForm("ad_id"))
-	if err != nil {
+	if err != nil || adID <= 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID объявления"})
 		return
 	}
@@ -65,62 +65,47 @@ func CreateFeedback(c *gin.Context) {
 		return
 	}
 
-	// Получить информацию о объявлении
-	var ad models.Ad
-	if err := database.DB.Where("id = ?", adID).First(&ad).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Объявление не найдено"})
-		return
-	}
-
-	// Проверить, что пользователь не оставляет отзыв на свое объявление
-	if ad.Nickname == reviewerNickname.(string) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя оставить отзыв на свое объявление"})
-		return
-	}
-
-	// Проверить, не оставлял ли пользователь уже отзыв на это объявление
-	var existingFeedback models.FeedbackAd
-	result := database.DB.Where("ad_id = ? AND reviewer_nickname = ?", adID, reviewerNickname.(string)).First(&existingFeedback)
-	if result.Error == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Вы уже оставили отзыв на это объявление"})
+	// Проверяем до загрузки картинки: объявление есть, оно не свое и отзыва на него еще нет
+	ad, err := services.Feedback.CheckCanReview(uint(adID), reviewerNickname.(string))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAdNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Объявление не найдено"})
+		case errors.Is(err, services.ErrFeedbackOwnAd):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя оставить отзыв на свое объявление"})
+		case errors.Is(err, services.ErrFeedbackExists):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Вы уже оставили отзыв на это объявление"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки объявления"})
+		}
 		return
 	}
 
 	// Сохранение изображения
-	uploadsDir := "./uploads/feedbacks"
-	if err := os.MkdirAll(uploadsDir, os.ModePerm); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания директории"})
-		return
-	}
-
-	ext := filepath.Ext(file.Filename)
-	filename := fmt.Sprintf("%s_%d%s", reviewerNickname.(string), time.Now().Unix(), ext)
-	imagePath := filepath.Join(uploadsDir, filename)
-
-	if err := c.SaveUploadedFile(file, imagePath); err != nil {
+	proof, err := uploadImage(c.Request.Context(), file, "feedbacks", imaging.ProofImage)
+	if err != nil {
+		if msg := imageErrorMessage(err, imaging.ProofImage); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения изображения"})
 		return
 	}
 
-	// Сохраняем URL для доступа через статическую папку /uploads
-	imageURL := fmt.Sprintf("http://localhost:8080/uploads/feedbacks/%s", filename)
-
-	// Создание отзыва
-	feedback := models.FeedbackAd{
-		AdID:             adID,
-		ReviewerNickname: reviewerNickname.(string),
-		AdOwnerNickname:  ad.Nickname,
-		Rating:           rating,
-		ReviewText:       reviewText,
-		ProofImage:       imageURL,
-		ConfirmFeedback:  false,
-	}
-
-	if err := database.DB.Create(&feedback).Error; err != nil {
+	feedback, err := services.Feedback.Create(ad, reviewerNickname.(string), rating, reviewText, proof.URL)
+	if err != nil {
+		deleteStoredFiles(c.Request.Context(), proof.URLs()...)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка создания отзыва: %v", err)})
 		return
 	}
 
+	services.Notify(ad.Nickname, events.TypeFeedbackPending, models.NotificationPayload{
+		"feedback_id":       feedback.ID,
+		"ad_id":             feedback.AdID,
+		"reviewer_nickname": feedback.ReviewerNickname,
+		"rating":            feedback.Rating,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message":  "Отзыв успешно отправлен на модерацию",
 		"feedback": feedback,
@@ -129,36 +114,65 @@ func CreateFeedback(c *gin.Context) {
 
 // GetFeedbacksByOwner godoc
 // @Summary Отзывы продавца
-// @Description Возвращает все отзывы о продавце
+// @Description Возвращает подтвержденные отзывы о продавце, сверху новые. По 20 штук, дальше по курсору из page
 // @Tags Отзывы
 // @Produce json
 // @Param nickname path string true "Никнейм продавца"
+// @Param limit query int false "Сколько отзывов вернуть (по умолчанию 20, максимум 100)"
+// @Param cursor query string false "Курсор из page.next_cursor или page.prev_cursor"
 // @Success 200 {object} map[string]interface{} "Список отзывов"
+// @Failure 400 {object} map[string]string "Битый курсор"
 // @Failure 500 {object} map[string]string "Ошибка загрузки"
 // @Router /feedback/{nickname} [get]
 func GetFeedbacksByOwner(c *gin.Context) {
 	ownerNickname := c.Param("nickname")
 
-	var feedbacks []models.FeedbackWithReviewer
+	feedbacks, page, err := services.Feedback.ListByOwner(ownerNickname, parsePageRequest(c, services.DefaultPageSize))
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный курсор, начни с первой страницы"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения отзывов: %v", err)})
+		return
+	}
 
-	result := database.DB.Table("feedback_ads").
-		Select("feedback_ads.*, accounts.avatar as reviewer_avatar, accounts.rating as reviewer_rating").
-		Joins("LEFT JOIN accounts ON feedback_ads.reviewer_nickname = accounts.nickname").
-		Where("feedback_ads.ad_owner_nickname = ? AND feedback_ads.confirm_feedback = ?", ownerNickname, true).
-		Order("feedback_ads.created_at DESC").
-		Find(&feedbacks)
+	setPageHeaders(c, "feedbacks", page)
+	c.JSON(http.StatusOK, gin.H{
+		"feedbacks": feedbacks,
+		"page":      page,
+	})
+}
 
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения отзывов: %v", result.Error)})
+// GetSellerReputation godoc
+// @Summary Репутация продавца
+// @Description Рейтинг продавца и из чего он складывается. Рейтинг - взвешенное среднее: к отзывам подмешано
+// @Description несколько оценок 4.0, старые отзывы со временем весят меньше, а отзывы опытных продавцов больше.
+// @Description all_time и last_30_days - сколько подтвержденных отзывов с каждой оценкой и их обычное среднее
+// @Tags Отзывы
+// @Produce json
+// @Param nickname path string true "Никнейм продавца"
+// @Success 200 {object} services.ReputationBreakdown "Репутация"
+// @Failure 404 {object} map[string]string "Пользователь не найден"
+// @Failure 500 {object} map[string]string "Ошибка загрузки"
+// @Router /feedback/{nickname}/reputation [get]
+func GetSellerReputation(c *gin.Context) {
+	breakdown, err := services.Reputation.Breakdown(c.Param("nickname"))
+	if errors.Is(err, services.ErrSellerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения репутации: %v", err)})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"feedbacks": feedbacks})
+	c.JSON(http.StatusOK, breakdown)
 }
 
 // ConfirmFeedback godoc
 // @Summary Подтвердить отзыв
-// @Description Подтверждает отзыв. Только продавец может подтвердить отзыв о себе. После подтверждения рейтинг пересчитывается
+// @Description Подтверждает отзыв. Только продавец может подтвердить отзыв о себе. Рейтинг и число успешных сделок пересчитываются в той же транзакции
 // @Tags Отзывы
 // @Security BearerAuth
 // @Produce json
@@ -167,32 +181,43 @@ func GetFeedbacksByOwner(c *gin.Context) {
 // @Failure 401 {object} map[string]string "Не авторизован"
 // @Failure 403 {object} map[string]string "Ты не можешь подтвердить этот отзыв"
 // @Failure 404 {object} map[string]string "Отзыв не найден"
+// @Failure 409 {object} map[string]string "Отзыв уже подтвержден"
 // @Failure 500 {object} map[string]string "Ошибка подтверждения"
 // @Router /feedback/{id}/confirm [put]
 func ConfirmFeedback(c *gin.Context) {
-	feedbackID, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID отзыва"})
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
 		return
 	}
 
-	var feedback models.FeedbackAd
-	if err := database.DB.Where("id = ?", feedbackID).First(&feedback).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Отзыв не найден"})
+	feedbackID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || feedbackID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID отзыва"})
 		return
 	}
 
-	feedback.ConfirmFeedback = true
-	if err := database.DB.Save(&feedback).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка подтверждения отзыва"})
+	// Подтвердить может только тот продавец, о котором отзыв. Рейтинг пересчитывается там же
+	feedback, err := services.Feedback.Confirm(uint(feedbackID), nickname.(string))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrFeedbackNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Отзыв не найден"})
+		case errors.Is(err, services.ErrFeedbackNotOwned):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Вы не можете подтвердить этот отзыв"})
+		case errors.Is(err, services.ErrFeedbackConfirmed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Отзыв уже подтвержден"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка подтверждения отзыва"})
+		}
 		return
 	}
 
-	// Пересчитываем рейтинг владельца объявления
-	if err := UpdateUserRating(feedback.AdOwnerNickname); err != nil {
-		// Логируем ошибку, но не возвращаем её клиенту, так как отзыв уже подтвержден
-		fmt.Printf("Ошибка обновления рейтинга пользователя %s: %v\n", feedback.AdOwnerNickname, err)
-	}
+	services.Notify(feedback.ReviewerNickname, events.TypeFeedbackConfirmed, models.NotificationPayload{
+		"feedback_id":       feedback.ID,
+		"ad_id":             feedback.AdID,
+		"ad_owner_nickname": feedback.AdOwnerNickname,
+	})
 
 	c.JSON(http.StatusOK, gin.H{"message": "Отзыв подтвержден"})
 }
@@ -224,8 +249,8 @@ func AddViewedAd(c *gin.Context) {
 	}
 
 	// Проверить существование объявления
-	var ad models.Ad
-	if err := database.DB.Where("id = ?", adID).First(&ad).Error; err != nil {
+	ad, err := services.Ads.Get(uint(adID))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Объявление не найдено"})
 		return
 	}
@@ -236,24 +261,10 @@ func AddViewedAd(c *gin.Context) {
 		return
 	}
 
-	// Проверить, не просмотрено ли уже
-	var existingView models.ViewedAd
-	result := database.DB.Where("user_nickname = ? AND ad_id = ?", userNickname.(string), adID).First(&existingView)
-
-	if result.Error == nil {
-		// Уже просмотрено, обновляем время
-		existingView.ViewedAt = time.Now()
-		database.DB.Save(&existingView)
-	} else {
-		// Создаем новую запись
-		viewedAd := models.ViewedAd{
-			UserNickname: userNickname.(string),
-			AdID:         adID,
-		}
-		if err := database.DB.Create(&viewedAd).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения просмотра"})
-			return
-		}
+	// Уже просмотренное поднимается наверх истории, новое добавляется
+	if err := services.Ads.RecordView(userNickname.(string), ad.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения просмотра"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Объявление добавлено в просмотренные"})
@@ -261,11 +272,14 @@ func AddViewedAd(c *gin.Context) {
 
 // GetViewedAds godoc
 // @Summary История просмотров
-// @Description Возвращает историю просмотренных объявлений пользователя
+// @Description Возвращает историю просмотренных объявлений пользователя, сверху последние. По 20 штук, дальше по курсору из page
 // @Tags Просмотренное
 // @Security BearerAuth
 // @Produce json
+// @Param limit query int false "Сколько вернуть (по умолчанию 20, максимум 100)"
+// @Param cursor query string false "Курсор из page.next_cursor или page.prev_cursor"
 // @Success 200 {object} map[string]interface{} "Список просмотренных объявлений"
+// @Failure 400 {object} map[string]string "Битый курсор"
 // @Failure 401 {object} map[string]string "Не авторизован"
 // @Failure 500 {object} map[string]string "Ошибка загрузки"
 // @Router /viewed-ads [get]
@@ -276,82 +290,19 @@ func GetViewedAds(c *gin.Context) {
 		return
 	}
 
-	// Получаем viewed_ads с соответствующими объявлениями
-	var viewedRecords []models.ViewedAd
-	result := database.DB.Where("user_nickname = ?", userNickname.(string)).
-		Order("viewed_at DESC").
-		Find(&viewedRecords)
-
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения просмотренных: %v", result.Error)})
+	viewedAds, page, err := services.Ads.Viewed(userNickname.(string), parsePageRequest(c, services.DefaultPageSize))
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный курсор, начни с первой страницы"})
 		return
 	}
-
-	// Создаем ответ с полной информацией об объявлениях
-	type AdWithAuthor struct {
-		models.Ad
-		AuthorAvatar  string  `json:"author_avatar"`
-		AuthorRating  float32 `json:"author_rating"`
-		OwnerTelegram string  `json:"owner_telegram"`
-	}
-
-	type ViewedAdResponse struct {
-		ID           uint          `json:"id"`
-		UserNickname string        `json:"user_nickname"`
-		AdID         int           `json:"ad_id"`
-		ViewedAt     time.Time     `json:"viewed_at"`
-		Ad           *AdWithAuthor `json:"Ad"`
-	}
-
-	viewedAds := make([]ViewedAdResponse, 0, len(viewedRecords))
-	for _, viewed := range viewedRecords {
-		var ad AdWithAuthor
-		err := database.DB.Table("ads").
-			Select("ads.*, accounts.avatar as author_avatar, accounts.rating as author_rating, accounts.telegram as owner_telegram").
-			Joins("LEFT JOIN accounts ON ads.nickname = accounts.nickname").
-			Where("ads.id = ?", viewed.AdID).
-			First(&ad).Error
-
-		if err == nil {
-			viewedAds = append(viewedAds, ViewedAdResponse{
-				ID:           viewed.ID,
-				UserNickname: viewed.UserNickname,
-				AdID:         viewed.AdID,
-				ViewedAt:     viewed.ViewedAt,
-				Ad:           &ad,
-			})
-		}
-	}
-
-	c.JSON(http.StatusOK, gin.H{"viewed_ads": viewedAds})
-}
-
-// UpdateUserRating пересчитывает средний рейтинг пользователя на основе подтвержденных отзывов
-func UpdateUserRating(nickname string) error {
-	// Получаем все подтвержденные отзывы для данного пользователя
-	var feedbacks []models.FeedbackAd
-	result := database.DB.Where("ad_owner_nickname = ? AND confirm_feedback = ?", nickname, true).Find(&feedbacks)
-
-	if result.Error != nil {
-		return result.Error
-	}
-
-	// Если нет отзывов, устанавливаем рейтинг 0
-	if len(feedbacks) == 0 {
-		return database.DB.Model(&models.Account{}).
-			Where("nickname = ?", nickname).
-			Update("rating", 0).Error
-	}
-
-	// Вычисляем средний рейтинг
-	var totalRating int
-	for _, feedback := range feedbacks {
-		totalRating += feedback.Rating
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения просмотренных: %v", err)})
+		return
 	}
-	averageRating := float32(totalRating) / float32(len(feedbacks))
 
-	// Обновляем рейтинг пользователя
-	return database.DB.Model(&models.Account{}).
-		Where("nickname = ?", nickname).
-		Update("rating", averageRating).Error
+	setPageHeaders(c, "viewed_ads", page)
+	c.JSON(http.StatusOK, gin.H{
+		"viewed_ads": viewedAds,
+		"page":       page,
+	})
 }
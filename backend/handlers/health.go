@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"arizonagamesstore/backend/database"
+	"arizonagamesstore/backend/storage"
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readyCheckTimeout - сколько ждем БД и хранилище, прежде чем сказать "не готов"
+const readyCheckTimeout = 2 * time.Second
+
+// Healthz - liveness проба: процесс жив и отвечает. Внешние зависимости не трогает,
+// иначе оркестратор перезапускал бы сервер каждый раз, когда моргнет база.
+// Лежит вне /api, поэтому в swagger ее нет
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz - readiness проба: можно ли слать трафик. Пингует БД, а с ?storage=1
+// еще и хранилище файлов (для S3 это лишний запрос, поэтому по умолчанию выключено)
+func Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readyCheckTimeout)
+	defer cancel()
+
+	checks := gin.H{}
+	ready := true
+
+	if err := pingDatabase(ctx); err != nil {
+		slog.WarnContext(ctx, "Readyz: БД недоступна", "error", err)
+		checks["database"] = "unavailable"
+		ready = false
+	} else {
+		checks["database"] = "ok"
+	}
+
+	if c.Query("storage") == "1" {
+		if checker, ok := storage.Files.(storage.Checker); ok {
+			if err := checker.Check(ctx); err != nil {
+				slog.WarnContext(ctx, "Readyz: хранилище недоступно", "error", err)
+				checks["storage"] = "unavailable"
+				ready = false
+			} else {
+				checks["storage"] = "ok"
+			}
+		}
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "checks": checks})
+}
+
+func pingDatabase(ctx context.Context) error {
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateMessageBody обрезает пробелы и проверяет длину сообщения.
+// Возвращает текст ошибки для пользователя или пустую строку
+func validateMessageBody(body string) (string, string) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", "Сообщение не может быть пустым"
+	}
+	if utf8.RuneCountInString(body) > models.MaxMessageLength {
+		return "", fmt.Sprintf("Сообщение слишком длинное (максимум %d символов)", models.MaxMessageLength)
+	}
+	return body, ""
+}
+
+// respondMessagingError переводит ошибки переписки в HTTP ответ
+func respondMessagingError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrConversationNotFound), errors.Is(err, services.ErrNotConversationMember):
+		// Чужую переписку не показываем даже по ID, чтобы не палить ее существование
+		c.JSON(http.StatusNotFound, gin.H{"error": "Переписка не найдена"})
+	case errors.Is(err, services.ErrAdNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Объявление не найдено"})
+	case errors.Is(err, services.ErrMessageOwnAd):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя написать самому себе"})
+	case errors.Is(err, services.ErrAdNotAvailable):
+		c.JSON(http.StatusConflict, gin.H{"error": "Объявление неактивно, начать переписку нельзя"})
+	case errors.Is(err, services.ErrUserBlocked):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Пользователь ограничил отправку сообщений"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s: %v", fallback, err)})
+	}
+}
+
+// StartConversation godoc
+// @Summary Написать продавцу
+// @Description Открывает переписку с продавцом по объявлению. Если переписка уже есть, вернется она же. Можно сразу передать первое сообщение
+// @Tags Сообщения
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID объявления"
+// @Param request body map[string]string false "Первое сообщение" example(message="Здравствуйте, дом еще продается?")
+// @Success 200 {object} map[string]interface{} "Переписка уже была"
+// @Success 201 {object} map[string]interface{} "Переписка создана"
+// @Failure 400 {object} map[string]string "Свое объявление или пустое сообщение"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 403 {object} map[string]string "Продавец ограничил сообщения"
+// @Failure 404 {object} map[string]string "Объявление не найдено"
+// @Failure 409 {object} map[string]string "Объявление неактивно"
+// @Failure 429 {object} map[string]string "Слишком много сообщений"
+// @Router /ads/{id}/conversations [post]
+func StartConversation(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	adID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || adID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID объявления"})
+		return
+	}
+
+	var req struct {
+		Message string `json:"message"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	var body string
+	if strings.TrimSpace(req.Message) != "" {
+		var errMsg string
+		if body, errMsg = validateMessageBody(req.Message); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+	}
+
+	conversation, created, err := services.Messages.Start(uint(adID), nickname.(string))
+	if err != nil {
+		respondMessagingError(c, err, "Ошибка создания переписки")
+		return
+	}
+
+	response := gin.H{"conversation": conversation}
+	if body != "" {
+		message, err := services.Messages.Send(conversation.ID, nickname.(string), body)
+		if err != nil {
+			respondMessagingError(c, err, "Ошибка отправки сообщения")
+			return
+		}
+		response["message"] = message
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, response)
+}
+
+// GetConversations godoc
+// @Summary Мои переписки
+// @Description Возвращает все переписки пользователя (и как покупателя, и как продавца) с последним сообщением и количеством непрочитанных
+// @Tags Сообщения
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Список переписок и общее число непрочитанных"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /conversations [get]
+func GetConversations(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	conversations, err := services.Messages.List(nickname.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения переписок: %v", err)})
+		return
+	}
+
+	var unread int64
+	for _, conversation := range conversations {
+		unread += conversation.UnreadCount
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversations": conversations,
+		"unread_total":  unread,
+	})
+}
+
+// GetConversationMessages godoc
+// @Summary Сообщения переписки
+// @Description Возвращает сообщения переписки от старых к новым. Для подгрузки истории передай before_id = ID самого старого загруженного сообщения
+// @Tags Сообщения
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "ID переписки"
+// @Param limit query int false "Сколько сообщений вернуть (по умолчанию 50, максимум 100)"
+// @Param before_id query int false "Вернуть сообщения старше этого ID"
+// @Success 200 {object} map[string]interface{} "Переписка и сообщения"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 404 {object} map[string]string "Переписка не найдена"
+// @Router /conversations/{id}/messages [get]
+func GetConversationMessages(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || conversationID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID переписки"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	beforeID, err := strconv.Atoi(c.DefaultQuery("before_id", "0"))
+	if err != nil || beforeID < 0 {
+		beforeID = 0
+	}
+
+	conversation, err := services.Messages.GetForMember(uint(conversationID), nickname.(string))
+	if err != nil {
+		respondMessagingError(c, err, "Ошибка получения переписки")
+		return
+	}
+
+	messages, err := services.Messages.History(conversation.ID, limit, uint(beforeID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения сообщений: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation": conversation,
+		"messages":     messages,
+	})
+}
+
+// SendMessage godoc
+// @Summary Отправить сообщение
+// @Description Отправляет сообщение в переписку. Не больше 20 сообщений в минуту
+// @Tags Сообщения
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID переписки"
+// @Param request body map[string]string true "Текст сообщения" example(body="Могу посмотреть сегодня вечером")
+// @Success 201 {object} map[string]interface{} "Сообщение отправлено"
+// @Failure 400 {object} map[string]string "Пустое или слишком длинное сообщение"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 403 {object} map[string]string "Собеседник ограничил сообщения"
+// @Failure 404 {object} map[string]string "Переписка не найдена"
+// @Failure 429 {object} map[string]string "Слишком много сообщений"
+// @Router /conversations/{id}/messages [post]
+func SendMessage(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || conversationID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID переписки"})
+		return
+	}
+
+	var req struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректные данные"})
+		return
+	}
+
+	body, errMsg := validateMessageBody(req.Body)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	message, err := services.Messages.Send(uint(conversationID), nickname.(string), body)
+	if err != nil {
+		respondMessagingError(c, err, "Ошибка отправки сообщения")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": message})
+}
+
+// MarkConversationRead godoc
+// @Summary Прочитать переписку
+// @Description Помечает прочитанными все входящие сообщения в переписке
+// @Tags Сообщения
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "ID переписки"
+// @Success 200 {object} map[string]interface{} "Сколько сообщений помечено"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 404 {object} map[string]string "Переписка не найдена"
+// @Router /conversations/{id}/read [post]
+func MarkConversationRead(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	conversationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || conversationID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID переписки"})
+		return
+	}
+
+	marked, err := services.Messages.MarkRead(uint(conversationID), nickname.(string))
+	if err != nil {
+		respondMessagingError(c, err, "Ошибка обновления сообщений")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked_read": marked})
+}
+
+// GetBlockedUsers godoc
+// @Summary Черный список
+// @Description Возвращает пользователей, которым запрещено писать тебе
+// @Tags Сообщения
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Черный список"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Router /blocks [get]
+func GetBlockedUsers(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	blocks, err := services.Messages.BlockedUsers(nickname.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения черного списка: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"blocks": blocks})
+}
+
+// BlockUser godoc
+// @Summary Заблокировать пользователя
+// @Description Запрещает пользователю писать тебе. Ты ему писать тоже не сможешь, пока не разблокируешь
+// @Tags Сообщения
+// @Security BearerAuth
+// @Produce json
+// @Param nickname path string true "Никнейм пользователя"
+// @Success 200 {object} map[string]string "Пользователь заблокирован"
+// @Failure 400 {object} map[string]string "Нельзя заблокировать себя"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 404 {object} map[string]string "Пользователь не найден"
+// @Failure 409 {object} map[string]string "Уже заблокирован"
+// @Router /blocks/{nickname} [post]
+func BlockUser(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	target := c.Param("nickname")
+	if target == nickname.(string) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя заблокировать самого себя"})
+		return
+	}
+
+	if _, err := services.Accounts.GetByNickname(target); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден"})
+		return
+	}
+
+	if err := services.Messages.Block(nickname.(string), target); err != nil {
+		if errors.Is(err, services.ErrAlreadyBlocked) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Пользователь уже заблокирован"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка блокировки: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Пользователь заблокирован"})
+}
+
+// UnblockUser godoc
+// @Summary Разблокировать пользователя
+// @Description Убирает пользователя из черного списка
+// @Tags Сообщения
+// @Security BearerAuth
+// @Produce json
+// @Param nickname path string true "Никнейм пользователя"
+// @Success 200 {object} map[string]string "Пользователь разблокирован"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 404 {object} map[string]string "Пользователь не в черном списке"
+// @Router /blocks/{nickname} [delete]
+func UnblockUser(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	removed, err := services.Messages.Unblock(nickname.(string), c.Param("nickname"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка разблокировки: %v", err)})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не в черном списке"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Пользователь разблокирован"})
+}
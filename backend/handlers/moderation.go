@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/services"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type moderationRequest struct {
+	Comment string `json:"comment"`
+}
+
+// bindModerationComment достает необязательный комментарий модератора из тела запроса
+func bindModerationComment(c *gin.Context) *string {
+	var req moderationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil
+	}
+
+	comment := strings.TrimSpace(req.Comment)
+	if comment == "" {
+		return nil
+	}
+	return &comment
+}
+
+// GetReportQueue godoc
+// @Summary Очередь жалоб
+// @Description Возвращает жалобы, сгруппированные по объявлениям: сколько жалоб и с какими причинами. Сверху самые "горячие" объявления. Только для модераторов и админов
+// @Tags Модерация
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Статус жалоб (по умолчанию pending)" Enums(pending, dismissed, resolved)
+// @Param limit query int false "Сколько объявлений вернуть (по умолчанию 20)"
+// @Param offset query int false "Сколько пропустить (по умолчанию 0)"
+// @Success 200 {object} map[string]interface{} "Очередь модерации"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 403 {object} map[string]string "Недостаточно прав"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /admin/reports [get]
+func GetReportQueue(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReportStatusPending)
+	if status != models.ReportStatusPending && status != models.ReportStatusDismissed && status != models.ReportStatusResolved {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестный статус жалобы"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	queue, err := services.Reports.GetQueue(status, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения жалоб: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": queue})
+}
+
+// GetAdReports godoc
+// @Summary Жалобы на объявление
+// @Description Возвращает все жалобы на конкретное объявление и историю решений по нему
+// @Tags Модерация
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "ID объявления"
+// @Success 200 {object} map[string]interface{} "Жалобы и решения"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 403 {object} map[string]string "Недостаточно прав"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /admin/ads/{id}/reports [get]
+func GetAdReports(c *gin.Context) {
+	adID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || adID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID объявления"})
+		return
+	}
+
+	reports, err := services.Reports.ListByAd(uint(adID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения жалоб: %v", err)})
+		return
+	}
+
+	decisions, err := services.Reports.GetDecisions(uint(adID), "", 100, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения решений: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports":   reports,
+		"decisions": decisions,
+	})
+}
+
+// GetModerationDecisions godoc
+// @Summary Журнал модерации
+// @Description Возвращает историю решений модераторов: кто, что и когда сделал
+// @Tags Модерация
+// @Security BearerAuth
+// @Produce json
+// @Param ad_id query int false "Фильтр по объявлению"
+// @Param moderator query string false "Фильтр по модератору"
+// @Param limit query int false "Сколько вернуть (по умолчанию 50)"
+// @Param offset query int false "Сколько пропустить (по умолчанию 0)"
+// @Success 200 {object} map[string]interface{} "Список решений"
+// @Failure 403 {object} map[string]string "Недостаточно прав"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /admin/decisions [get]
+func GetModerationDecisions(c *gin.Context) {
+	var adID uint
+	if adIDStr := c.Query("ad_id"); adIDStr != "" {
+		id, err := strconv.Atoi(adIDStr)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID объявления"})
+			return
+		}
+		adID = uint(id)
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	decisions, err := services.Reports.GetDecisions(adID, c.Query("moderator"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения решений: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"decisions": decisions})
+}
+
+// DismissReport godoc
+// @Summary Отклонить жалобу
+// @Description Отклоняет жалобу как необоснованную. Объявление не трогается
+// @Tags Модерация
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID жалобы"
+// @Param request body map[string]string false "Комментарий модератора" example(comment="Нарушений не найдено")
+// @Success 200 {object} map[string]interface{} "Жалоба отклонена"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 403 {object} map[string]string "Недостаточно прав"
+// @Failure 404 {object} map[string]string "Жалоба не найдена"
+// @Failure 409 {object} map[string]string "Жалоба уже рассмотрена"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /admin/reports/{id}/dismiss [post]
+func DismissReport(c *gin.Context) {
+	moderator, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	reportID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || reportID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID жалобы"})
+		return
+	}
+
+	decision, err := services.Reports.Dismiss(uint(reportID), moderator.(string), bindModerationComment(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrReportNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Жалоба не найдена"})
+		case errors.Is(err, services.ErrReportAlreadyResolved):
+			c.JSON(http.StatusConflict, gin.H{"error": "Жалоба уже рассмотрена"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка отклонения жалобы: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Жалоба отклонена",
+		"decision": decision,
+	})
+}
+
+// HideReportedAd godoc
+// @Summary Скрыть объявление
+// @Description Скрывает объявление из ленты и закрывает все открытые жалобы на него
+// @Tags Модерация
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID объявления"
+// @Param request body map[string]string false "Комментарий модератора" example(comment="Подозрение на мошенничество")
+// @Success 200 {object} map[string]interface{} "Объявление скрыто"
+// @Failure 403 {object} map[string]string "Недостаточно прав"
+// @Failure 404 {object} map[string]string "Объявление не найдено"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /admin/ads/{id}/hide [post]
+func HideReportedAd(c *gin.Context) {
+	applyModerationAction(c, models.ModerationActionHide, "Объявление скрыто")
+}
+
+// DeleteReportedAd godoc
+// @Summary Удалить объявление (модерация)
+// @Description Убирает объявление в архив и закрывает все открытые жалобы на него. Из базы объявление не удаляется, чтобы не пропали отзывы о продавце. Вернуть его продавец не сможет. Решение остается в журнале модерации
+// @Tags Модерация
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID объявления"
+// @Param request body map[string]string false "Комментарий модератора" example(comment="Мошенничество")
+// @Success 200 {object} map[string]interface{} "Объявление удалено"
+// @Failure 403 {object} map[string]string "Недостаточно прав"
+// @Failure 404 {object} map[string]string "Объявление не найдено"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /admin/ads/{id} [delete]
+func DeleteReportedAd(c *gin.Context) {
+	applyModerationAction(c, models.ModerationActionDelete, "Объявление удалено")
+}
+
+// WarnAdAuthor godoc
+// @Summary Предупредить автора
+// @Description Выносит предупреждение автору объявления и закрывает все открытые жалобы на него. Объявление остается в ленте
+// @Tags Модерация
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "ID объявления"
+// @Param request body map[string]string false "Комментарий модератора" example(comment="Не указывайте контакты в описании")
+// @Success 200 {object} map[string]interface{} "Предупреждение вынесено"
+// @Failure 403 {object} map[string]string "Недостаточно прав"
+// @Failure 404 {object} map[string]string "Объявление не найдено"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /admin/ads/{id}/warn [post]
+func WarnAdAuthor(c *gin.Context) {
+	applyModerationAction(c, models.ModerationActionWarn, "Предупреждение вынесено")
+}
+
+func applyModerationAction(c *gin.Context, action string, message string) {
+	moderator, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	adID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || adID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID объявления"})
+		return
+	}
+
+	ad, decision, err := services.Reports.ApplyAdDecision(uint(adID), action, moderator.(string), bindModerationComment(c))
+	if err != nil {
+		if errors.Is(err, services.ErrAdNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Объявление не найдено"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка модерации: %v", err)})
+		return
+	}
+
+	// В счетчике категории учитываются только активные объявления
+	if (action == models.ModerationActionHide || action == models.ModerationActionDelete) && ad.Status == models.AdStatusActive {
+		if err := services.Ads.Unlisted(ad.Category); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Ошибка обновления статистики", "category", ad.Category, "error", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  message,
+		"decision": decision,
+	})
+}
+
+// GetReportConversations godoc
+// @Summary Переписка по жалобе
+// @Description Возвращает всю переписку покупателей с продавцом по объявлению, на которое пожаловались. Нужна для разбора спорных ситуаций
+// @Tags Модерация
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "ID жалобы"
+// @Success 200 {object} map[string]interface{} "Жалоба и переписки"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 403 {object} map[string]string "Недостаточно прав"
+// @Failure 404 {object} map[string]string "Жалоба не найдена"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /admin/reports/{id}/conversations [get]
+func GetReportConversations(c *gin.Context) {
+	reportID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || reportID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID жалобы"})
+		return
+	}
+
+	report, conversations, err := services.Messages.ReportConversations(uint(reportID))
+	if err != nil {
+		if errors.Is(err, services.ErrReportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Жалоба не найдена"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения переписки: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report":        report,
+		"conversations": conversations,
+	})
+}
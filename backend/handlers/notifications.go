@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"arizonagamesstore/backend/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotifications godoc
+// @Summary Уведомления
+// @Description Возвращает уведомления пользователя, сверху новые: отзывы, решения по жалобам, истекающие объявления и т.д. Общее количество в заголовке X-Total-Count и в поле total
+// @Tags Уведомления
+// @Security BearerAuth
+// @Produce json
+// @Param unread query bool false "Только непрочитанные"
+// @Param limit query int false "Сколько вернуть (по умолчанию 20, максимум 100)"
+// @Param offset query int false "Сколько пропустить (по умолчанию 0)"
+// @Success 200 {object} map[string]interface{} "Список уведомлений"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /notifications [get]
+func GetNotifications(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, total, err := services.Notifications.List(nickname.(string), unreadOnly, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения уведомлений: %v", err)})
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"total":         total,
+	})
+}
+
+// GetUnreadNotificationsCount godoc
+// @Summary Счетчик непрочитанных уведомлений
+// @Description Возвращает количество непрочитанных уведомлений - для красной точки на колокольчике
+// @Tags Уведомления
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]int "Количество непрочитанных"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /notifications/unread-count [get]
+func GetUnreadNotificationsCount(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	count, err := services.Notifications.CountUnread(nickname.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка подсчета уведомлений: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": count})
+}
+
+// MarkNotificationRead godoc
+// @Summary Прочитать уведомление
+// @Description Помечает одно уведомление прочитанным
+// @Tags Уведомления
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "ID уведомления"
+// @Success 200 {object} map[string]string "Уведомление прочитано"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 404 {object} map[string]string "Уведомление не найдено"
+// @Router /notifications/{id}/read [post]
+func MarkNotificationRead(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	notificationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || notificationID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID уведомления"})
+		return
+	}
+
+	if err := services.Notifications.MarkRead(uint(notificationID), nickname.(string)); err != nil {
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Уведомление не найдено"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка обновления уведомления: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Уведомление прочитано"})
+}
+
+// MarkAllNotificationsRead godoc
+// @Summary Прочитать все уведомления
+// @Description Помечает прочитанными все уведомления пользователя
+// @Tags Уведомления
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Сколько уведомлений помечено"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /notifications/read-all [post]
+func MarkAllNotificationsRead(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	marked, err := services.Notifications.MarkAllRead(nickname.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка обновления уведомлений: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked_read": marked})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"arizonagamesstore/backend/services"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parsePageRequest читает limit, cursor и offset. offset нужен только старым клиентам,
+// с курсором он игнорируется
+func parsePageRequest(c *gin.Context, defaultLimit int) services.PageRequest {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > services.MaxPageSize {
+		limit = services.MaxPageSize
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	return services.PageRequest{
+		Limit:  limit,
+		Cursor: c.Query("cursor"),
+		Offset: offset,
+		Seed:   c.Query("seed"),
+	}
+}
+
+// setPageHeaders выставляет X-Total-Count и Content-Range (например "ads 20-39/125")
+func setPageHeaders(c *gin.Context, unit string, page *services.Page) {
+	c.Header("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if page.Count == 0 {
+		c.Header("Content-Range", fmt.Sprintf("%s */%d", unit, page.Total))
+		return
+	}
+	c.Header("Content-Range", fmt.Sprintf("%s %d-%d/%d", unit, page.Start, page.Start+int64(page.Count)-1, page.Total))
+}
@@ -1,11 +1,11 @@
 package handlers
 
 import (
+	"arizonagamesstore/backend/imaging"
 	"arizonagamesstore/backend/services"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -13,7 +13,7 @@ import (
 
 // UpdateProfileBackground godoc
 // @Summary Изменить фон профиля
-// @Description Загружает новый фон для профиля на S3. Макс. размер 10MB
+// @Description Загружает новый фон для профиля в файловое хранилище. Макс. размер 10MB
 // @Tags Профиль
 // @Security BearerAuth
 // @Accept multipart/form-data
@@ -22,7 +22,7 @@ import (
 // @Success 200 {object} map[string]string "URL нового фона"
 // @Failure 400 {object} map[string]string "Файл не загружен или слишком большой"
 // @Failure 401 {object} map[string]string "Не авторизован"
-// @Failure 500 {object} map[string]string "Ошибка загрузки на S3"
+// @Failure 500 {object} map[string]string "Ошибка загрузки в хранилище"
 // @Router /profile/update-background [post]
 func UpdateProfileBackground(c *gin.Context) {
 	// Получаем nickname из контекста
@@ -33,7 +33,7 @@ func UpdateProfileBackground(c *gin.Context) {
 	}
 
 	// Получаем текущего пользователя из БД
-	user, err := services.GetUserByNickname(nickname.(string))
+	user, err := services.Accounts.GetByNickname(nickname.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения данных пользователя"})
 		return
@@ -52,59 +52,34 @@ func UpdateProfileBackground(c *gin.Context) {
 		return
 	}
 
-	// Проверка типа файла
-	if !strings.HasPrefix(file.Header.Get("Content-Type"), "image/") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Файл должен быть изображением"})
-		return
-	}
-
-	// Генерируем уникальное имя файла
-	uniqueFileName := generateUniqueFileName(file.Filename)
-	imagePath := fmt.Sprintf("profile-backgrounds/%s/%s", user.Nickname, uniqueFileName)
-
-	// Создаем временную директорию
-	tempDir := "./temp_uploads"
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания временной директории"})
-		return
-	}
-
-	// Сохраняем файл временно
-	tempFilePath := filepath.Join(tempDir, uniqueFileName)
-	if err := c.SaveUploadedFile(file, tempFilePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения файла"})
-		return
-	}
-	defer os.Remove(tempFilePath)
-
-	// Удаляем старое изображение из S3, если оно есть
-	if user.BackgroundAvatarProfile != "" {
-		oldKey := extractS3KeyFromURL(user.BackgroundAvatarProfile)
-		if oldKey != "" {
-			if err := DeleteFileFromS3(oldKey); err != nil {
-				fmt.Printf("Предупреждение: не удалось удалить старый файл из S3: %v\n", err)
-				// Продолжаем выполнение, даже если удаление не удалось
-			}
+	// Загружаем новое изображение в хранилище. Тип файла проверяется
+	// по содержимому, а не по Content-Type от браузера
+	background, errUpload := uploadImage(c.Request.Context(), file, "profile-backgrounds/"+user.Nickname, imaging.BackgroundImage)
+	if errUpload != nil {
+		if msg := imageErrorMessage(errUpload, imaging.BackgroundImage); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
 		}
-	}
-
-	// Загружаем новое изображение в S3
-	fmt.Println("Загружаем фон профиля в S3")
-	publicURL, errS3 := UploadFileToS3(tempFilePath, imagePath)
-	if errS3 != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка загрузки в S3: %v", errS3)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка загрузки в хранилище: %v", errUpload)})
 		return
 	}
-	fmt.Println("Фон профиля загружен:", publicURL)
+	publicURL := background.URL
+	slog.DebugContext(c.Request.Context(), "Фон профиля загружен", "url", publicURL)
 
 	// Обновляем БД через сервисный слой
-	if err := services.UpdateProfileBackground(nickname.(string), publicURL); err != nil {
+	if err := services.Accounts.UpdateProfileBackground(nickname.(string), publicURL); err != nil {
 		// Если обновление БД не удалось, удаляем только что загруженный файл
-		DeleteFileFromS3(imagePath)
+		deleteStoredFile(c.Request.Context(), publicURL)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления профиля"})
 		return
 	}
 
+	// Удаляем старое изображение, если оно есть
+	if err := deleteStoredFile(c.Request.Context(), user.BackgroundAvatarProfile); err != nil {
+		slog.WarnContext(c.Request.Context(), "Не удалось удалить старый фон из хранилища", "error", err)
+		// Продолжаем выполнение, даже если удаление не удалось
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message":                   "Фон профиля успешно обновлен",
 		"background_avatar_profile": publicURL,
@@ -130,7 +105,7 @@ func DeleteProfileBackground(c *gin.Context) {
 	}
 
 	// Получаем текущего пользователя
-	user, err := services.GetUserByNickname(nickname.(string))
+	user, err := services.Accounts.GetByNickname(nickname.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения данных пользователя"})
 		return
@@ -142,17 +117,14 @@ func DeleteProfileBackground(c *gin.Context) {
 		return
 	}
 
-	// Удаляем файл из S3
-	oldKey := extractS3KeyFromURL(user.BackgroundAvatarProfile)
-	if oldKey != "" {
-		if err := DeleteFileFromS3(oldKey); err != nil {
-			fmt.Printf("Предупреждение: не удалось удалить файл из S3: %v\n", err)
-			// Продолжаем, даже если удаление из S3 не удалось
-		}
+	// Удаляем файл из хранилища
+	if err := deleteStoredFile(c.Request.Context(), user.BackgroundAvatarProfile); err != nil {
+		slog.WarnContext(c.Request.Context(), "Не удалось удалить фон из хранилища", "error", err)
+		// Продолжаем, даже если удаление из хранилища не удалось
 	}
 
 	// Обновляем БД через сервисный слой - устанавливаем пустую строку
-	if err := services.DeleteProfileBackground(nickname.(string)); err != nil {
+	if err := services.Accounts.DeleteProfileBackground(nickname.(string)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления профиля"})
 		return
 	}
@@ -204,7 +176,7 @@ func UpdateTelegram(c *gin.Context) {
 	}
 
 	// Обновляем telegram через сервисный слой
-	if err := services.UpdateTelegram(nickname.(string), telegram); err != nil {
+	if err := services.Accounts.UpdateTelegram(nickname.(string), telegram); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления telegram"})
 		return
 	}
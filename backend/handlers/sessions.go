@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"arizonagamesstore/backend/services"
+	"arizonagamesstore/backend/utils"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSessions godoc
+// @Summary Активные сессии
+// @Description Возвращает все устройства, где выполнен вход: браузер, IP, когда заходили и когда последний раз пользовались. Текущее устройство помечено current
+// @Tags Сессии
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Список сессий"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /sessions [get]
+func GetSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	currentToken, _ := c.Cookie("refresh_token")
+
+	sessions, err := services.Sessions.List(userID.(uint), currentToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения сессий: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeSession godoc
+// @Summary Завершить сессию
+// @Description Выходит из аккаунта на выбранном устройстве. Там уже выданный access токен доживет свои 3 минуты, дальше попросит войти заново. Если завершить текущую сессию - это обычный выход
+// @Tags Сессии
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID сессии из списка сессий"
+// @Success 200 {object} map[string]string "Сессия завершена"
+// @Failure 400 {object} map[string]string "Неверный ID"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 404 {object} map[string]string "Сессия не найдена"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /sessions/{id} [delete]
+func RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	sessionID := c.Param("id")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID сессии"})
+		return
+	}
+
+	currentToken, _ := c.Cookie("refresh_token")
+
+	wasCurrent, err := services.Sessions.Revoke(userID.(uint), sessionID, currentToken)
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Сессия не найдена"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка завершения сессии: %v", err)})
+		return
+	}
+
+	if wasCurrent {
+		utils.SetAuthCookie(c, "access_token", "", -1)
+		utils.SetAuthCookie(c, "refresh_token", "", -1)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Сессия завершена"})
+}
+
+// RevokeAllSessions godoc
+// @Summary Выйти везде
+// @Description Завершает все сессии пользователя, включая текущую, и чистит cookies. Пригодится если потерял телефон или заходил с чужого компа
+// @Tags Сессии
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Сколько сессий завершено"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /sessions/revoke-all [post]
+func RevokeAllSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	revoked, err := services.Sessions.RevokeAll(userID.(uint), "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка завершения сессий: %v", err)})
+		return
+	}
+
+	utils.SetAuthCookie(c, "access_token", "", -1)
+	utils.SetAuthCookie(c, "refresh_token", "", -1)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Вы вышли на всех устройствах",
+		"revoked": revoked,
+	})
+}
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"arizonagamesstore/backend/imaging"
 	"arizonagamesstore/backend/services"
+	"arizonagamesstore/backend/utils"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -45,7 +47,7 @@ func UpdateNickname(c *gin.Context) {
 	}
 
 	// Получаем текущего пользователя
-	user, err := services.GetUserByNickname(nickname.(string))
+	user, err := services.Accounts.GetByNickname(nickname.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения данных пользователя"})
 		return
@@ -78,7 +80,7 @@ func UpdateNickname(c *gin.Context) {
 	}
 
 	// Проверка уникальности никнейма через сервисный слой
-	nicknameExists, err := services.CheckNicknameExists(req.Nickname)
+	nicknameExists, err := services.Accounts.NicknameExists(req.Nickname)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки никнейма"})
 		return
@@ -89,28 +91,38 @@ func UpdateNickname(c *gin.Context) {
 	}
 
 	// Обновляем никнейм
-	if err := services.UpdateUserNickname(nickname.(string), req.Nickname); err != nil {
+	if err := services.Accounts.UpdateNickname(nickname.(string), req.Nickname); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления никнейма"})
 		return
 	}
 
-	if err := services.UpdateNickNameAds(nickname.(string), req.Nickname); err != nil {
+	if err := services.Ads.RenameOwner(nickname.(string), req.Nickname); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления никнейма в ads"})
 		return
 	}
 
+	if err := services.Messages.RenameUser(nickname.(string), req.Nickname); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления никнейма в сообщениях"})
+		return
+	}
+
+	if err := services.Notifications.RenameUser(nickname.(string), req.Nickname); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления никнейма в уведомлениях"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Никнейм успешно обновлен"})
 }
 
 // UpdateEmail godoc
 // @Summary Изменить email
-// @Description Изменяет email пользователя. После изменения нужно будет заново подтвердить email
+// @Description Отправляет код на новый email. Адрес поменяется только после подтверждения кода через /profile/confirm-email, код живет 10 минут
 // @Tags Профиль
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param request body map[string]string true "Новый email" example(email="new@arizona.rp")
-// @Success 200 {object} map[string]string "Email обновлен! Проверь почту для подтверждения"
+// @Success 200 {object} map[string]string "Код отправлен на новый email"
 // @Failure 400 {object} map[string]string "Некорректный email"
 // @Failure 401 {object} map[string]string "Не авторизован"
 // @Failure 409 {object} map[string]string "Такой email уже используется"
@@ -133,7 +145,7 @@ func UpdateEmail(c *gin.Context) {
 	}
 
 	// Получаем текущего пользователя
-	user, err := services.GetUserByNickname(nickname.(string))
+	user, err := services.Accounts.GetByNickname(nickname.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения данных пользователя"})
 		return
@@ -153,29 +165,121 @@ func UpdateEmail(c *gin.Context) {
 		}
 	}
 
-	// Проверка уникальности email через сервисный слой
-	emailExists, err := services.CheckEmailExists(req.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки email"})
+	// Сам email поменяется только после кода с нового адреса
+	if err := services.EmailChanges.Request(user, req.Email); err != nil {
+		if errors.Is(err, services.ErrEmailTaken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Email уже используется"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка отправки кода на новый email"})
 		return
 	}
-	if emailExists {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email уже используется"})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Код подтверждения отправлен на новый email"})
+}
+
+// ConfirmEmailChange godoc
+// @Summary Подтвердить новый email
+// @Description Вводишь код, который пришел на новый адрес, и email меняется. На старый адрес уходит письмо со ссылкой отмены, она работает 7 дней. На код 5 попыток
+// @Tags Профиль
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body map[string]string true "Код из письма" example(code="123456")
+// @Success 200 {object} map[string]string "Email изменен"
+// @Failure 400 {object} map[string]string "Неверный код, он истек или email уже занят"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 429 {object} map[string]string "Слишком много попыток"
+// @Failure 500 {object} map[string]string "Ошибка обновления"
+// @Router /profile/confirm-email [post]
+func ConfirmEmailChange(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
 		return
 	}
 
-	// Обновляем email
-	if err := services.UpdateUserEmail(nickname.(string), req.Email); err != nil {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат. Укажите код."})
+		return
+	}
+
+	change, attemptsLeft, err := services.EmailChanges.Confirm(userID.(uint), req.Code)
+	switch {
+	case errors.Is(err, services.ErrEmailChangeCodeInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         fmt.Sprintf("Неверный код. Осталось попыток: %d", attemptsLeft),
+			"attempts_left": attemptsLeft,
+		})
+		return
+	case errors.Is(err, services.ErrEmailChangeTooManyTries):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Слишком много неверных попыток. Запросите смену email заново"})
+		return
+	case errors.Is(err, services.ErrEmailChangeNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Код истек или смена email не запрошена"})
+		return
+	case errors.Is(err, services.ErrEmailTaken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email уже используется"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления email"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Email успешно обновлен"})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email успешно обновлен",
+		"email":   change.NewEmail,
+	})
+}
+
+// UndoEmailChange godoc
+// @Summary Отменить смену email
+// @Description Ссылка из письма, которое приходит на старый адрес после смены. Возвращает старый email и завершает все сессии: если email менял не ты, угонщика выкинет
+// @Tags Профиль
+// @Produce json
+// @Param token query string true "Токен из ссылки"
+// @Success 200 {object} map[string]string "Старый email возвращен"
+// @Failure 400 {object} map[string]string "Ссылка неверная или истекла"
+// @Failure 409 {object} map[string]string "Старый email уже занят другим аккаунтом"
+// @Failure 500 {object} map[string]string "Ошибка обновления"
+// @Router /email-change/undo [get]
+func UndoEmailChange(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ссылка неверная или истекла"})
+		return
+	}
+
+	change, err := services.EmailChanges.Undo(token)
+	if err != nil {
+		if errors.Is(err, services.ErrEmailUndoInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Ссылка неверная или истекла"})
+			return
+		}
+		if errors.Is(err, services.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Старый email уже занят другим аккаунтом, напишите в поддержку"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка отмены смены email"})
+		return
+	}
+
+	utils.SetAuthCookie(c, "access_token", "", -1)
+	utils.SetAuthCookie(c, "refresh_token", "", -1)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Смена email отменена, все сессии завершены. Рекомендуем сменить пароль",
+		"email":   change.OldEmail,
+	})
 }
 
 // UpdatePassword godoc
 // @Summary Изменить пароль
-// @Description Изменяет пароль пользователя. Нужно ввести старый пароль для подтверждения
+// @Description Изменяет пароль пользователя. Нужно ввести старый пароль для подтверждения. Все сессии, кроме текущей, завершаются
 // @Tags Профиль
 // @Security BearerAuth
 // @Accept json
@@ -211,7 +315,7 @@ func UpdatePassword(c *gin.Context) {
 	}
 
 	// Получаем текущего пользователя
-	user, err := services.GetUserByNickname(nickname.(string))
+	user, err := services.Accounts.GetByNickname(nickname.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения данных пользователя"})
 		return
@@ -244,11 +348,17 @@ func UpdatePassword(c *gin.Context) {
 	}
 
 	// Обновляем пароль
-	if err := services.UpdateUserPassword(nickname.(string), string(hashedPassword)); err != nil {
+	if err := services.Accounts.UpdatePassword(nickname.(string), string(hashedPassword)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления пароля"})
 		return
 	}
 
+	// Со старым паролем могли зайти с чужого устройства, поэтому остальные сессии закрываем
+	currentToken, _ := c.Cookie("refresh_token")
+	if _, err := services.Sessions.RevokeAll(user.ID, currentToken); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Ошибка завершения сессий", "user", user.Nickname, "error", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Пароль успешно обновлен"})
 }
 
@@ -287,7 +397,7 @@ func UpdateTheme(c *gin.Context) {
 	}
 
 	// Обновляем тему
-	if err := services.UpdateUserTheme(nickname.(string), req.Theme); err != nil {
+	if err := services.Accounts.UpdateTheme(nickname.(string), req.Theme); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления темы"})
 		return
 	}
@@ -345,7 +455,7 @@ func UpdateDescription(c *gin.Context) {
 	}
 
 	// Получаем текущего пользователя для проверки cooldown
-	user, err := services.GetUserByNickname(nickname.(string))
+	user, err := services.Accounts.GetByNickname(nickname.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения данных пользователя"})
 		return
@@ -364,7 +474,7 @@ func UpdateDescription(c *gin.Context) {
 	}
 
 	// Обновляем описание
-	if err := services.UpdateUserDescription(nickname.(string), sanitized); err != nil {
+	if err := services.Accounts.UpdateDescription(nickname.(string), sanitized); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления описания"})
 		return
 	}
@@ -429,7 +539,7 @@ func containsDangerousPatterns(text string) bool {
 
 // UpdateProfileAvatar godoc
 // @Summary Изменить аватар
-// @Description Загружает новый аватар на S3. Макс. размер 5MB
+// @Description Загружает новый аватар в файловое хранилище. Макс. размер 5MB
 // @Tags Профиль
 // @Security BearerAuth
 // @Accept multipart/form-data
@@ -453,63 +563,37 @@ func UpdateProfileAvatar(c *gin.Context) {
 		return
 	}
 
-	allowedTypes := map[string]bool{
-		"image/jpeg": true,
-		"image/png":  true,
-		"image/gif":  true,
-	}
-	if !allowedTypes[file.Header.Get("Content-Type")] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Недопустимый тип файла. Разрешены только JPEG, PNG и GIF."})
-		return
-	}
-
 	if file.Size > 5*1024*1024 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Размер файла превышает 5 МБ."})
 		return
 	}
 
-	uniqueFileName := generateUniqueFileName(file.Filename)
-	imagePath := fmt.Sprintf("avatars/%s/%s", nickname.(string), uniqueFileName)
-
-	tempDir := "./temp_uploads"
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания временной директории"})
-		return
-	}
-
-	tempFilePath := filepath.Join(tempDir, uniqueFileName)
-	if err := c.SaveUploadedFile(file, tempFilePath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения файла"})
-		return
-	}
-
-	s3URL, err := UploadFileToS3(tempFilePath, imagePath)
+	// Формат определяется по содержимому файла, Content-Type от браузера не проверяем
+	avatar, err := uploadImage(c.Request.Context(), file, "avatars/"+nickname.(string), imaging.AvatarImage)
 	if err != nil {
+		if msg := imageErrorMessage(err, imaging.AvatarImage); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка загрузки файла в хранилище"})
 		return
 	}
+	avatarURL := avatar.URL
 
-	defer os.Remove(tempFilePath)
-
-	user, err := services.GetUserByNickname(nickname.(string))
+	user, err := services.Accounts.GetByNickname(nickname.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения данных пользователя"})
 		return
 	}
 
-	if user.Avatar != "" {
-		oldKey := extractS3KeyFromURL(user.Avatar)
-		if oldKey != "" {
-			if err := DeleteFileFromS3(oldKey); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления старого аватара"})
-				return
-			}
-		}
+	if err := deleteStoredFile(c.Request.Context(), user.Avatar); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления старого аватара"})
+		return
 	}
 
-	if err := services.UpdateUserAvatar(nickname.(string), s3URL); err != nil {
+	if err := services.Accounts.UpdateAvatar(nickname.(string), avatarURL); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления данных пользователя"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Аватар успешно обновлен", "avatar_url": s3URL})
+	c.JSON(http.StatusOK, gin.H{"message": "Аватар успешно обновлен", "avatar_url": avatarURL})
 }
@@ -28,7 +28,7 @@ func GetAdCount(c *gin.Context) {
 		return
 	}
 
-	count, err := services.GetAdCounts(req.CategoryName)
+	count, err := services.Ads.CategoryCount(req.CategoryName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка при получении статистики: %v", err)})
 		return
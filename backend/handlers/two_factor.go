@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"arizonagamesstore/backend/services"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// GetTwoFactorStatus godoc
+// @Summary Статус 2FA
+// @Description Включена ли двухфакторка и сколько резервных кодов осталось
+// @Tags 2FA
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} services.TwoFactorStatus "Статус 2FA"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /2fa/status [get]
+func GetTwoFactorStatus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	status, err := services.TwoFactor.Status(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Ошибка получения статуса 2FA: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// SetupTwoFactor godoc
+// @Summary Подключить 2FA
+// @Description Выдает секрет и otpauth:// ссылку для QR кода. Сканируешь в Google Authenticator, Aegis или любом другом приложении, потом подтверждаешь кодом через /2fa/confirm. До подтверждения вход работает как раньше
+// @Tags 2FA
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} services.TwoFactorSetup "Секрет и ссылка для QR"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 409 {object} map[string]string "2FA уже включена"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /2fa/setup [post]
+func SetupTwoFactor(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	user, err := services.Accounts.GetByNickname(nickname.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения данных пользователя"})
+		return
+	}
+
+	setup, err := services.TwoFactor.BeginSetup(user)
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Двухфакторная аутентификация уже включена"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка подключения 2FA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// ConfirmTwoFactor godoc
+// @Summary Подтвердить подключение 2FA
+// @Description Вводишь код из приложения, и 2FA включается. В ответе 10 резервных кодов - они показываются один раз, сохрани их. Каждый код одноразовый
+// @Tags 2FA
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body map[string]string true "Код из приложения" example(code="123456")
+// @Success 200 {object} map[string]interface{} "2FA включена, резервные коды"
+// @Failure 400 {object} map[string]string "Неверный код или настройка не начата"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 409 {object} map[string]string "2FA уже включена"
+// @Failure 429 {object} map[string]string "Слишком много попыток"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /2fa/confirm [post]
+func ConfirmTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат. Укажите код."})
+		return
+	}
+
+	backupCodes, err := services.TwoFactor.ConfirmSetup(userID.(uint), req.Code)
+	switch {
+	case errors.Is(err, services.ErrTwoFactorCodeInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный код. Проверь, что время на телефоне выставлено автоматически"})
+		return
+	case errors.Is(err, services.ErrTwoFactorSetupNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Сначала начните подключение 2FA"})
+		return
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Двухфакторная аутентификация уже включена"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка включения 2FA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Двухфакторная аутентификация включена. Сохраните резервные коды, больше мы их не покажем",
+		"backup_codes": backupCodes,
+	})
+}
+
+// DisableTwoFactor godoc
+// @Summary Выключить 2FA
+// @Description Выключает двухфакторку. Нужны пароль и код из приложения (или резервный код), чтобы угонщик с одной только сессией не мог ее снять
+// @Tags 2FA
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body map[string]string true "Пароль и код" example(password="MyPass123!" code="123456")
+// @Success 200 {object} map[string]string "2FA выключена"
+// @Failure 400 {object} map[string]string "Неверный код или 2FA не включена"
+// @Failure 401 {object} map[string]string "Неверный пароль"
+// @Failure 429 {object} map[string]string "Слишком много попыток"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /2fa/disable [post]
+func DisableTwoFactor(c *gin.Context) {
+	nickname, exists := c.Get("nickname")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат. Укажите пароль и код."})
+		return
+	}
+
+	user, err := services.Accounts.GetByNickname(nickname.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения данных пользователя"})
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный пароль"})
+		return
+	}
+
+	if err := services.TwoFactor.Disable(user.ID, req.Code); err != nil {
+		switch {
+		case errors.Is(err, services.ErrTwoFactorCodeInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный код"})
+		case errors.Is(err, services.ErrTwoFactorNotEnabled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Двухфакторная аутентификация не включена"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка выключения 2FA"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Двухфакторная аутентификация выключена"})
+}
+
+// RegenerateBackupCodes godoc
+// @Summary Новые резервные коды
+// @Description Выдает новые 10 резервных кодов, старые сразу перестают работать. Нужен код из приложения
+// @Tags 2FA
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body map[string]string true "Код из приложения" example(code="123456")
+// @Success 200 {object} map[string]interface{} "Новые резервные коды"
+// @Failure 400 {object} map[string]string "Неверный код или 2FA не включена"
+// @Failure 401 {object} map[string]string "Не авторизован"
+// @Failure 429 {object} map[string]string "Слишком много попыток"
+// @Failure 500 {object} map[string]string "Ошибка БД"
+// @Router /2fa/backup-codes [post]
+func RegenerateBackupCodes(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат. Укажите код."})
+		return
+	}
+
+	backupCodes, err := services.TwoFactor.RegenerateBackupCodes(userID.(uint), req.Code)
+	switch {
+	case errors.Is(err, services.ErrTwoFactorCodeInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный код"})
+		return
+	case errors.Is(err, services.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Двухфакторная аутентификация не включена"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания резервных кодов"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Новые резервные коды созданы, старые больше не действуют",
+		"backup_codes": backupCodes,
+	})
+}
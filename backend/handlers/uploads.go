@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"arizonagamesstore/backend/imaging"
+	"arizonagamesstore/backend/storage"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+
+	"github.com/google/uuid"
+)
+
+// storedImage - URL обработанной картинки и ее уменьшенных копий
+type storedImage struct {
+	URL      string
+	Variants map[string]string
+}
+
+// URLs возвращает все URL картинки, чтобы удалить их разом
+func (img *storedImage) URLs() []string {
+	urls := []string{img.URL}
+	for _, url := range img.Variants {
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+// uploadImage прогоняет картинку через imaging (проверка формата и разрешения,
+// удаление EXIF, перекодирование в JPEG) и кладет ее в хранилище как
+// <prefix>/<uuid>.jpg, а уменьшенные копии рядом как <uuid>_<variant>.jpg
+func uploadImage(ctx context.Context, file *multipart.FileHeader, prefix string, opts imaging.Options) (*storedImage, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file, %v", err)
+	}
+	defer src.Close()
+
+	processed, err := imaging.Process(src, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	name := uuid.New().String()
+	result := &storedImage{Variants: make(map[string]string, len(processed.Variants))}
+
+	url, err := storage.Files.Put(ctx, fmt.Sprintf("%s/%s.jpg", prefix, name), bytes.NewReader(processed.Data), int64(len(processed.Data)), imaging.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	result.URL = url
+
+	for variant, data := range processed.Variants {
+		url, err := storage.Files.Put(ctx, fmt.Sprintf("%s/%s_%s.jpg", prefix, name, variant), bytes.NewReader(data), int64(len(data)), imaging.ContentType)
+		if err != nil {
+			deleteStoredFiles(ctx, result.URLs()...)
+			return nil, err
+		}
+		result.Variants[variant] = url
+	}
+
+	return result, nil
+}
+
+// imageErrorMessage возвращает понятный пользователю текст, если картинка
+// отклонена пайплайном. Для остальных ошибок - пустая строка
+func imageErrorMessage(err error, opts imaging.Options) string {
+	switch {
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		return "Файл не является изображением. Разрешены JPEG, PNG, GIF и WebP"
+	case errors.Is(err, imaging.ErrImageTooSmall):
+		return fmt.Sprintf("Слишком маленькое разрешение изображения (минимум %dx%d)", opts.MinWidth, opts.MinHeight)
+	case errors.Is(err, imaging.ErrImageTooLarge):
+		if opts.MaxWidth > 0 {
+			return fmt.Sprintf("Слишком большое разрешение изображения (максимум %dx%d)", opts.MaxWidth, opts.MaxHeight)
+		}
+		return "Слишком большое разрешение изображения"
+	}
+	return ""
+}
+
+// deleteStoredFile удаляет файл по URL из БД. Файлы не из нашего хранилища
+// (дефолтный аватар, старые ссылки) просто пропускаются
+func deleteStoredFile(ctx context.Context, url string) error {
+	if url == "" {
+		return nil
+	}
+
+	key, ok := storage.Files.KeyFromURL(url)
+	if !ok {
+		return nil
+	}
+	return storage.Files.Delete(ctx, key)
+}
+
+// deleteStoredFiles удаляет несколько файлов, ошибки только логирует
+func deleteStoredFiles(ctx context.Context, urls ...string) {
+	for _, url := range urls {
+		if err := deleteStoredFile(ctx, url); err != nil {
+			slog.WarnContext(ctx, "Не удалось удалить файл из хранилища", "url", url, "error", err)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/config"
+	"arizonagamesstore/backend/mail"
+	"arizonagamesstore/backend/repository"
+	"arizonagamesstore/backend/scheduler"
+	"arizonagamesstore/backend/services"
+	"arizonagamesstore/backend/storage"
+	"arizonagamesstore/backend/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Тесты гоняют настоящий роутер из NewRouter, но без внешнего мира: репозитории
+// в памяти, файлы во временной папке, письма в mail.Memory, часы стоят на testStart
+
+var testStart = time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+type testServer struct {
+	router *gin.Engine
+	mail   *mail.Memory
+	clock  *clock.Fake
+}
+
+// flushMail прогоняет очередь писем, как это сделала бы задача send-emails
+func (s *testServer) flushMail(t *testing.T) {
+	t.Helper()
+	if err := services.Mail.ProcessOutbox(context.Background()); err != nil {
+		t.Fatalf("process outbox: %v", err)
+	}
+}
+
+var codePattern = regexp.MustCompile(`<div class="code">(\d{6})</div>`)
+
+// lastCode отправляет очередь и достает код из последнего письма на адрес to
+func (s *testServer) lastCode(t *testing.T, to string) string {
+	t.Helper()
+	s.flushMail(t)
+
+	sent := s.mail.Messages()
+	for i := len(sent) - 1; i >= 0; i-- {
+		if sent[i].To != to {
+			continue
+		}
+		if match := codePattern.FindStringSubmatch(sent[i].HTML); match != nil {
+			return match[1]
+		}
+	}
+	t.Fatalf("no code was mailed to %s", to)
+	return ""
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	cfg := &config.Config{
+		Env: "test",
+		Server: config.ServerConfig{
+			AppURL:      "http://localhost:8080",
+			CORSOrigins: []string{"http://localhost:3000"},
+		},
+		JWT: config.JWTConfig{
+			Secret:        "test-access-secret",
+			RefreshSecret: "test-refresh-secret",
+		},
+	}
+	utils.Init(cfg)
+	services.Init(cfg)
+
+	srv := &testServer{
+		mail:  mail.NewMemory(),
+		clock: clock.NewFake(testStart),
+	}
+	srv.router = NewRouter(cfg, Deps{
+		Repos: repository.NewMemory(),
+		Files: storage.NewLocal(t.TempDir(), "http://localhost:8080/uploads"),
+		Mail:  srv.mail,
+		Clock: srv.clock,
+		Jobs:  scheduler.New(),
+	})
+	return srv
+}
+
+// Лимитеры запросов общие на весь процесс и считают по IP,
+// поэтому каждый клиент приходит со своего адреса
+var lastClientIP uint32
+
+type client struct {
+	t        *testing.T
+	srv      *testServer
+	ip       string
+	nickname string
+	cookies  map[string]string
+}
+
+func (s *testServer) newClient(t *testing.T) *client {
+	n := atomic.AddUint32(&lastClientIP, 1)
+	return &client{
+		t:       t,
+		srv:     s,
+		ip:      fmt.Sprintf("10.%d.%d.%d", n>>16&0xff, n>>8&0xff, n&0xff),
+		cookies: make(map[string]string),
+	}
+}
+
+func (c *client) do(method string, path string, body io.Reader, contentType string) *httptest.ResponseRecorder {
+	c.t.Helper()
+
+	req := httptest.NewRequest(method, path, body)
+	req.RemoteAddr = c.ip + ":40000"
+	req.Header.Set("User-Agent", "harness-test")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for name, value := range c.cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	rec := httptest.NewRecorder()
+	c.srv.router.ServeHTTP(rec, req)
+
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.MaxAge < 0 || cookie.Value == "" {
+			delete(c.cookies, cookie.Name)
+		} else {
+			c.cookies[cookie.Name] = cookie.Value
+		}
+	}
+	return rec
+}
+
+func (c *client) postJSON(path string, payload interface{}) *httptest.ResponseRecorder {
+	c.t.Helper()
+	return c.sendJSON(http.MethodPost, path, payload)
+}
+
+func (c *client) sendJSON(method string, path string, payload interface{}) *httptest.ResponseRecorder {
+	c.t.Helper()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return c.do(method, path, bytes.NewReader(body), "application/json")
+}
+
+// sendForm шлет multipart форму. files - имя поля и содержимое файла
+func (c *client) sendForm(method string, path string, fields map[string]string, files map[string][]byte) *httptest.ResponseRecorder {
+	c.t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	for name, content := range files {
+		part, err := form.CreateFormFile(name, name+".png")
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		part.Write(content)
+	}
+	if err := form.Close(); err != nil {
+		c.t.Fatal(err)
+	}
+	return c.do(method, path, &body, form.FormDataContentType())
+}
+
+// signUp регистрирует и подтверждает аккаунт, после чего клиент уже залогинен
+func (s *testServer) signUp(t *testing.T, nickname string) *client {
+	t.Helper()
+
+	c := s.newClient(t)
+	email := nickname + "@example.com"
+	rec := c.postJSON("/api/register", gin.H{"nickname": nickname, "email": email, "password": "Secret123"})
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = c.postJSON("/api/verify-email", gin.H{"email": email, "code": s.lastCode(t, email)})
+	expectStatus(t, rec, http.StatusOK)
+
+	c.nickname = nickname
+	return c
+}
+
+// createAd публикует объявление от имени клиента и возвращает его ID
+func (c *client) createAd(title string) uint {
+	c.t.Helper()
+
+	rec := c.sendForm(http.MethodPost, "/api/createnewads", map[string]string{
+		"server":      "Phoenix",
+		"title":       title,
+		"description": "Тестовое объявление",
+		"type":        "Продать",
+		"currency":    "$",
+		"price":       "1000",
+		"category":    "house",
+		"nickname":    c.nickname,
+	}, map[string][]byte{"image": testImage(c.t)})
+	expectStatus(c.t, rec, http.StatusOK)
+
+	var mine struct {
+		Ads []struct {
+			ID    uint   `json:"id"`
+			Title string `json:"title"`
+		} `json:"ads"`
+	}
+	decode(c.t, c.do(http.MethodGet, "/api/ads/my", nil, ""), &mine)
+	for _, ad := range mine.Ads {
+		if ad.Title == title {
+			return ad.ID
+		}
+	}
+	c.t.Fatalf("ad %q is missing from /api/ads/my", title)
+	return 0
+}
+
+// sendFeedback оставляет отзыв на объявление. ID отзыва ненулевой, только если он создан
+func (c *client) sendFeedback(adID uint, rating int) (*httptest.ResponseRecorder, uint) {
+	c.t.Helper()
+
+	rec := c.sendForm(http.MethodPost, "/api/feedback", map[string]string{
+		"ad_id":       strconv.Itoa(int(adID)),
+		"rating":      strconv.Itoa(rating),
+		"review_text": "Все честно, рекомендую",
+	}, map[string][]byte{"proof_image": testImage(c.t)})
+	if rec.Code != http.StatusCreated {
+		return rec, 0
+	}
+
+	var created struct {
+		Feedback struct {
+			ID uint `json:"id"`
+		} `json:"feedback"`
+	}
+	decodeBody(c.t, rec, &created)
+	return rec, created.Feedback.ID
+}
+
+// review - sendFeedback, который обязан пройти
+func (c *client) review(adID uint, rating int) uint {
+	c.t.Helper()
+	rec, id := c.sendFeedback(adID, rating)
+	expectStatus(c.t, rec, http.StatusCreated)
+	return id
+}
+
+func (c *client) confirmFeedback(id uint) *httptest.ResponseRecorder {
+	c.t.Helper()
+	return c.do(http.MethodPut, fmt.Sprintf("/api/feedback/%d/confirm", id), nil, "")
+}
+
+// testImage - PNG 400x300, проходит ограничения на размер картинок объявлений
+func testImage(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	for x := 0; x < 400; x++ {
+		for y := 0; y < 300; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, want, rec.Body.String())
+	}
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, dest interface{}) {
+	t.Helper()
+	expectStatus(t, rec, http.StatusOK)
+	decodeBody(t, rec, dest)
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, dest interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), dest); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body.String(), err)
+	}
+}
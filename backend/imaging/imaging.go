@@ -0,0 +1,259 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+	FormatWEBP = "webp"
+)
+
+// ContentType - во что перекодируются все картинки
+const ContentType = "image/jpeg"
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrImageTooSmall     = errors.New("image resolution is too small")
+	ErrImageTooLarge     = errors.New("image resolution is too large")
+)
+
+// maxPixels защищает от "картинок-бомб": маленький файл с огромным разрешением
+const maxPixels = 40_000_000
+
+type Variant struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+}
+
+// Options описывает, что делать с картинкой. MinWidth/MinHeight и
+// MaxWidth/MaxHeight - жесткие ограничения, картинка вне них отклоняется.
+// FitWidth/FitHeight - мягкое ограничение: большая картинка уменьшается
+type Options struct {
+	MinWidth  int
+	MinHeight int
+	MaxWidth  int
+	MaxHeight int
+	FitWidth  int
+	FitHeight int
+	Quality   int
+	Variants  []Variant
+}
+
+const (
+	VariantThumb  = "thumb"
+	VariantMedium = "medium"
+)
+
+// AdImage - картинка объявления: разрешение от 300x200 до 1920x1080,
+// плюс превью для ленты и средний размер для карточки
+var AdImage = Options{
+	MinWidth:  300,
+	MinHeight: 200,
+	MaxWidth:  1920,
+	MaxHeight: 1080,
+	Quality:   85,
+	Variants: []Variant{
+		{Name: VariantThumb, MaxWidth: 320, MaxHeight: 240},
+		{Name: VariantMedium, MaxWidth: 800, MaxHeight: 600},
+	},
+}
+
+// AvatarImage - аватар, больше 512 пикселей на сайте нигде не нужно
+var AvatarImage = Options{
+	FitWidth:  512,
+	FitHeight: 512,
+	Quality:   85,
+}
+
+// BackgroundImage - фон профиля
+var BackgroundImage = Options{
+	FitWidth:  1920,
+	FitHeight: 1920,
+	Quality:   85,
+}
+
+// ProofImage - скриншот-доказательство к отзыву, важна читаемость текста
+var ProofImage = Options{
+	FitWidth:  2560,
+	FitHeight: 2560,
+	Quality:   90,
+}
+
+type Result struct {
+	Width    int
+	Height   int
+	Data     []byte
+	Variants map[string][]byte
+}
+
+// Detect определяет настоящий формат по первым байтам файла,
+// не доверяя ни расширению, ни Content-Type от клиента
+func Detect(head []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return FormatJPEG, nil
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG, nil
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return FormatGIF, nil
+	case len(head) >= 12 && bytes.Equal(head[0:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WEBP")):
+		return FormatWEBP, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Process проверяет картинку и перекодирует ее в JPEG. Метаданные (EXIF, GPS)
+// при этом пропадают, ориентация с телефона применяется к пикселям
+func Process(r io.Reader, opts Options) (*Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := Detect(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := decodeConfig(format, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+
+	img, err := decode(format, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+
+	if format == FormatJPEG {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if (opts.MinWidth > 0 && width < opts.MinWidth) || (opts.MinHeight > 0 && height < opts.MinHeight) {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooSmall, width, height)
+	}
+	if (opts.MaxWidth > 0 && width > opts.MaxWidth) || (opts.MaxHeight > 0 && height > opts.MaxHeight) {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, width, height)
+	}
+
+	main := flatten(img)
+	if opts.FitWidth > 0 && opts.FitHeight > 0 {
+		main = fit(main, opts.FitWidth, opts.FitHeight)
+	}
+
+	quality := opts.Quality
+	if quality == 0 {
+		quality = 85
+	}
+
+	result := &Result{
+		Width:    main.Bounds().Dx(),
+		Height:   main.Bounds().Dy(),
+		Variants: make(map[string][]byte, len(opts.Variants)),
+	}
+
+	if result.Data, err = encode(main, quality); err != nil {
+		return nil, err
+	}
+
+	for _, variant := range opts.Variants {
+		encoded, err := encode(fit(main, variant.MaxWidth, variant.MaxHeight), quality)
+		if err != nil {
+			return nil, err
+		}
+		result.Variants[variant.Name] = encoded
+	}
+
+	return result, nil
+}
+
+func decodeConfig(format string, data []byte) (image.Config, error) {
+	reader := bytes.NewReader(data)
+	switch format {
+	case FormatJPEG:
+		return jpeg.DecodeConfig(reader)
+	case FormatPNG:
+		return png.DecodeConfig(reader)
+	case FormatGIF:
+		return gif.DecodeConfig(reader)
+	case FormatWEBP:
+		return webp.DecodeConfig(reader)
+	}
+	return image.Config{}, ErrUnsupportedFormat
+}
+
+// decode для GIF берет только первый кадр
+func decode(format string, data []byte) (image.Image, error) {
+	reader := bytes.NewReader(data)
+	switch format {
+	case FormatJPEG:
+		return jpeg.Decode(reader)
+	case FormatPNG:
+		return png.Decode(reader)
+	case FormatGIF:
+		return gif.Decode(reader)
+	case FormatWEBP:
+		return webp.Decode(reader)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// flatten кладет картинку на белый фон: у JPEG нет прозрачности
+func flatten(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+	return dst
+}
+
+// fit уменьшает картинку, чтобы она влезла в maxWidth x maxHeight с сохранением пропорций.
+// Маленькие картинки не увеличиваются
+func fit(img *image.RGBA, maxWidth int, maxHeight int) *image.RGBA {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if width <= maxWidth && height <= maxHeight {
+		return img
+	}
+
+	scale := float64(maxWidth) / float64(width)
+	if heightScale := float64(maxHeight) / float64(height); heightScale < scale {
+		scale = heightScale
+	}
+
+	newWidth := max(1, int(float64(width)*scale+0.5))
+	newHeight := max(1, int(float64(height)*scale+0.5))
+
+	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	return dst
+}
+
+func encode(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,116 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// jpegOrientation достает тег Orientation (0x0112) из EXIF. Сам EXIF после
+// перекодирования пропадает, поэтому поворот надо применить к пикселям заранее.
+// Если тега нет или EXIF битый, возвращает 1 (как есть)
+func jpegOrientation(data []byte) int {
+	offset := 2
+	for offset+4 <= len(data) {
+		if data[offset] != 0xFF {
+			return 1
+		}
+		marker := data[offset+1]
+		// SOS - дальше идут сжатые данные, метаданных уже не будет
+		if marker == 0xDA {
+			return 1
+		}
+
+		segmentLength := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+		segmentStart := offset + 4
+		segmentEnd := offset + 2 + segmentLength
+		if segmentLength < 2 || segmentEnd > len(data) {
+			return 1
+		}
+
+		if marker == 0xE1 && segmentEnd-segmentStart > 6 && string(data[segmentStart:segmentStart+6]) == "Exif\x00\x00" {
+			return tiffOrientation(data[segmentStart+6 : segmentEnd])
+		}
+
+		offset = segmentEnd
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifdOffset := int(order.Uint32(tiff[4:8]))
+	if ifdOffset+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifdOffset : ifdOffset+2]))
+	for i := 0; i < entries; i++ {
+		entry := ifdOffset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			value := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation поворачивает и отражает картинку по значению EXIF Orientation
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// Для 5-8 стороны меняются местами
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = width-1-x, y
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dx, dy = x, height-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = height-1-y, x
+			case 7:
+				dx, dy = height-1-y, width-1-x
+			case 8:
+				dx, dy = y, width-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+
+	return dst
+}
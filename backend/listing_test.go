@@ -0,0 +1,233 @@
+package main
+
+import (
+	"arizonagamesstore/backend/events"
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/services"
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type feedResponse struct {
+	Ads []struct {
+		ID                   uint   `json:"id"`
+		Title                string `json:"title"`
+		TitleHighlight       string `json:"title_highlight"`
+		DescriptionHighlight string `json:"description_highlight"`
+	} `json:"ads"`
+	Page struct {
+		NextCursor string `json:"next_cursor"`
+		PrevCursor string `json:"prev_cursor"`
+		Total      int64  `json:"total"`
+		Seed       string `json:"seed"`
+	} `json:"page"`
+	Total int64 `json:"total"`
+}
+
+func (c *client) feed(path string, query url.Values) feedResponse {
+	c.t.Helper()
+	var feed feedResponse
+	decode(c.t, c.do(http.MethodGet, path+"?"+query.Encode(), nil, ""), &feed)
+	return feed
+}
+
+func (f feedResponse) titles() []string {
+	titles := make([]string, 0, len(f.Ads))
+	for _, ad := range f.Ads {
+		titles = append(titles, ad.Title)
+	}
+	return titles
+}
+
+func TestAdFeeds(t *testing.T) {
+	srv := newTestServer(t)
+	seller := srv.signUp(t, "Seller")
+	guest := srv.newClient(t)
+
+	// Между объявлениями проходит минута, чтобы лента по дате шла в известном порядке
+	titles := []string{"Дом у моря", "Особняк на холме", "Гараж"}
+	for _, title := range titles {
+		seller.createAd(title)
+		srv.clock.Advance(time.Minute)
+	}
+
+	t.Run("category feed pages", func(t *testing.T) {
+		first := guest.feed("/api/ads", url.Values{"category": {"house"}, "limit": {"2"}})
+		if got := strings.Join(first.titles(), ", "); got != "Гараж, Особняк на холме" || first.Page.Total != 3 {
+			t.Fatalf("first page = %q of %d", got, first.Page.Total)
+		}
+
+		second := guest.feed("/api/ads", url.Values{"category": {"house"}, "limit": {"2"}, "cursor": {first.Page.NextCursor}})
+		if got := strings.Join(second.titles(), ", "); got != "Дом у моря" || second.Page.NextCursor != "" {
+			t.Fatalf("second page = %q, next cursor %q", got, second.Page.NextCursor)
+		}
+
+		back := guest.feed("/api/ads", url.Values{"category": {"house"}, "limit": {"2"}, "cursor": {second.Page.PrevCursor}})
+		if got := strings.Join(back.titles(), ", "); got != "Гараж, Особняк на холме" {
+			t.Fatalf("previous page = %q", got)
+		}
+	})
+
+	t.Run("oldest first", func(t *testing.T) {
+		feed := guest.feed("/api/ads", url.Values{"category": {"house"}, "sort": {"date_asc"}})
+		if got := strings.Join(feed.titles(), ", "); got != strings.Join(titles, ", ") {
+			t.Fatalf("feed = %q", got)
+		}
+	})
+
+	t.Run("other category is empty", func(t *testing.T) {
+		feed := guest.feed("/api/ads", url.Values{"category": {"business"}})
+		if len(feed.Ads) != 0 || feed.Page.Total != 0 {
+			t.Fatalf("business feed = %q", feed.titles())
+		}
+	})
+
+	t.Run("random feed keeps its order across pages", func(t *testing.T) {
+		first := guest.feed("/api/ads/random", url.Values{"limit": {"2"}})
+		if first.Page.Seed == "" || first.Page.NextCursor == "" {
+			t.Fatalf("first page has no seed or cursor: %+v", first.Page)
+		}
+		again := guest.feed("/api/ads/random", url.Values{"limit": {"2"}, "seed": {first.Page.Seed}})
+		if strings.Join(again.titles(), ", ") != strings.Join(first.titles(), ", ") {
+			t.Fatalf("same seed gave %q, then %q", first.titles(), again.titles())
+		}
+
+		second := guest.feed("/api/ads/random", url.Values{"limit": {"2"}, "cursor": {first.Page.NextCursor}})
+		seen := map[string]bool{}
+		for _, title := range append(first.titles(), second.titles()...) {
+			seen[title] = true
+		}
+		if len(seen) != len(titles) {
+			t.Fatalf("random pages %q + %q do not cover every ad once", first.titles(), second.titles())
+		}
+	})
+
+	t.Run("seller page", func(t *testing.T) {
+		var page struct {
+			Listings []struct {
+				Title string `json:"title"`
+			} `json:"listings"`
+		}
+		decode(t, guest.do(http.MethodGet, "/api/listings/user/Seller", nil, ""), &page)
+		if len(page.Listings) != len(titles) {
+			t.Fatalf("seller page = %+v", page.Listings)
+		}
+	})
+
+	t.Run("search highlights matches", func(t *testing.T) {
+		feed := guest.feed("/api/ads/search", url.Values{"q": {"особняк"}})
+		if feed.Total != 1 || len(feed.Ads) != 1 {
+			t.Fatalf("search = %q of %d", feed.titles(), feed.Total)
+		}
+		if want := "<mark>Особняк</mark> на холме"; feed.Ads[0].TitleHighlight != want {
+			t.Fatalf("title highlight = %q, want %q", feed.Ads[0].TitleHighlight, want)
+		}
+	})
+}
+
+func TestViewedAds(t *testing.T) {
+	srv := newTestServer(t)
+	seller := srv.signUp(t, "Seller")
+	buyer := srv.signUp(t, "Buyer")
+
+	first := seller.createAd("Дом у моря")
+	second := seller.createAd("Гараж")
+
+	for _, id := range []uint{first, second, first} {
+		rec := buyer.sendForm(http.MethodPost, "/api/viewed-ads", map[string]string{"ad_id": strconv.Itoa(int(id))}, nil)
+		expectStatus(t, rec, http.StatusOK)
+		srv.clock.Advance(time.Minute)
+	}
+
+	var viewed struct {
+		ViewedAds []struct {
+			AdID int `json:"ad_id"`
+			Ad   struct {
+				Title string `json:"title"`
+			} `json:"Ad"`
+		} `json:"viewed_ads"`
+	}
+	decode(t, buyer.do(http.MethodGet, "/api/viewed-ads", nil, ""), &viewed)
+
+	// Повторный просмотр поднимает объявление наверх, а не дублирует его
+	if len(viewed.ViewedAds) != 2 || viewed.ViewedAds[0].AdID != int(first) || viewed.ViewedAds[1].Ad.Title != "Гараж" {
+		t.Fatalf("viewed ads = %+v", viewed.ViewedAds)
+	}
+}
+
+func TestExpireOldAds(t *testing.T) {
+	srv := newTestServer(t)
+	seller := srv.signUp(t, "Seller")
+	guest := srv.newClient(t)
+	seller.createAd("Дом у моря")
+
+	count := func() int {
+		t.Helper()
+		var body struct {
+			Count int `json:"count"`
+		}
+		decode(t, guest.do(http.MethodGet, "/api/getadcount?CategoryName=house", nil, ""), &body)
+		return body.Count
+	}
+	notifications := func() []string {
+		t.Helper()
+		var body struct {
+			Notifications []models.Notification `json:"notifications"`
+		}
+		decode(t, seller.do(http.MethodGet, "/api/notifications", nil, ""), &body)
+		types := make([]string, 0, len(body.Notifications))
+		for _, notification := range body.Notifications {
+			types = append(types, notification.Type)
+		}
+		return types
+	}
+	expire := func() {
+		t.Helper()
+		if err := services.Ads.ExpireOld(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := count(); got != 1 {
+		t.Fatalf("ad count = %d, want 1", got)
+	}
+
+	srv.clock.Advance(models.AdLifetime - models.AdExpiryWarning + time.Minute)
+	expire()
+	expire()
+	if got := notifications(); len(got) != 1 || got[0] != events.TypeAdExpiring {
+		t.Fatalf("notifications before expiry = %q", got)
+	}
+
+	srv.clock.Advance(models.AdExpiryWarning)
+	expire()
+	if got := notifications(); len(got) != 2 || got[0] != events.TypeAdExpired {
+		t.Fatalf("notifications after expiry = %q", got)
+	}
+	if feed := guest.feed("/api/ads", url.Values{"category": {"house"}}); len(feed.Ads) != 0 {
+		t.Fatalf("expired ad is still in the feed: %q", feed.titles())
+	}
+	if got := count(); got != 0 {
+		t.Fatalf("ad count after expiry = %d, want 0", got)
+	}
+
+	// Пересчет счетчиков сходится с тем, что насчитали по ходу
+	if err := services.Ads.RecalculateStatistics(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := count(); got != 0 {
+		t.Fatalf("recalculated ad count = %d, want 0", got)
+	}
+
+	srv.clock.Advance(models.AdArchiveAfter + time.Hour)
+	expire()
+	mine := seller.feed("/api/ads/my", url.Values{"status": {models.AdStatusArchived}})
+	if len(mine.Ads) != 1 {
+		t.Fatalf("archived ads = %q", mine.titles())
+	}
+}
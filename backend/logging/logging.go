@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"arizonagamesstore/backend/config"
+	"context"
+	"log/slog"
+	"os"
+)
+
+type ctxKey struct{}
+
+var userContent bool
+
+// Init настраивает slog по конфигу и делает его логгером по умолчанию.
+// После этого и старые log.Printf идут через тот же обработчик, в том же формате
+func Init(cfg config.LogConfig) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		level = slog.LevelInfo
+	}
+
+	userContent = cfg.UserContent
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+
+	slog.SetDefault(slog.New(contextHandler{handler}))
+}
+
+// UserContent - можно ли писать в лог тексты от пользователей (LOG_USER_CONTENT).
+// По умолчанию нельзя: заголовки и описания объявлений логам ни к чему
+func UserContent() bool {
+	return userContent
+}
+
+// WithRequestID кладет ID запроса в контекст. Все, что логируется через
+// slog.*Context с этим контекстом, получит поле request_id
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, requestID)
+}
+
+// RequestID достает ID запроса из контекста, пустая строка если его нет
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(ctxKey{}).(string)
+	return requestID
+}
+
+// contextHandler добавляет request_id из контекста к каждой записи
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Console ничего не отправляет, а пишет письмо в лог. Для разработки:
+// в production конфиг не пустит EMAIL_TEST_MODE, поэтому коды в логе можно показать
+type Console struct{}
+
+func (Console) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "[TEST MODE] Письмо не отправлено", "to", msg.To, "subject", msg.Subject, "text", msg.Text)
+	return nil
+}
+
+// File складывает письма в папку как .html, чтобы их можно было открыть в браузере
+type File struct {
+	dir     string
+	counter atomic.Uint64
+}
+
+func NewFile(dir string) *File {
+	return &File{dir: dir}
+}
+
+func (f *File) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		return err
+	}
+
+	// Адрес в имени файла - чтобы сразу видеть, кому письмо. Счетчик - чтобы письма
+	// одной секунды не затирали друг друга
+	to := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%s-%03d-%s.html", time.Now().Format("20060102-150405"), f.counter.Add(1)%1000, to)
+
+	content := fmt.Sprintf("<!-- To: %s -->\n<!-- Subject: %s -->\n%s", msg.To, msg.Subject, msg.HTML)
+	if err := os.WriteFile(filepath.Join(f.dir, name), []byte(content), 0o644); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Письмо сохранено в файл", "to", msg.To, "subject", msg.Subject, "file", name)
+	return nil
+}
@@ -0,0 +1,36 @@
+// Package mail - письма пользователям: шаблоны в templates/, отправители (SMTP,
+// файлы или консоль для разработки, память для тестов). Сервисы письма напрямую
+// не шлют, а кладут в очередь email_outbox - ее разбирает фоновая задача
+package mail
+
+import (
+	"arizonagamesstore/backend/config"
+	"context"
+)
+
+// Message - готовое письмо
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	// Text - короткая текстовая версия, ее печатает Console
+	Text string
+}
+
+// Sender доставляет письмо. Ошибка значит, что письмо надо попробовать отправить еще раз
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender выбирает отправителя по конфигу: EMAIL_TEST_MODE - в лог,
+// EMAIL_DIR - файлами в папку, иначе SMTP
+func NewSender(cfg config.SMTPConfig) Sender {
+	switch {
+	case cfg.TestMode:
+		return Console{}
+	case cfg.Dir != "":
+		return NewFile(cfg.Dir)
+	default:
+		return NewSMTP(cfg)
+	}
+}
@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory запоминает отправленные письма. Для тестов
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+	err      error
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// FailWith заставляет Send возвращать err, пока не передадут nil. Так проверяются повторы
+func (m *Memory) FailWith(err error) {
+	m.mu.Lock()
+	m.err = err
+	m.mu.Unlock()
+}
+
+// Messages - копия всех доставленных писем по порядку
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
@@ -0,0 +1,84 @@
+package mail
+
+import (
+	"arizonagamesstore/backend/config"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTP отправляет письма через SMTP сервер со STARTTLS
+type SMTP struct {
+	cfg config.SMTPConfig
+}
+
+func NewSMTP(cfg config.SMTPConfig) *SMTP {
+	return &SMTP{cfg: cfg}
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	cfg := s.cfg
+	if cfg.Host == "" || cfg.Port == "" || cfg.From == "" || cfg.Password == "" {
+		return errors.New("SMTP configuration is incomplete")
+	}
+
+	// Зависший SMTP сервер не должен держать воркер очереди дольше таймаута задачи
+	dialer := net.Dialer{Timeout: 15 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(cfg.Host, cfg.Port))
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("greeting: %w", err)
+	}
+	defer client.Close()
+
+	if err := client.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+		return fmt.Errorf("starttls: %w", err)
+	}
+	if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+	if err := client.Mail(cfg.From); err != nil {
+		return fmt.Errorf("mail from: %w", err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("rcpt to: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	if _, err := w.Write(encode(cfg, msg)); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// encode собирает письмо с заголовками. Тема в UTF-8 кодируется по RFC 2047
+func encode(cfg config.SMTPConfig, msg Message) []byte {
+	return []byte(
+		"From: " + mime.QEncoding.Encode("UTF-8", cfg.FromName) + " <" + cfg.From + ">\r\n" +
+			"To: " + msg.To + "\r\n" +
+			"Subject: " + mime.QEncoding.Encode("UTF-8", msg.Subject) + "\r\n" +
+			"MIME-Version: 1.0\r\n" +
+			"Content-Type: text/html; charset=UTF-8\r\n" +
+			"\r\n" +
+			msg.HTML)
+}
@@ -0,0 +1,94 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Template - тип письма, он же имя файла в templates/
+type Template string
+
+const (
+	Verification    Template = "verification"
+	PasswordReset   Template = "password_reset"
+	EmailChangeCode Template = "email_change_code"
+	EmailChanged    Template = "email_changed"
+	Notification    Template = "notification"
+)
+
+// CodeData - письма с одноразовым кодом: Verification, PasswordReset, EmailChangeCode
+type CodeData struct {
+	Code     string
+	Lifetime time.Duration
+}
+
+func (d CodeData) Minutes() int {
+	return int(d.Lifetime / time.Minute)
+}
+
+// EmailChangedData - письмо на старый адрес после смены email
+type EmailChangedData struct {
+	NewEmail string
+	UndoURL  string
+}
+
+// NotificationData - короткое уведомление со ссылкой на сайт (ссылка необязательна)
+type NotificationData struct {
+	Title string
+	Text  string
+	URL   string
+}
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+type compiled struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+var templates = map[Template]compiled{}
+
+func init() {
+	for _, name := range []Template{Verification, PasswordReset, EmailChangeCode, EmailChanged, Notification} {
+		files := []string{"templates/layout.html", "templates/" + string(name) + ".html"}
+		// Тело письма экранирует html/template, а тему и текстовую версию
+		// рендерим text/template, иначе в них попадут &#39; и прочие сущности
+		templates[name] = compiled{
+			html: htmltemplate.Must(htmltemplate.ParseFS(templateFiles, files...)),
+			text: texttemplate.Must(texttemplate.ParseFS(templateFiles, files...)),
+		}
+	}
+}
+
+// Render собирает письмо по шаблону. Данные экранируются, так что адреса
+// и ссылки от пользователей можно передавать как есть
+func Render(name Template, to string, data interface{}) (Message, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("mail: unknown template %q", name)
+	}
+
+	var subject, html, text bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()),
+	}, nil
+}
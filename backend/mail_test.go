@@ -0,0 +1,121 @@
+package main
+
+import (
+	"arizonagamesstore/backend/services"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMailOutboxRetries(t *testing.T) {
+	errSMTP := errors.New("smtp: connection refused")
+
+	tests := []struct {
+		name string
+		// failures - сколько прогонов очереди SMTP лежит. Между прогонами часы
+		// сдвигаются на step, чтобы письмо успевало дождаться следующей попытки
+		failures  int
+		step      time.Duration
+		delivered bool
+	}{
+		{"sent right away", 0, 0, true},
+		{"sent on retry", 1, 30 * time.Second, true},
+		{"sent after several retries", 3, 2 * time.Minute, true},
+		{"dead letter after all attempts", 20, 3 * time.Hour, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			c := srv.newClient(t)
+			const email = "mailbox@example.com"
+
+			srv.mail.FailWith(errSMTP)
+			rec := c.postJSON("/api/register", gin.H{"nickname": "Mailbox", "email": email, "password": "Secret123"})
+			expectStatus(t, rec, http.StatusOK)
+
+			for i := 0; i < tt.failures; i++ {
+				srv.flushMail(t)
+				srv.clock.Advance(tt.step)
+			}
+
+			srv.mail.FailWith(nil)
+			srv.clock.Advance(tt.step)
+			srv.flushMail(t)
+
+			if got := len(srv.mail.Messages()); (got == 1) != tt.delivered {
+				t.Fatalf("delivered %d emails, want delivered=%v", got, tt.delivered)
+			}
+			if tt.delivered {
+				rec = c.postJSON("/api/verify-email", gin.H{"email": email, "code": srv.lastCode(t, email)})
+				expectStatus(t, rec, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestMailOutboxWaitsForBackoff(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.newClient(t)
+
+	srv.mail.FailWith(errors.New("smtp: timeout"))
+	rec := c.postJSON("/api/register", gin.H{"nickname": "Patient", "email": "patient@example.com", "password": "Secret123"})
+	expectStatus(t, rec, http.StatusOK)
+	srv.flushMail(t)
+
+	// SMTP ожил, но первая повторная попытка только через 30 секунд
+	srv.mail.FailWith(nil)
+	srv.clock.Advance(20 * time.Second)
+	srv.flushMail(t)
+	if got := len(srv.mail.Messages()); got != 0 {
+		t.Fatalf("email was retried before backoff, delivered %d", got)
+	}
+
+	srv.clock.Advance(15 * time.Second)
+	srv.flushMail(t)
+	if got := len(srv.mail.Messages()); got != 1 {
+		t.Fatalf("delivered %d emails after backoff, want 1", got)
+	}
+}
+
+func TestMailOutboxRetention(t *testing.T) {
+	tests := []struct {
+		name    string
+		deliver bool
+		age     time.Duration
+		deleted int64
+	}{
+		{"sent yesterday", true, 24 * time.Hour, 0},
+		{"sent more than a week ago", true, 8 * 24 * time.Hour, 1},
+		{"pending is never pruned", false, 8 * 24 * time.Hour, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			c := srv.newClient(t)
+
+			if !tt.deliver {
+				srv.mail.FailWith(errors.New("smtp: connection refused"))
+			}
+			rec := c.postJSON("/api/register", gin.H{"nickname": "Archive", "email": "archive@example.com", "password": "Secret123"})
+			expectStatus(t, rec, http.StatusOK)
+			if tt.deliver {
+				srv.flushMail(t)
+			}
+
+			srv.clock.Advance(tt.age)
+			deleted, err := services.Mail.Prune(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deleted != tt.deleted {
+				t.Fatalf("pruned %d emails, want %d", deleted, tt.deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConversationFlow(t *testing.T) {
+	srv := newTestServer(t)
+	seller := srv.signUp(t, "Seller")
+	buyer := srv.signUp(t, "Buyer")
+	stranger := srv.signUp(t, "Stranger")
+	adID := seller.createAd("Машина с тюнингом")
+
+	expectStatus(t, seller.postJSON(fmt.Sprintf("/api/ads/%d/conversations", adID), nil), http.StatusBadRequest)
+
+	rec := buyer.postJSON(fmt.Sprintf("/api/ads/%d/conversations", adID), gin.H{"message": "Еще продаете?"})
+	expectStatus(t, rec, http.StatusCreated)
+	var started struct {
+		Conversation struct {
+			ID uint `json:"id"`
+		} `json:"conversation"`
+	}
+	decodeBody(t, rec, &started)
+	conversationPath := fmt.Sprintf("/api/conversations/%d", started.Conversation.ID)
+
+	// Повторное "Написать" открывает ту же переписку
+	expectStatus(t, buyer.postJSON(fmt.Sprintf("/api/ads/%d/conversations", adID), nil), http.StatusOK)
+	expectStatus(t, stranger.do(http.MethodGet, conversationPath+"/messages", nil, ""), http.StatusNotFound)
+
+	var list struct {
+		Conversations []struct {
+			ID                uint    `json:"id"`
+			AdTitle           string  `json:"ad_title"`
+			CompanionNickname string  `json:"companion_nickname"`
+			LastMessage       *string `json:"last_message"`
+			UnreadCount       int64   `json:"unread_count"`
+		} `json:"conversations"`
+		UnreadTotal int64 `json:"unread_total"`
+	}
+	decode(t, seller.do(http.MethodGet, "/api/conversations", nil, ""), &list)
+	if len(list.Conversations) != 1 || list.UnreadTotal != 1 {
+		t.Fatalf("seller conversations = %+v, unread %d", list.Conversations, list.UnreadTotal)
+	}
+	got := list.Conversations[0]
+	if got.AdTitle != "Машина с тюнингом" || got.CompanionNickname != "Buyer" || got.LastMessage == nil || *got.LastMessage != "Еще продаете?" {
+		t.Fatalf("conversation summary = %+v", got)
+	}
+
+	expectStatus(t, seller.postJSON(conversationPath+"/messages", gin.H{"body": "Да, приезжайте"}), http.StatusCreated)
+
+	var read struct {
+		MarkedRead int64 `json:"marked_read"`
+	}
+	decode(t, seller.postJSON(conversationPath+"/read", nil), &read)
+	if read.MarkedRead != 1 {
+		t.Fatalf("marked read = %d, want 1", read.MarkedRead)
+	}
+
+	var history struct {
+		Messages []struct {
+			SenderNickname string `json:"sender_nickname"`
+			Body           string `json:"body"`
+		} `json:"messages"`
+	}
+	decode(t, buyer.do(http.MethodGet, conversationPath+"/messages", nil, ""), &history)
+	if len(history.Messages) != 2 || history.Messages[0].SenderNickname != "Buyer" || history.Messages[1].Body != "Да, приезжайте" {
+		t.Fatalf("messages = %+v", history.Messages)
+	}
+
+	// После блокировки писать нельзя ни одной из сторон
+	expectStatus(t, seller.postJSON("/api/blocks/Buyer", nil), http.StatusOK)
+	expectStatus(t, seller.postJSON("/api/blocks/Buyer", nil), http.StatusConflict)
+	expectStatus(t, buyer.postJSON(conversationPath+"/messages", gin.H{"body": "Алло?"}), http.StatusForbidden)
+	expectStatus(t, seller.do(http.MethodDelete, "/api/blocks/Buyer", nil, ""), http.StatusOK)
+	expectStatus(t, buyer.postJSON(conversationPath+"/messages", gin.H{"body": "Алло?"}), http.StatusCreated)
+}
+
+func TestFavoritePriceChange(t *testing.T) {
+	srv := newTestServer(t)
+	seller := srv.signUp(t, "Seller")
+	buyer := srv.signUp(t, "Buyer")
+	adID := seller.createAd("Квартира в центре")
+
+	add := func() int {
+		return buyer.sendForm(http.MethodPost, "/api/favorites", map[string]string{"ad_id": strconv.Itoa(int(adID))}, nil).Code
+	}
+	if code := add(); code != http.StatusOK {
+		t.Fatalf("add favorite: status %d", code)
+	}
+	if code := add(); code != http.StatusConflict {
+		t.Fatalf("add favorite twice: status %d, want 409", code)
+	}
+
+	rec := seller.sendForm(http.MethodPut, fmt.Sprintf("/api/ads/%d", adID), map[string]string{"price": "800"}, nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	var favorites struct {
+		Favorites []struct {
+			AdID        uint `json:"ad_id"`
+			PriceChange *struct {
+				OldPrice *int64 `json:"old_price"`
+				NewPrice *int64 `json:"new_price"`
+				Dropped  bool   `json:"dropped"`
+			} `json:"price_change"`
+		} `json:"favorites"`
+	}
+	decode(t, buyer.do(http.MethodGet, "/api/favorites", nil, ""), &favorites)
+	if len(favorites.Favorites) != 1 || favorites.Favorites[0].AdID != adID {
+		t.Fatalf("favorites = %+v", favorites.Favorites)
+	}
+	change := favorites.Favorites[0].PriceChange
+	if change == nil || *change.OldPrice != 1000 || *change.NewPrice != 800 || !change.Dropped {
+		t.Fatalf("price change = %+v, want 1000 -> 800", change)
+	}
+
+	expectStatus(t, buyer.do(http.MethodDelete, fmt.Sprintf("/api/favorites/%d", adID), nil, ""), http.StatusOK)
+	decode(t, buyer.do(http.MethodGet, "/api/favorites", nil, ""), &favorites)
+	if len(favorites.Favorites) != 0 {
+		t.Fatalf("favorites after remove = %+v", favorites.Favorites)
+	}
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "arizona"
+
+// Registry - свой реестр вместо глобального prometheus.DefaultRegisterer,
+// чтобы в /metrics попадало только то, что мы сами зарегистрировали
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestDuration - время ответа по маршруту gin (шаблон вида /api/ads/:id, а не сам URL),
+	// методу и статусу. Из _count этой гистограммы получается и число запросов по статусам
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by gin route, method and status code.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"route", "method", "status"})
+
+	RateLimitBlocks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ratelimit",
+		Name:      "blocks_total",
+		Help:      "How many times a client was blocked by a rate limiter.",
+	}, []string{"limiter"})
+
+	StorageUploadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "upload_duration_seconds",
+		Help:      "File upload duration by storage backend and result.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"backend", "result"})
+
+	AdsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ads",
+		Name:      "created_total",
+		Help:      "Ads created, by initial status (active or draft).",
+	}, []string{"status"})
+
+	AdsExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ads",
+		Name:      "expired_total",
+		Help:      "Ads moved to expired by the expiry job.",
+	})
+
+	// EmailsProcessed - попытки отправки из email_outbox: sent, retry (будет повтор) или dead
+	EmailsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "email",
+		Name:      "processed_total",
+		Help:      "Outbox delivery attempts by template and result (sent, retry, dead).",
+	}, []string{"template", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		RateLimitBlocks,
+		StorageUploadDuration,
+		AdsCreated,
+		AdsExpired,
+		EmailsProcessed,
+	)
+}
+
+// RegisterDB добавляет статистику пула соединений: открытые, занятые, ожидания и т.д.
+func RegisterDB(db *sql.DB) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+}
+
+// RegisterGaugeFunc - для значений, которые проще посчитать в момент сбора,
+// например сколько IP сейчас заблокировано лимитером
+func RegisterGaugeFunc(opts prometheus.GaugeOpts, labels prometheus.Labels, fn func() float64) {
+	opts.Namespace = namespace
+	opts.ConstLabels = labels
+	Registry.MustRegister(prometheus.NewGaugeFunc(opts, fn))
+}
+
+// Middleware замеряет каждый запрос. Запросы мимо маршрутов пишутся как route="unmatched",
+// иначе сканеры наплодили бы по метке на каждый случайный URL
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		HTTPRequestDuration.
+			WithLabelValues(route, c.Request.Method, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler отдает метрики в формате Prometheus. Если token не пустой,
+// без заголовка Authorization: Bearer <token> отвечает 401
+func Handler(token string) gin.HandlerFunc {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	expected := []byte("Bearer " + token)
+
+	return func(c *gin.Context) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), expected) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+			c.Abort()
+			return
+		}
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// ObserveSince - короткая запись для гистограмм длительности
+func ObserveSince(observer prometheus.Observer, start time.Time) {
+	observer.Observe(time.Since(start).Seconds())
+}
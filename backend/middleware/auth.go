@@ -1,11 +1,11 @@
 package middleware
 
 import (
-	"arizonagamesstore/backend/database"
 	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/services"
 	"arizonagamesstore/backend/utils"
+	"errors"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,6 +19,7 @@ func AuthRequired() gin.HandlerFunc {
 			if err == nil {
 				c.Set("user_id", claims.UserID)
 				c.Set("nickname", claims.Nickname)
+				c.Set("user_role", models.NormalizeRole(claims.Role))
 				c.Next()
 				return
 			}
@@ -31,34 +32,29 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := utils.ValidateRefreshToken(refreshToken)
+		result, err := services.Sessions.Rotate(refreshToken, c.ClientIP())
 		if err != nil {
+			if errors.Is(err, services.ErrRefreshTokenReused) {
+				utils.SetAuthCookie(c, "access_token", "", -1)
+				utils.SetAuthCookie(c, "refresh_token", "", -1)
+			}
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Необходима авторизация"})
 			c.Abort()
 			return
 		}
 
-		var storedToken models.RefreshToken
-		result := database.DB.Where("token = ? AND account_id = ? AND expires_at > ?",
-			refreshToken, claims.UserID, time.Now()).First(&storedToken)
-
-		if result.Error != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Необходима авторизация"})
-			c.Abort()
-			return
-		}
-
-		newAccessToken, err := utils.GenerateAccessToken(claims.UserID, claims.Nickname)
+		newAccessToken, err := utils.GenerateAccessToken(result.AccountID, result.Nickname, result.Role)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка генерации токена"})
 			c.Abort()
 			return
 		}
 
-		utils.SetAuthCookie(c, "access_token", newAccessToken, 180)
+		services.SetRefreshedCookies(c, newAccessToken, result)
 
-		c.Set("user_id", claims.UserID)
-		c.Set("nickname", claims.Nickname)
+		c.Set("user_id", result.AccountID)
+		c.Set("nickname", result.Nickname)
+		c.Set("user_role", result.Role)
 		c.Next()
 	}
 }
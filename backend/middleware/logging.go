@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"arizonagamesstore/backend/logging"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// Чужой ID принимаем, только если он похож на ID: иначе через заголовок
+// можно было бы дописать в лог что угодно
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Служебные маршруты дергаются пробами и Prometheus каждые несколько секунд,
+// их пишем только на уровне debug
+var quietRoutes = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// RequestID берет X-Request-ID от прокси или генерирует новый, возвращает его
+// в ответе и кладет в контекст запроса, чтобы он попадал в каждую строку лога
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Header(requestIDHeader, requestID)
+		c.Set("request_id", requestID)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
+
+		c.Next()
+	}
+}
+
+// RequestLogger пишет одну строку на запрос: маршрут, статус, время, ник.
+// Query не пишем - там бывают токены (например, отмена смены email)
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case quietRoutes[route]:
+			level = slog.LevelDebug
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("ip", c.ClientIP()),
+		}
+		if nickname, exists := c.Get("nickname"); exists {
+			attrs = append(attrs, slog.Any("user", nickname))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+
+		slog.LogAttrs(c.Request.Context(), level, "Запрос", attrs...)
+	}
+}
+
+// Recovery - как gin.Recovery, но паника со стеком уходит в slog вместе с request_id.
+// Ловит панику в middleware и в маршрутах без таймаута: остальные обработчики крутятся
+// в горутине RequestTimeout, и их паники она перехватывает сама
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		abortWithPanic(c, err, debug.Stack())
+	})
+}
+
+// abortWithPanic пишет панику в лог и отвечает 500. stack снимается там, где паника поймана
+func abortWithPanic(c *gin.Context, err any, stack []byte) {
+	slog.ErrorContext(c.Request.Context(), "Паника в обработчике",
+		"error", err,
+		"route", c.FullPath(),
+		"stack", string(stack),
+	)
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Внутренняя ошибка сервера"})
+}
@@ -1,15 +1,18 @@
 package middleware
 
 import (
+	"arizonagamesstore/backend/metrics"
 	"fmt"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type rateLimiter struct {
+	name     string
 	mu       sync.RWMutex
 	requests map[string][]time.Time
 	blocked  map[string]time.Time
@@ -18,17 +21,28 @@ type rateLimiter struct {
 	blockDuration time.Duration
 }
 
-func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+func newRateLimiter(name string, limit int, window time.Duration) *rateLimiter {
+	return newRateLimiterWithBlock(name, limit, window, 15*time.Minute)
+}
+
+func newRateLimiterWithBlock(name string, limit int, window time.Duration, blockDuration time.Duration) *rateLimiter {
 	rl := &rateLimiter{
-		requests: make(map[string][]time.Time),
-		blocked:  make(map[string]time.Time),
-		limit:    limit,
-		window:   window,
-		blockDuration: 15 * time.Minute,
+		name:          name,
+		requests:      make(map[string][]time.Time),
+		blocked:       make(map[string]time.Time),
+		limit:         limit,
+		window:        window,
+		blockDuration: blockDuration,
 	}
 
 	go rl.cleanup()
 
+	metrics.RegisterGaugeFunc(prometheus.GaugeOpts{
+		Subsystem: "ratelimit",
+		Name:      "blocked_clients",
+		Help:      "Clients currently blocked by a rate limiter.",
+	}, prometheus.Labels{"limiter": name}, rl.blockedCount)
+
 	return rl
 }
 
@@ -88,6 +102,7 @@ func (rl *rateLimiter) allow(ip string) bool {
 
 	if len(valid) >= rl.limit {
 		rl.blocked[ip] = now.Add(rl.blockDuration)
+		metrics.RateLimitBlocks.WithLabelValues(rl.name).Inc()
 		return false
 	}
 
@@ -110,10 +125,29 @@ func (rl *rateLimiter) isBlocked(ip string) (bool, time.Duration) {
 	return false, 0
 }
 
+// blockedCount - сколько ключей заблокировано прямо сейчас, для /metrics.
+// Истекшие блокировки cleanup убирает раз в 5 минут, поэтому сверяемся со временем
+func (rl *rateLimiter) blockedCount() float64 {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	now := time.Now()
+	count := 0
+	for _, blockedUntil := range rl.blocked {
+		if now.Before(blockedUntil) {
+			count++
+		}
+	}
+	return float64(count)
+}
+
 var (
-	registerLimiter = newRateLimiter(3, 1*time.Hour)
-	loginLimiter    = newRateLimiter(5, 5*time.Minute)
-	verifyLimiter   = newRateLimiter(10, 10*time.Minute)
+	registerLimiter = newRateLimiter("register", 3, 1*time.Hour)
+	loginLimiter    = newRateLimiter("login", 5, 5*time.Minute)
+	verifyLimiter   = newRateLimiter("verify", 10, 10*time.Minute)
+
+	// Сообщения считаются по нику, а не по IP: ставится после AuthRequired
+	messageLimiter = newRateLimiterWithBlock("messages", 20, 1*time.Minute, 5*time.Minute)
 )
 
 func RateLimitRegister() gin.HandlerFunc {
@@ -190,3 +224,31 @@ func RateLimitVerify() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+func RateLimitMessages() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.ClientIP()
+		if nickname, exists := c.Get("nickname"); exists {
+			key = nickname.(string)
+		}
+
+		if blocked, remaining := messageLimiter.isBlocked(key); blocked {
+			minutes := int(remaining.Minutes())
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": fmt.Sprintf("Слишком много сообщений. Отправка заблокирована на %d мин.", minutes),
+			})
+			c.Abort()
+			return
+		}
+
+		if !messageLimiter.allow(key) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "Слишком много сообщений. Попробуйте позже (максимум 20 сообщений в минуту)",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
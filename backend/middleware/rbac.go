@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"arizonagamesstore/backend/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentRole достает роль, которую положил AuthRequired
+func currentRole(c *gin.Context) (string, bool) {
+	role, exists := c.Get("user_role")
+	if !exists {
+		return "", false
+	}
+	roleStr, ok := role.(string)
+	if !ok {
+		return "", false
+	}
+	return models.NormalizeRole(roleStr), true
+}
+
+// RequireRole пропускает пользователя, если у него одна из перечисленных ролей.
+// Ставится после AuthRequired
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := currentRole(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Необходима авторизация"})
+			c.Abort()
+			return
+		}
+
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+		c.Abort()
+	}
+}
+
+// RequirePermission пропускает пользователя, если его роль дает все перечисленные права.
+// Ставится после AuthRequired
+func RequirePermission(permissions ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := currentRole(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Необходима авторизация"})
+			c.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			if !models.HasPermission(role, permission) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
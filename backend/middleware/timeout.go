@@ -3,28 +3,58 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"runtime/debug"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RequestTimeout(timeout time.Duration) gin.HandlerFunc {
+type handlerPanic struct {
+	err   any
+	stack []byte
+}
+
+// RequestTimeout ограничивает время обработки запроса. Долгоживущие
+// маршруты (поток событий) передаются в skipPaths и не ограничиваются
+func RequestTimeout(timeout time.Duration, skipPaths ...string) gin.HandlerFunc {
+	skip := make(map[string]struct{}, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = struct{}{}
+	}
+
 	return func(c *gin.Context) {
+		if _, ok := skip[c.FullPath()]; ok {
+			c.Next()
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
 
 		c.Request = c.Request.WithContext(ctx)
 
 		finished := make(chan struct{})
+		panicked := make(chan handlerPanic, 1)
 
+		// Паника в чужой горутине не доходит до Recovery и роняет весь процесс,
+		// поэтому ловим ее здесь, а отвечаем уже из горутины запроса
 		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					panicked <- handlerPanic{err: err, stack: debug.Stack()}
+					return
+				}
+				close(finished)
+			}()
 			c.Next()
-			close(finished)
 		}()
 
 		select {
 		case <-finished:
 			return
+		case p := <-panicked:
+			abortWithPanic(c, p.err, p.stack)
+			return
 		case <-ctx.Done():
 			c.JSON(http.StatusRequestTimeout, gin.H{
 				"error": "Запрос превысил время ожидания. Попробуйте снова.",
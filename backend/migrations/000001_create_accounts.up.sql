@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS accounts (
+    id SERIAL PRIMARY KEY,
+    nickname VARCHAR(50) NOT NULL,
+    email VARCHAR(255),
+    email_verified BOOLEAN DEFAULT FALSE,
+    password_hash VARCHAR(100),
+    user_role VARCHAR(20) DEFAULT 'User',
+    avatar TEXT DEFAULT '',
+    background_avatar_profile TEXT DEFAULT '',
+    rating REAL DEFAULT 0,
+    success_transactions INTEGER DEFAULT 0,
+    user_description TEXT DEFAULT '',
+    theme VARCHAR(10) DEFAULT 'dark',
+    telegram VARCHAR(64) DEFAULT '',
+    last_seen_at TIMESTAMP,
+    last_settings_change TIMESTAMP,
+    last_nickname_change TIMESTAMP,
+    last_email_change TIMESTAMP,
+    reg_ip VARCHAR(64) DEFAULT '',
+    last_ip VARCHAR(64) DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Базы, созданные до версионированных миграций: настройки профиля
+-- раньше накатывались отдельным скриптом
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS theme VARCHAR(10) DEFAULT 'dark';
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS last_settings_change TIMESTAMP;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS last_nickname_change TIMESTAMP;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS last_email_change TIMESTAMP;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_nickname ON accounts(nickname);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_email ON accounts(email);
@@ -0,0 +1,57 @@
+CREATE TABLE IF NOT EXISTS ads (
+    id SERIAL PRIMARY KEY,
+    server_name VARCHAR(100),
+    title VARCHAR(255),
+    description TEXT,
+    type VARCHAR(50),
+    currency VARCHAR(20),
+    price BIGINT,
+    price_period VARCHAR(20),
+    rental_hours_limit INTEGER,
+    image TEXT,
+    image_thumb TEXT DEFAULT '',
+    image_medium TEXT DEFAULT '',
+    category VARCHAR(50),
+    nickname VARCHAR(50),
+    views INTEGER DEFAULT 0,
+    status VARCHAR(20) DEFAULT 'active',
+    expires_at TIMESTAMP,
+    bumped_at TIMESTAMP,
+    status_changed_at TIMESTAMP,
+    expiry_notified_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Старые базы: жизненный цикл объявлений и уменьшенные картинки появились позже.
+-- Раньше это делал database.CreateAdLifecycleColumns и CreateAdImageColumns
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS status VARCHAR(20) DEFAULT 'active';
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS bumped_at TIMESTAMP;
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP;
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS expiry_notified_at TIMESTAMP;
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS image_thumb TEXT DEFAULT '';
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS image_medium TEXT DEFAULT '';
+
+UPDATE ads SET expires_at = created_at + INTERVAL '48 hours' WHERE expires_at IS NULL;
+UPDATE ads SET bumped_at = created_at WHERE bumped_at IS NULL;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT FROM information_schema.columns WHERE table_name = 'ads' AND column_name = 'hidden') THEN
+        UPDATE ads SET status = 'hidden' WHERE hidden = TRUE;
+        ALTER TABLE ads DROP COLUMN hidden;
+    END IF;
+END $$;
+
+-- Полнотекстовый поиск: название весит больше описания, словарь русский
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('russian', coalesce(description, '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_ads_status ON ads(status);
+CREATE INDEX IF NOT EXISTS idx_ads_status_expires ON ads(status, expires_at);
+CREATE INDEX IF NOT EXISTS idx_ads_bumped ON ads(bumped_at DESC);
+CREATE INDEX IF NOT EXISTS idx_ads_nickname ON ads(nickname);
+CREATE INDEX IF NOT EXISTS idx_ads_search_vector ON ads USING GIN(search_vector);
@@ -0,0 +1,17 @@
+-- Счетчики активных объявлений по категориям для главной.
+-- Уникальный индекс отдельно от CREATE TABLE: в старых базах таблицу делал AutoMigrate без ключа
+CREATE TABLE IF NOT EXISTS statistics (
+    category_name VARCHAR(50) NOT NULL,
+    ad_count BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_statistics_category ON statistics(category_name);
+
+INSERT INTO statistics (category_name, ad_count) VALUES
+    ('accs', 0),
+    ('business', 0),
+    ('house', 0),
+    ('security', 0),
+    ('vehicles', 0),
+    ('others', 0)
+ON CONFLICT (category_name) DO NOTHING;
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS reports (
+    id SERIAL PRIMARY KEY,
+    ad_id INTEGER NOT NULL,
+    reporter_nickname VARCHAR(50) NOT NULL,
+    reason VARCHAR(100) NOT NULL,
+    description TEXT,
+    status VARCHAR(20) DEFAULT 'pending',
+    resolved_by VARCHAR(50),
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS status VARCHAR(20) DEFAULT 'pending';
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS resolved_by VARCHAR(50);
+ALTER TABLE reports ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports(status);
+CREATE INDEX IF NOT EXISTS idx_reports_ad ON reports(ad_id);
+
+-- Журнал решений модерации. Без внешних ключей: запись остается, даже если объявление удалено
+CREATE TABLE IF NOT EXISTS moderation_decisions (
+    id SERIAL PRIMARY KEY,
+    ad_id INTEGER NOT NULL,
+    report_id INTEGER,
+    ad_owner_nickname VARCHAR(50) NOT NULL,
+    moderator_nickname VARCHAR(50) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_decisions_ad ON moderation_decisions(ad_id);
+CREATE INDEX IF NOT EXISTS idx_moderation_decisions_owner ON moderation_decisions(ad_owner_nickname);
+CREATE INDEX IF NOT EXISTS idx_moderation_decisions_time ON moderation_decisions(created_at DESC);
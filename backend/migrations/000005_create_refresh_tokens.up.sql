@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL,
+    family_id VARCHAR(36) NOT NULL,
+    token TEXT NOT NULL,
+    user_agent VARCHAR(512) DEFAULT '',
+    ip VARCHAR(64) DEFAULT '',
+    last_used_at TIMESTAMP,
+    rotated_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_refresh_token_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+
+-- Колонки сессий (список устройств, ротация токенов) в старых базах
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS user_agent VARCHAR(512) DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS ip VARCHAR(64) DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMP;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id VARCHAR(36);
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS rotated_at TIMESTAMP;
+
+UPDATE refresh_tokens SET last_used_at = created_at WHERE last_used_at IS NULL;
+UPDATE refresh_tokens SET family_id = md5(random()::text || id::text) WHERE family_id IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token ON refresh_tokens(token);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_account_expires ON refresh_tokens(account_id, expires_at);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires ON refresh_tokens(expires_at);
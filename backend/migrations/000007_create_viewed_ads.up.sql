@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS viewed_ads (
+    id SERIAL PRIMARY KEY,
+    user_nickname VARCHAR(255) NOT NULL,
+    ad_id INTEGER NOT NULL,
+    viewed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user FOREIGN KEY (user_nickname) REFERENCES accounts(nickname) ON DELETE CASCADE,
+    CONSTRAINT fk_viewed_ad FOREIGN KEY (ad_id) REFERENCES ads(id) ON DELETE CASCADE,
+    CONSTRAINT unique_user_ad UNIQUE(user_nickname, ad_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_viewed_ads_user ON viewed_ads(user_nickname);
+CREATE INDEX IF NOT EXISTS idx_viewed_ads_ad ON viewed_ads(ad_id);
+CREATE INDEX IF NOT EXISTS idx_viewed_ads_time ON viewed_ads(viewed_at DESC);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS feedback_ads (
+    id SERIAL PRIMARY KEY,
+    ad_id INTEGER NOT NULL,
+    reviewer_nickname VARCHAR(255) NOT NULL,
+    ad_owner_nickname VARCHAR(255) NOT NULL,
+    rating INTEGER NOT NULL CHECK (rating >= 1 AND rating <= 5),
+    review_text TEXT NOT NULL,
+    proof_image VARCHAR(500) NOT NULL,
+    confirm_feedback BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_ad FOREIGN KEY (ad_id) REFERENCES ads(id) ON DELETE CASCADE,
+    CONSTRAINT fk_reviewer FOREIGN KEY (reviewer_nickname) REFERENCES accounts(nickname) ON DELETE CASCADE,
+    CONSTRAINT fk_ad_owner FOREIGN KEY (ad_owner_nickname) REFERENCES accounts(nickname) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_feedback_ads_ad_owner ON feedback_ads(ad_owner_nickname);
+CREATE INDEX IF NOT EXISTS idx_feedback_ads_reviewer ON feedback_ads(reviewer_nickname);
+CREATE INDEX IF NOT EXISTS idx_feedback_ads_confirm ON feedback_ads(confirm_feedback);
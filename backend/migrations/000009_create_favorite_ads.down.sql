@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS ad_price_changes;
+DROP TABLE IF EXISTS favorite_ads;
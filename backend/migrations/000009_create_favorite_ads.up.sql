@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS favorite_ads (
+    id SERIAL PRIMARY KEY,
+    user_nickname VARCHAR(255) NOT NULL,
+    ad_id INTEGER NOT NULL,
+    price_at_add BIGINT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_favorite_user FOREIGN KEY (user_nickname) REFERENCES accounts(nickname) ON DELETE CASCADE,
+    CONSTRAINT fk_favorite_ad FOREIGN KEY (ad_id) REFERENCES ads(id) ON DELETE CASCADE,
+    CONSTRAINT unique_favorite_user_ad UNIQUE(user_nickname, ad_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_favorite_ads_user ON favorite_ads(user_nickname);
+CREATE INDEX IF NOT EXISTS idx_favorite_ads_ad ON favorite_ads(ad_id);
+
+-- История цен, чтобы показать в избранном "подешевело"
+CREATE TABLE IF NOT EXISTS ad_price_changes (
+    id SERIAL PRIMARY KEY,
+    ad_id INTEGER NOT NULL,
+    old_price BIGINT,
+    new_price BIGINT,
+    changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_price_change_ad FOREIGN KEY (ad_id) REFERENCES ads(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ad_price_changes_ad_time ON ad_price_changes(ad_id, changed_at DESC);
@@ -0,0 +1,35 @@
+-- Внешних ключей на ads и accounts нет специально: переписка нужна как
+-- доказательство в спорах и не должна пропадать вместе с объявлением
+CREATE TABLE IF NOT EXISTS conversations (
+    id SERIAL PRIMARY KEY,
+    ad_id INTEGER NOT NULL,
+    seller_nickname VARCHAR(50) NOT NULL,
+    buyer_nickname VARCHAR(50) NOT NULL,
+    last_message_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_conversation_ad_buyer UNIQUE(ad_id, buyer_nickname)
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversations_seller ON conversations(seller_nickname, last_message_at DESC);
+CREATE INDEX IF NOT EXISTS idx_conversations_buyer ON conversations(buyer_nickname, last_message_at DESC);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id SERIAL PRIMARY KEY,
+    conversation_id INTEGER NOT NULL,
+    sender_nickname VARCHAR(50) NOT NULL,
+    body TEXT NOT NULL,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_message_conversation FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_messages_conversation ON messages(conversation_id, id);
+CREATE INDEX IF NOT EXISTS idx_messages_unread ON messages(conversation_id) WHERE read_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS user_blocks (
+    id SERIAL PRIMARY KEY,
+    blocker_nickname VARCHAR(50) NOT NULL,
+    blocked_nickname VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_user_block UNIQUE(blocker_nickname, blocked_nickname)
+);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS email_changes (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL UNIQUE,
+    old_email VARCHAR(255) DEFAULT '',
+    old_email_verified BOOLEAN DEFAULT FALSE,
+    new_email VARCHAR(255) NOT NULL,
+    code_hash VARCHAR(100) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    confirmed_at TIMESTAMP,
+    undo_token_hash VARCHAR(64) DEFAULT '',
+    undo_expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_email_change_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_changes_undo ON email_changes(undo_token_hash) WHERE undo_token_hash <> '';
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS two_factor (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL UNIQUE,
+    secret VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    enabled_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_two_factor_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS two_factor_backup_codes (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL,
+    code_hash VARCHAR(100) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_two_factor_backup_account FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_two_factor_backup_account ON two_factor_backup_codes(account_id) WHERE used_at IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_feedback_ads_owner_confirmed;
+ALTER TABLE feedback_ads DROP COLUMN IF EXISTS confirmed_at;
+DROP TABLE IF EXISTS seller_reputation;
@@ -0,0 +1,23 @@
+-- Накопленная репутация продавца. Суммы весов хранятся уже с затуханием на момент decayed_at,
+-- поэтому новый отзыв добавляется без перечитывания старых
+CREATE TABLE IF NOT EXISTS seller_reputation (
+    account_id INTEGER PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+    stars_1 INTEGER NOT NULL DEFAULT 0,
+    stars_2 INTEGER NOT NULL DEFAULT 0,
+    stars_3 INTEGER NOT NULL DEFAULT 0,
+    stars_4 INTEGER NOT NULL DEFAULT 0,
+    stars_5 INTEGER NOT NULL DEFAULT 0,
+    rating_sum INTEGER NOT NULL DEFAULT 0,
+    weighted_sum DOUBLE PRECISION NOT NULL DEFAULT 0,
+    weight_total DOUBLE PRECISION NOT NULL DEFAULT 0,
+    rating DOUBLE PRECISION NOT NULL DEFAULT 0,
+    decayed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- "За последние 30 дней" считаем по моменту подтверждения. У старых отзывов его нет,
+-- берем время создания. Записи репутации для них соберет задача refresh-reputation
+ALTER TABLE feedback_ads ADD COLUMN IF NOT EXISTS confirmed_at TIMESTAMP;
+UPDATE feedback_ads SET confirmed_at = created_at WHERE confirm_feedback = TRUE AND confirmed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_feedback_ads_owner_confirmed ON feedback_ads(ad_owner_nickname, confirmed_at) WHERE confirm_feedback = TRUE;
@@ -0,0 +1,2 @@
+-- Стертые тела писем не восстановить, откатывать нечего
+SELECT 1;
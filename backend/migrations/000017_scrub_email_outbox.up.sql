@@ -0,0 +1,2 @@
+-- Тела уже отправленных писем больше не нужны, а в них лежат коды и ссылки отмены
+UPDATE email_outbox SET html = '', text = '' WHERE status IN ('sent', 'dead') AND (html <> '' OR text <> '');
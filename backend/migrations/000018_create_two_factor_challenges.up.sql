@@ -0,0 +1,10 @@
+-- Счетчик неверных 2FA кодов по challenge токену (jti), чтобы код нельзя было подбирать
+-- все 5 минут жизни токена
+CREATE TABLE IF NOT EXISTS two_factor_challenges (
+    jti VARCHAR(64) PRIMARY KEY,
+    account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_two_factor_challenges_account ON two_factor_challenges(account_id);
@@ -0,0 +1,9 @@
+package migrations
+
+import "embed"
+
+// FS - SQL миграции, вшитые в бинарник, чтобы сервер и команда migrate
+// не зависели от того, из какой папки их запустили
+//
+//go:embed *.sql
+var FS embed.FS
@@ -27,14 +27,32 @@ type Account struct {
 	CreatedAt               time.Time  `gorm:"autoCreateTime"`
 }
 
+// RefreshToken - один выданный refresh токен. Токены одной сессии (устройства)
+// связаны FamilyID: при каждом обновлении старый токен помечается RotatedAt,
+// а в ту же семью добавляется новый
 type RefreshToken struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement"`
-	AccountID uint      `gorm:"not null;index"`
-	Token     string    `gorm:"not null;uniqueIndex"`
-	ExpiresAt time.Time `gorm:"not null"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ID         uint       `gorm:"primaryKey;autoIncrement"`
+	AccountID  uint       `gorm:"not null;index"`
+	FamilyID   string     `gorm:"column:family_id;not null;index"`
+	Token      string     `gorm:"not null;uniqueIndex"`
+	UserAgent  string     `gorm:"column:user_agent"`
+	IP         string     `gorm:"column:ip"`
+	LastUsedAt time.Time  `gorm:"column:last_used_at"`
+	RotatedAt  *time.Time `gorm:"column:rotated_at"`
+	ExpiresAt  time.Time  `gorm:"not null"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
 }
 
+const (
+	// RefreshTokenLifetime - сколько живет сессия без входа. Каждое обновление продлевает срок
+	RefreshTokenLifetime = 30 * 24 * time.Hour
+
+	// RefreshReuseGrace - сколько уже замененный токен еще можно предъявить без последствий.
+	// Браузер часто шлет несколько запросов разом с одной и той же cookie,
+	// и это не кража, а гонка
+	RefreshReuseGrace = 30 * time.Second
+)
+
 type EmailVerification struct {
 	ID           uint      `gorm:"primaryKey;autoIncrement"`
 	Email        string    `gorm:"not null"`
@@ -44,3 +62,95 @@ type EmailVerification struct {
 	ExpiresAt    time.Time `gorm:"not null"`
 	CreatedAt    time.Time `gorm:"autoCreateTime"`
 }
+
+// PasswordReset - запрос на сброс пароля. Код хранится только в виде bcrypt-хеша,
+// на один аккаунт не больше одного активного запроса
+type PasswordReset struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	AccountID uint      `gorm:"column:account_id;not null;uniqueIndex"`
+	CodeHash  string    `gorm:"column:code_hash;not null"`
+	Attempts  int       `gorm:"column:attempts;default:0"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+const (
+	PasswordResetLifetime    = 10 * time.Minute
+	PasswordResetMaxAttempts = 5
+	PasswordResetCooldown    = time.Minute
+)
+
+// EmailChange - смена email в процессе. Пока ConfirmedAt пустой, ждем код с нового адреса.
+// После подтверждения запись хранит старый адрес и хеш ссылки, по которой смену можно отменить
+type EmailChange struct {
+	ID               uint       `gorm:"primaryKey;autoIncrement"`
+	AccountID        uint       `gorm:"column:account_id;not null;uniqueIndex"`
+	OldEmail         string     `gorm:"column:old_email"`
+	OldEmailVerified bool       `gorm:"column:old_email_verified"`
+	NewEmail         string     `gorm:"column:new_email;not null"`
+	CodeHash         string     `gorm:"column:code_hash;not null"`
+	Attempts         int        `gorm:"column:attempts;default:0"`
+	ExpiresAt        time.Time  `gorm:"not null"`
+	ConfirmedAt      *time.Time `gorm:"column:confirmed_at"`
+	UndoTokenHash    string     `gorm:"column:undo_token_hash"`
+	UndoExpiresAt    *time.Time `gorm:"column:undo_expires_at"`
+	CreatedAt        time.Time  `gorm:"autoCreateTime"`
+}
+
+const (
+	EmailChangeCodeLifetime = 10 * time.Minute
+	EmailChangeUndoLifetime = 7 * 24 * time.Hour
+	EmailChangeMaxAttempts  = 5
+)
+
+// TwoFactor - TOTP второй фактор аккаунта. Пока Enabled=false, это незавершенная настройка:
+// секрет выдан, но код из приложения еще не подтвержден.
+// LastUsedStep - номер последнего принятого 30-секундного окна, чтобы один код нельзя было ввести дважды
+type TwoFactor struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement"`
+	AccountID    uint       `gorm:"column:account_id;not null;uniqueIndex"`
+	Secret       string     `gorm:"column:secret;not null"`
+	Enabled      bool       `gorm:"column:enabled;default:false"`
+	LastUsedStep int64      `gorm:"column:last_used_step;default:0"`
+	EnabledAt    *time.Time `gorm:"column:enabled_at"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime"`
+}
+
+func (TwoFactor) TableName() string {
+	return "two_factor"
+}
+
+// TwoFactorBackupCode - одноразовый резервный код на случай, если телефона нет под рукой
+type TwoFactorBackupCode struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement"`
+	AccountID uint       `gorm:"column:account_id;not null;index"`
+	CodeHash  string     `gorm:"column:code_hash;not null"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
+func (TwoFactorBackupCode) TableName() string {
+	return "two_factor_backup_codes"
+}
+
+// TwoFactorChallenge - неверные коды, введенные по одному challenge токену (ключ - его jti).
+// Запись появляется только после первой ошибки и не нужна после ExpiresAt
+type TwoFactorChallenge struct {
+	JTI       string    `gorm:"column:jti;primaryKey"`
+	AccountID uint      `gorm:"column:account_id;not null;index"`
+	Attempts  int       `gorm:"column:attempts;default:0"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
+
+func (TwoFactorChallenge) TableName() string {
+	return "two_factor_challenges"
+}
+
+const (
+	TwoFactorBackupCodeCount = 10
+
+	// TwoFactorChallengeLifetime - сколько после ввода пароля есть времени ввести код
+	TwoFactorChallengeLifetime = 5 * time.Minute
+	// TwoFactorMaxAttempts - после стольких неверных кодов challenge сгорает и нужно снова ввести пароль
+	TwoFactorMaxAttempts = 5
+)
@@ -2,28 +2,72 @@ package models
 
 import "time"
 
+const (
+	AdStatusDraft    = "draft"
+	AdStatusActive   = "active"
+	AdStatusExpired  = "expired"
+	AdStatusSold     = "sold"
+	AdStatusHidden   = "hidden"
+	AdStatusArchived = "archived"
+)
+
+// AdLifetime - сколько объявление висит в ленте до перехода в expired.
+// AdArchiveAfter - сколько expired объявление ждет продления, прежде чем уйти в архив.
+// AdExpiryWarning - за сколько до истечения предупреждаем автора
+const (
+	AdLifetime      = 48 * time.Hour
+	AdArchiveAfter  = 30 * 24 * time.Hour
+	AdExpiryWarning = 6 * time.Hour
+)
+
 type Ad struct {
-	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	ServerName       string    `gorm:"column:server_name" json:"server_name"`
-	Title            string    `gorm:"column:title" json:"title"`
-	Description      string    `gorm:"column:description" json:"description"`
-	Type             string    `gorm:"column:type" json:"type"`
-	Currency         *string   `gorm:"column:currency" json:"currency,omitempty"`
-	Price            *int64    `gorm:"column:price" json:"price,omitempty"`
-	PricePeriod      *string   `gorm:"column:price_period" json:"price_period,omitempty"`
-	RentalHoursLimit *int      `gorm:"column:rental_hours_limit" json:"rental_hours_limit,omitempty"`
-	Image            string    `gorm:"column:image" json:"image"`
-	Category         string    `gorm:"column:category" json:"category"`
-	Nickname         string    `gorm:"column:nickname" json:"nickname"`
-	Views            int       `gorm:"column:views;default:0" json:"views"`
-	CreatedAt        time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	ID               uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ServerName       string     `gorm:"column:server_name" json:"server_name"`
+	Title            string     `gorm:"column:title" json:"title"`
+	Description      string     `gorm:"column:description" json:"description"`
+	Type             string     `gorm:"column:type" json:"type"`
+	Currency         *string    `gorm:"column:currency" json:"currency,omitempty"`
+	Price            *int64     `gorm:"column:price" json:"price,omitempty"`
+	PricePeriod      *string    `gorm:"column:price_period" json:"price_period,omitempty"`
+	RentalHoursLimit *int       `gorm:"column:rental_hours_limit" json:"rental_hours_limit,omitempty"`
+	Image            string     `gorm:"column:image" json:"image"`
+	ImageThumb       string     `gorm:"column:image_thumb" json:"image_thumb"`
+	ImageMedium      string     `gorm:"column:image_medium" json:"image_medium"`
+	Category         string     `gorm:"column:category" json:"category"`
+	Nickname         string     `gorm:"column:nickname" json:"nickname"`
+	Views            int        `gorm:"column:views;default:0" json:"views"`
+	Status           string     `gorm:"column:status;size:20;default:'active'" json:"status"`
+	ExpiresAt        *time.Time `gorm:"column:expires_at" json:"expires_at,omitempty"`
+	BumpedAt         *time.Time `gorm:"column:bumped_at" json:"bumped_at,omitempty"`
+	StatusChangedAt  *time.Time `gorm:"column:status_changed_at" json:"status_changed_at,omitempty"`
+	ExpiryNotifiedAt *time.Time `gorm:"column:expiry_notified_at" json:"-"`
+	CreatedAt        time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// AdWithAuthor - объявление вместе с аватаром, рейтингом и telegram автора для карточки в ленте
+type AdWithAuthor struct {
+	Ad
+	AuthorAvatar  string  `json:"author_avatar"`
+	AuthorRating  float32 `json:"author_rating"`
+	OwnerTelegram string  `json:"owner_telegram"`
+}
+
+// AdSearchResult - объявление из поиска с релевантностью и подсвеченными совпадениями
+type AdSearchResult struct {
+	AdWithAuthor
+	Rank                 float32 `json:"rank"`
+	TitleHighlight       string  `json:"title_highlight"`
+	DescriptionHighlight string  `json:"description_highlight"`
 }
 
 type Report struct {
-	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	AdID             uint      `gorm:"column:ad_id;not null" json:"ad_id"`
-	ReporterNickname string    `gorm:"column:reporter_nickname;size:50;not null" json:"reporter_nickname"`
-	Reason           string    `gorm:"column:reason;size:100;not null" json:"reason"`
-	Description      *string   `gorm:"column:description;type:text" json:"description,omitempty"`
-	CreatedAt        time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	ID               uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	AdID             uint       `gorm:"column:ad_id;not null" json:"ad_id"`
+	ReporterNickname string     `gorm:"column:reporter_nickname;size:50;not null" json:"reporter_nickname"`
+	Reason           string     `gorm:"column:reason;size:100;not null" json:"reason"`
+	Description      *string    `gorm:"column:description;type:text" json:"description,omitempty"`
+	Status           string     `gorm:"column:status;size:20;default:'pending'" json:"status"`
+	ResolvedBy       *string    `gorm:"column:resolved_by;size:50" json:"resolved_by,omitempty"`
+	ResolvedAt       *time.Time `gorm:"column:resolved_at" json:"resolved_at,omitempty"`
+	CreatedAt        time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
 }
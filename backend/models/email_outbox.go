@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	// OutboxDead - письмо так и не ушло за все попытки. Лежит для разбора, повторно не отправляется
+	OutboxDead = "dead"
+)
+
+// OutboxEmail - письмо в очереди на отправку. Уже отрендеренное: если шаблон
+// поменяют, пока письмо ждет повтора, уйдет то, что собрали при постановке
+type OutboxEmail struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Template      string     `gorm:"column:template;size:50;not null" json:"template"`
+	Recipient     string     `gorm:"column:recipient;size:255;not null" json:"recipient"`
+	Subject       string     `gorm:"column:subject;size:255;not null" json:"subject"`
+	HTML          string     `gorm:"column:html;not null" json:"-"`
+	Text          string     `gorm:"column:text;not null" json:"-"`
+	Status        string     `gorm:"column:status;size:20;not null;default:'pending'" json:"status"`
+	Attempts      int        `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at;not null" json:"next_attempt_at"`
+	LastError     string     `gorm:"column:last_error;not null" json:"last_error,omitempty"`
+	SentAt        *time.Time `gorm:"column:sent_at" json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (OutboxEmail) TableName() string {
+	return "email_outbox"
+}
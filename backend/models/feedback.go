@@ -5,15 +5,16 @@ import (
 )
 
 type FeedbackAd struct {
-	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	AdID             int       `gorm:"not null" json:"ad_id"`
-	ReviewerNickname string    `gorm:"not null" json:"reviewer_nickname"`
-	AdOwnerNickname  string    `gorm:"not null" json:"ad_owner_nickname"`
-	Rating           int       `gorm:"not null;check:rating >= 1 AND rating <= 5" json:"rating"`
-	ReviewText       string    `gorm:"type:text;not null" json:"review_text"`
-	ProofImage       string    `gorm:"not null" json:"proof_image"`
-	ConfirmFeedback  bool      `gorm:"default:false" json:"confirm_feedback"`
-	CreatedAt        time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID               uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	AdID             int        `gorm:"not null" json:"ad_id"`
+	ReviewerNickname string     `gorm:"not null" json:"reviewer_nickname"`
+	AdOwnerNickname  string     `gorm:"not null" json:"ad_owner_nickname"`
+	Rating           int        `gorm:"not null;check:rating >= 1 AND rating <= 5" json:"rating"`
+	ReviewText       string     `gorm:"type:text;not null" json:"review_text"`
+	ProofImage       string     `gorm:"not null" json:"proof_image"`
+	ConfirmFeedback  bool       `gorm:"default:false" json:"confirm_feedback"`
+	ConfirmedAt      *time.Time `gorm:"column:confirmed_at" json:"confirmed_at,omitempty"`
+	CreatedAt        time.Time  `gorm:"autoCreateTime" json:"created_at"`
 }
 
 func (FeedbackAd) TableName() string {
@@ -31,6 +32,14 @@ func (ViewedAd) TableName() string {
 	return "viewed_ads"
 }
 
+// ViewedAdWithAuthor - запись истории просмотров вместе с самим объявлением
+type ViewedAdWithAuthor struct {
+	AdWithAuthor
+	ViewedID     uint
+	UserNickname string
+	ViewedAt     time.Time
+}
+
 type FeedbackWithReviewer struct {
 	ID               uint      `json:"id"`
 	AdID             int       `json:"ad_id"`
@@ -44,3 +53,27 @@ type FeedbackWithReviewer struct {
 	ConfirmFeedback  bool      `json:"confirm_feedback"`
 	CreatedAt        time.Time `json:"created_at"`
 }
+
+type FavoriteAd struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserNickname string    `gorm:"not null" json:"user_nickname"`
+	AdID         int       `gorm:"not null" json:"ad_id"`
+	PriceAtAdd   *int64    `gorm:"column:price_at_add" json:"price_at_add,omitempty"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (FavoriteAd) TableName() string {
+	return "favorite_ads"
+}
+
+type AdPriceChange struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	AdID      int       `gorm:"not null" json:"ad_id"`
+	OldPrice  *int64    `gorm:"column:old_price" json:"old_price,omitempty"`
+	NewPrice  *int64    `gorm:"column:new_price" json:"new_price,omitempty"`
+	ChangedAt time.Time `gorm:"autoCreateTime" json:"changed_at"`
+}
+
+func (AdPriceChange) TableName() string {
+	return "ad_price_changes"
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// MaxMessageLength - максимальная длина одного сообщения в символах
+const MaxMessageLength = 2000
+
+// Conversation - переписка покупателя с продавцом по конкретному объявлению.
+// ad_id без внешнего ключа: если модератор удалит объявление, переписка
+// останется как доказательство для разбора спора
+type Conversation struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	AdID           uint      `gorm:"column:ad_id;not null" json:"ad_id"`
+	SellerNickname string    `gorm:"column:seller_nickname;size:50;not null" json:"seller_nickname"`
+	BuyerNickname  string    `gorm:"column:buyer_nickname;size:50;not null" json:"buyer_nickname"`
+	LastMessageAt  time.Time `gorm:"column:last_message_at" json:"last_message_at"`
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (Conversation) TableName() string {
+	return "conversations"
+}
+
+// HasMember проверяет, участвует ли пользователь в переписке
+func (c *Conversation) HasMember(nickname string) bool {
+	return c.SellerNickname == nickname || c.BuyerNickname == nickname
+}
+
+// Companion возвращает ник второго участника переписки
+func (c *Conversation) Companion(nickname string) string {
+	if c.SellerNickname == nickname {
+		return c.BuyerNickname
+	}
+	return c.SellerNickname
+}
+
+type Message struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ConversationID uint       `gorm:"column:conversation_id;not null" json:"conversation_id"`
+	SenderNickname string     `gorm:"column:sender_nickname;size:50;not null" json:"sender_nickname"`
+	Body           string     `gorm:"column:body;type:text;not null" json:"body"`
+	ReadAt         *time.Time `gorm:"column:read_at" json:"read_at,omitempty"`
+	CreatedAt      time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (Message) TableName() string {
+	return "messages"
+}
+
+// UserBlock - пользователь BlockerNickname не хочет получать сообщения от BlockedNickname
+type UserBlock struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	BlockerNickname string    `gorm:"column:blocker_nickname;size:50;not null" json:"blocker_nickname"`
+	BlockedNickname string    `gorm:"column:blocked_nickname;size:50;not null" json:"blocked_nickname"`
+	CreatedAt       time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (UserBlock) TableName() string {
+	return "user_blocks"
+}
+
+// ConversationSummary - строка в списке переписок: с кем, по какому объявлению,
+// последнее сообщение и сколько непрочитанных
+type ConversationSummary struct {
+	Conversation
+	AdTitle            string  `json:"ad_title"`
+	AdImage            string  `json:"ad_image"`
+	AdStatus           string  `json:"ad_status"`
+	CompanionNickname  string  `json:"companion_nickname"`
+	CompanionAvatar    string  `json:"companion_avatar"`
+	LastMessage        *string `json:"last_message"`
+	LastSenderNickname *string `json:"last_sender_nickname"`
+	UnreadCount        int64   `json:"unread_count"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const (
+	ReportStatusPending   = "pending"
+	ReportStatusDismissed = "dismissed"
+	ReportStatusResolved  = "resolved"
+)
+
+const (
+	ModerationActionDismiss = "dismiss"
+	ModerationActionHide    = "hide"
+	ModerationActionDelete  = "delete"
+	ModerationActionWarn    = "warn"
+)
+
+// ModerationDecision - запись о решении модератора по жалобе или объявлению.
+// ad_id намеренно без внешнего ключа: история должна пережить удаление объявления
+type ModerationDecision struct {
+	ID                uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	AdID              uint      `gorm:"column:ad_id;not null" json:"ad_id"`
+	ReportID          *uint     `gorm:"column:report_id" json:"report_id,omitempty"`
+	AdOwnerNickname   string    `gorm:"column:ad_owner_nickname;size:50;not null" json:"ad_owner_nickname"`
+	ModeratorNickname string    `gorm:"column:moderator_nickname;size:50;not null" json:"moderator_nickname"`
+	Action            string    `gorm:"column:action;size:20;not null" json:"action"`
+	Comment           *string   `gorm:"column:comment;type:text" json:"comment,omitempty"`
+	CreatedAt         time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (ModerationDecision) TableName() string {
+	return "moderation_decisions"
+}
+
+type ReportReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
+
+// ReportedAd - одна строка очереди модерации: объявление и все жалобы на него
+type ReportedAd struct {
+	AdID           uint                `json:"ad_id"`
+	Ad             *AdWithAuthor       `json:"ad"`
+	ReportCount    int64               `json:"report_count"`
+	Reasons        []ReportReasonCount `json:"reasons"`
+	LastReportedAt time.Time           `json:"last_reported_at"`
+}
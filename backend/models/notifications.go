@@ -0,0 +1,53 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// NotificationPayload - данные уведомления, в БД лежат как jsonb
+type NotificationPayload map[string]interface{}
+
+func (p NotificationPayload) Value() (driver.Value, error) {
+	if p == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (p *NotificationPayload) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*p = NotificationPayload{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported notification payload type")
+	}
+	return json.Unmarshal(data, p)
+}
+
+// Notification - запись в центре уведомлений. Type совпадает с типом
+// push-события, чтобы фронт обрабатывал их одинаково
+type Notification struct {
+	ID           uint                `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserNickname string              `gorm:"column:user_nickname;size:50;not null" json:"user_nickname"`
+	Type         string              `gorm:"column:type;size:50;not null" json:"type"`
+	Payload      NotificationPayload `gorm:"column:payload;type:jsonb;not null" json:"payload"`
+	ReadAt       *time.Time          `gorm:"column:read_at" json:"read_at,omitempty"`
+	CreatedAt    time.Time           `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+)
+
+// SellerReputation - накопленная репутация продавца по подтвержденным отзывам.
+// Из нее берутся accounts.rating и accounts.success_transactions.
+// WeightedSum и WeightTotal хранятся с затуханием на момент DecayedAt: чтобы добавить
+// новый отзыв, суммы домножаются на затухание за прошедшее время, старые отзывы не нужны
+type SellerReputation struct {
+	AccountID   uint      `gorm:"primaryKey;autoIncrement:false"`
+	Stars1      int       `gorm:"column:stars_1"`
+	Stars2      int       `gorm:"column:stars_2"`
+	Stars3      int       `gorm:"column:stars_3"`
+	Stars4      int       `gorm:"column:stars_4"`
+	Stars5      int       `gorm:"column:stars_5"`
+	RatingSum   int       `gorm:"column:rating_sum"`
+	WeightedSum float64   `gorm:"column:weighted_sum"`
+	WeightTotal float64   `gorm:"column:weight_total"`
+	Rating      float64   `gorm:"column:rating"`
+	DecayedAt   time.Time `gorm:"column:decayed_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+func (SellerReputation) TableName() string {
+	return "seller_reputation"
+}
+
+// Stars - сколько отзывов с каждой оценкой, ключ - число звезд
+func (r *SellerReputation) Stars() map[int]int {
+	return map[int]int{1: r.Stars1, 2: r.Stars2, 3: r.Stars3, 4: r.Stars4, 5: r.Stars5}
+}
+
+// AddStar учитывает оценку в счетчиках без весов
+func (r *SellerReputation) AddStar(rating int) {
+	switch rating {
+	case 1:
+		r.Stars1++
+	case 2:
+		r.Stars2++
+	case 3:
+		r.Stars3++
+	case 4:
+		r.Stars4++
+	case 5:
+		r.Stars5++
+	}
+	r.RatingSum += rating
+}
+
+// Count - сколько подтвержденных отзывов. Каждый из них - успешная сделка
+func (r *SellerReputation) Count() int {
+	return r.Stars1 + r.Stars2 + r.Stars3 + r.Stars4 + r.Stars5
+}
+
+// ReputationReview - подтвержденный отзыв вместе с репутацией его автора на момент выборки
+type ReputationReview struct {
+	FeedbackID     uint
+	Rating         int
+	CreatedAt      time.Time
+	ConfirmedAt    time.Time
+	ReviewerRating float32
+	ReviewerDeals  int
+}
@@ -0,0 +1,69 @@
+package models
+
+const (
+	RoleUser      = "User"
+	RoleModerator = "Moderator"
+	RoleAdmin     = "Admin"
+)
+
+type Permission string
+
+const (
+	PermissionViewReports    Permission = "reports:view"
+	PermissionResolveReports Permission = "reports:resolve"
+	PermissionModerateAds    Permission = "ads:moderate"
+	PermissionWarnUsers      Permission = "users:warn"
+	PermissionViewModLog     Permission = "moderation_log:view"
+	PermissionReadMessages   Permission = "messages:read"
+	PermissionManageRoles    Permission = "roles:manage"
+)
+
+// rolePermissions - матрица прав. Обычному пользователю staff-права не положены,
+// модератор разбирает жалобы, админ может все то же самое плюс раздавать роли
+var rolePermissions = map[string][]Permission{
+	RoleUser: {},
+	RoleModerator: {
+		PermissionViewReports,
+		PermissionResolveReports,
+		PermissionModerateAds,
+		PermissionWarnUsers,
+		PermissionViewModLog,
+		PermissionReadMessages,
+	},
+	RoleAdmin: {
+		PermissionViewReports,
+		PermissionResolveReports,
+		PermissionModerateAds,
+		PermissionWarnUsers,
+		PermissionViewModLog,
+		PermissionReadMessages,
+		PermissionManageRoles,
+	},
+}
+
+// NormalizeRole приводит роль из БД к одной из известных. Пустая или неизвестная
+// роль считается обычным пользователем
+func NormalizeRole(role string) string {
+	if _, ok := rolePermissions[role]; ok {
+		return role
+	}
+	return RoleUser
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func HasPermission(role string, permission Permission) bool {
+	for _, p := range rolePermissions[NormalizeRole(role)] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func PermissionsForRole(role string) []Permission {
+	return rolePermissions[NormalizeRole(role)]
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/services"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// signUpModerator регистрирует аккаунт и перезаходит уже модератором: роль зашита в access token
+func (s *testServer) signUpModerator(t *testing.T, nickname string) *client {
+	t.Helper()
+
+	c := s.signUp(t, nickname)
+	if err := services.Accounts.UpdateRole(nickname, models.RoleModerator); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, c.postJSON("/api/login", gin.H{"nickname": nickname, "password": "Secret123"}), http.StatusOK)
+	return c
+}
+
+func TestModerationQueue(t *testing.T) {
+	srv := newTestServer(t)
+	seller := srv.signUp(t, "Seller")
+	moderator := srv.signUpModerator(t, "Moder")
+	first := srv.signUp(t, "First")
+	second := srv.signUp(t, "Second")
+
+	spamID := seller.createAd("Дом с подвохом")
+	fineID := seller.createAd("Честный бизнес")
+	expectStatus(t, first.postJSON("/api/reports", gin.H{"ad_id": spamID, "reason": "Спам"}), http.StatusOK)
+	expectStatus(t, second.postJSON("/api/reports", gin.H{"ad_id": spamID, "reason": "Спам"}), http.StatusOK)
+	expectStatus(t, first.postJSON("/api/reports", gin.H{"ad_id": fineID, "reason": "Мошенничество"}), http.StatusOK)
+
+	expectStatus(t, seller.do(http.MethodGet, "/api/admin/reports", nil, ""), http.StatusForbidden)
+
+	var queue struct {
+		Reports []struct {
+			AdID        uint `json:"ad_id"`
+			ReportCount int  `json:"report_count"`
+			Ad          *struct {
+				Title string `json:"title"`
+			} `json:"ad"`
+		} `json:"reports"`
+	}
+	decode(t, moderator.do(http.MethodGet, "/api/admin/reports", nil, ""), &queue)
+	if len(queue.Reports) != 2 || queue.Reports[0].AdID != spamID || queue.Reports[0].ReportCount != 2 {
+		t.Fatalf("queue = %+v, want the ad with two reports first", queue.Reports)
+	}
+	if queue.Reports[0].Ad == nil || queue.Reports[0].Ad.Title != "Дом с подвохом" {
+		t.Fatalf("queue row ad = %+v", queue.Reports[0].Ad)
+	}
+
+	var reports struct {
+		Reports []struct {
+			ID uint `json:"id"`
+		} `json:"reports"`
+	}
+	decode(t, moderator.do(http.MethodGet, fmt.Sprintf("/api/admin/ads/%d/reports", fineID), nil, ""), &reports)
+	if len(reports.Reports) != 1 {
+		t.Fatalf("reports on the fine ad = %+v", reports.Reports)
+	}
+	dismissPath := fmt.Sprintf("/api/admin/reports/%d/dismiss", reports.Reports[0].ID)
+	expectStatus(t, moderator.postJSON(dismissPath, nil), http.StatusOK)
+	expectStatus(t, moderator.postJSON(dismissPath, nil), http.StatusConflict)
+
+	decode(t, moderator.do(http.MethodGet, "/api/admin/reports", nil, ""), &queue)
+	if len(queue.Reports) != 1 || queue.Reports[0].AdID != spamID {
+		t.Fatalf("queue after dismiss = %+v", queue.Reports)
+	}
+}
+
+// Удаление модератором уводит объявление в архив, а отзывы по нему остаются в рейтинге продавца
+func TestModerationDeleteKeepsFeedback(t *testing.T) {
+	srv := newTestServer(t)
+	seller := srv.signUp(t, "Seller")
+	buyer := srv.signUp(t, "Buyer")
+	moderator := srv.signUpModerator(t, "Moder")
+
+	adID := seller.createAd("Бизнес под снос")
+	expectStatus(t, seller.confirmFeedback(buyer.review(adID, 5)), http.StatusOK)
+	expectStatus(t, buyer.postJSON("/api/reports", gin.H{"ad_id": adID, "reason": "Мошенничество"}), http.StatusOK)
+
+	rec := moderator.do(http.MethodDelete, fmt.Sprintf("/api/admin/ads/%d", adID), nil, "")
+	expectStatus(t, rec, http.StatusOK)
+
+	var mine struct {
+		Ads []struct {
+			ID uint `json:"id"`
+		} `json:"ads"`
+	}
+	decode(t, seller.do(http.MethodGet, "/api/ads/my?status=archived", nil, ""), &mine)
+	if len(mine.Ads) != 1 || mine.Ads[0].ID != adID {
+		t.Fatalf("archived ads = %+v, want the deleted ad", mine.Ads)
+	}
+
+	var public struct {
+		Feedbacks []struct {
+			AdID uint `json:"ad_id"`
+		} `json:"feedbacks"`
+	}
+	decode(t, srv.newClient(t).do(http.MethodGet, "/api/feedback/Seller", nil, ""), &public)
+	if len(public.Feedbacks) != 1 {
+		t.Fatalf("feedback after moderation delete = %+v", public.Feedbacks)
+	}
+
+	var log struct {
+		Decisions []struct {
+			Action          string `json:"action"`
+			AdOwnerNickname string `json:"ad_owner_nickname"`
+		} `json:"decisions"`
+	}
+	decode(t, moderator.do(http.MethodGet, fmt.Sprintf("/api/admin/decisions?ad_id=%d", adID), nil, ""), &log)
+	if len(log.Decisions) != 1 || log.Decisions[0].Action != models.ModerationActionDelete || log.Decisions[0].AdOwnerNickname != "Seller" {
+		t.Fatalf("decisions = %+v", log.Decisions)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitBlocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		body  gin.H
+		limit int
+	}{
+		{"register", "/api/register", gin.H{"nickname": "x"}, 3},
+		{"login", "/api/login", gin.H{"nickname": "Nobody", "password": "Secret123"}, 5},
+		{"verify email", "/api/verify-email", gin.H{"email": "nobody@example.com", "code": "000000"}, 10},
+	}
+
+	srv := newTestServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := srv.newClient(t)
+			for i := 0; i < tt.limit; i++ {
+				if rec := c.postJSON(tt.path, tt.body); rec.Code == http.StatusTooManyRequests {
+					t.Fatalf("request %d of %d was blocked", i+1, tt.limit)
+				}
+			}
+			expectStatus(t, c.postJSON(tt.path, tt.body), http.StatusTooManyRequests)
+
+			// Блокировка по IP: соседний клиент проходит
+			if rec := srv.newClient(t).postJSON(tt.path, tt.body); rec.Code == http.StatusTooManyRequests {
+				t.Fatalf("another IP was blocked too")
+			}
+		})
+	}
+}
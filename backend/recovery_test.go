@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandlerPanicReturns500(t *testing.T) {
+	srv := newTestServer(t)
+	srv.router.GET("/api/test-panic", func(c *gin.Context) {
+		var ads map[string]int
+		ads["boom"]++
+	})
+	c := srv.newClient(t)
+
+	// Обработчик работает в горутине RequestTimeout: если паника оттуда уйдет, упадет весь тестовый бинарник
+	rec := c.do(http.MethodGet, "/api/test-panic", nil, "")
+	expectStatus(t, rec, http.StatusInternalServerError)
+	if rec.Header().Get("X-Request-ID") == "" {
+		t.Fatal("500 response has no X-Request-ID")
+	}
+
+	expectStatus(t, c.do(http.MethodGet, "/healthz", nil, ""), http.StatusOK)
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// AccountRepository - аккаунты и ожидающие подтверждения регистрации
+type AccountRepository interface {
+	FindByID(id uint) (*models.Account, error)
+	FindByNickname(nickname string) (*models.Account, error)
+	FindByEmail(email string) (*models.Account, error)
+	Create(account *models.Account) error
+	// Update меняет колонки аккаунта. ErrNotFound, если такого ника нет
+	Update(nickname string, fields map[string]interface{}) error
+	CountByRegIP(ip string) (int64, error)
+
+	// SaveVerification заменяет прежние коды для этого email и ника новым
+	SaveVerification(verification *models.EmailVerification) error
+	FindVerification(email string) (*models.EmailVerification, error)
+	DeleteVerification(email string) error
+}
+
+type postgresAccounts struct {
+	db *gorm.DB
+}
+
+func (r *postgresAccounts) FindByID(id uint) (*models.Account, error) {
+	return r.findBy("id = ?", id)
+}
+
+func (r *postgresAccounts) FindByNickname(nickname string) (*models.Account, error) {
+	return r.findBy("nickname = ?", nickname)
+}
+
+func (r *postgresAccounts) FindByEmail(email string) (*models.Account, error) {
+	return r.findBy("email = ?", email)
+}
+
+func (r *postgresAccounts) findBy(query string, value interface{}) (*models.Account, error) {
+	var account models.Account
+	if err := r.db.Where(query, value).First(&account).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &account, nil
+}
+
+func (r *postgresAccounts) Create(account *models.Account) error {
+	return r.db.Create(account).Error
+}
+
+func (r *postgresAccounts) Update(nickname string, fields map[string]interface{}) error {
+	return affected(r.db.Model(&models.Account{}).Where("nickname = ?", nickname).Updates(fields))
+}
+
+func (r *postgresAccounts) CountByRegIP(ip string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Account{}).Where("reg_ip = ?", ip).Count(&count).Error
+	return count, err
+}
+
+func (r *postgresAccounts) SaveVerification(verification *models.EmailVerification) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("email = ? OR nickname = ?", verification.Email, verification.Nickname).
+			Delete(&models.EmailVerification{}).Error; err != nil {
+			return err
+		}
+		verification.ID = 0
+		return tx.Create(verification).Error
+	})
+}
+
+func (r *postgresAccounts) FindVerification(email string) (*models.EmailVerification, error) {
+	var verification models.EmailVerification
+	if err := r.db.Where("email = ?", email).Order("id DESC").First(&verification).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &verification, nil
+}
+
+func (r *postgresAccounts) DeleteVerification(email string) error {
+	return r.db.Where("email = ?", email).Delete(&models.EmailVerification{}).Error
+}
@@ -0,0 +1,368 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// adWithAuthorColumns - что выбирать в models.AdWithAuthor из запроса adsWithAuthor
+const adWithAuthorColumns = "ads.*, accounts.avatar as author_avatar, accounts.rating as author_rating, accounts.telegram as owner_telegram"
+
+// adsWithAuthor - таблица ads, к которой присоединен автор. Основа для лент, поиска и
+// выборок по id, чтобы JOIN и список колонок были в одном месте
+func adsWithAuthor(db *gorm.DB) *gorm.DB {
+	return db.Table("ads").Joins("LEFT JOIN accounts ON ads.nickname = accounts.nickname")
+}
+
+// AdFilter - фильтры лент и поиска. Пустое поле не фильтрует
+type AdFilter struct {
+	Nickname string
+	// Statuses - пусто значит только active
+	Statuses []string
+	Category string
+	// Server - "all" то же, что пусто
+	Server   string
+	Type     string
+	PriceMin *float64
+	PriceMax *float64
+	Currency string
+}
+
+// Маркеры подсветки в AdSearchResult. Текст объявления экранируется уже после подсветки,
+// а маркеры заменяются на <mark>, так что HTML из описания не пролезет
+const (
+	HighlightStart = "{{hl}}"
+	HighlightStop  = "{{/hl}}"
+)
+
+// AdRepository - объявления, их просмотры и счетчики категорий
+type AdRepository interface {
+	FindByID(id uint) (*models.Ad, error)
+	// FindWithAuthor возвращает найденные объявления в любом порядке, отсутствующие id пропускает
+	FindWithAuthor(ids []uint) ([]models.AdWithAuthor, error)
+	// ListByOwner - объявления автора, сверху поднятые последними.
+	// Пустой status - все, кроме архива
+	ListByOwner(nickname string, status string) ([]models.AdWithAuthor, error)
+	Create(ad *models.Ad) error
+	// UpdateIfStatus меняет колонки, только если объявление все еще в статусе from.
+	// ErrNotFound, если объявления нет или статус успели поменять
+	UpdateIfStatus(id uint, from string, fields map[string]interface{}) error
+	IncrementViews(id uint) error
+	// RecordView добавляет объявление в историю просмотров или обновляет время просмотра
+	RecordView(nickname string, adID uint, at time.Time) error
+	RenameOwner(oldNickname string, newNickname string) error
+	// AdjustCategoryCount сдвигает счетчик категории, ниже нуля он не опускается
+	AdjustCategoryCount(category string, delta int) error
+	// CategoryCount - счетчик активных объявлений категории, 0 для незнакомой категории
+	CategoryCount(category string) (int64, error)
+	// RecountCategories сверяет счетчики всех категорий с таблицей ads и возвращает новые значения
+	RecountCategories(ctx context.Context) (map[string]int64, error)
+
+	// List - лента по фильтру с пагинацией по ключу. sort - date_desc (по умолчанию), date_asc,
+	// price_desc, price_asc, views_desc или random: тогда порядок задает req.Seed, он должен пройти IsShuffleSeed
+	List(filter AdFilter, sort string, req PageRequest) ([]models.AdWithAuthor, *Page, error)
+	// Search ищет активные объявления по словам в названии и описании. Пустой sort или
+	// relevance - по релевантности, остальные как в List (кроме random)
+	Search(text string, filter AdFilter, sort string, limit int, offset int) ([]models.AdSearchResult, int64, error)
+	// Viewed - история просмотров пользователя, сверху последние. Удаленных объявлений в ней нет
+	Viewed(nickname string, req PageRequest) ([]models.ViewedAdWithAuthor, *Page, error)
+
+	// ExpiringSoon - активные объявления с expires_at между from и to, о которых автора еще не предупреждали
+	ExpiringSoon(ctx context.Context, from time.Time, to time.Time) ([]models.Ad, error)
+	// MarkExpiryNotified запоминает, что автора предупредили. ErrNotFound, если это уже сделал кто-то другой
+	MarkExpiryNotified(ctx context.Context, id uint, at time.Time) error
+	// Expire переводит в expired активные объявления с expires_at раньше now и возвращает их
+	Expire(ctx context.Context, now time.Time) ([]models.Ad, error)
+	// Archive переводит в archived объявления, которые лежат в expired с момента раньше before
+	Archive(ctx context.Context, before time.Time, now time.Time) (int64, error)
+}
+
+type postgresAds struct {
+	db *gorm.DB
+}
+
+func (r *postgresAds) FindByID(id uint) (*models.Ad, error) {
+	var ad models.Ad
+	if err := r.db.Where("id = ?", id).First(&ad).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &ad, nil
+}
+
+func (r *postgresAds) FindWithAuthor(ids []uint) ([]models.AdWithAuthor, error) {
+	ads := make([]models.AdWithAuthor, 0, len(ids))
+	if len(ids) == 0 {
+		return ads, nil
+	}
+	err := adsWithAuthor(r.db).Select(adWithAuthorColumns).Where("ads.id IN ?", ids).Find(&ads).Error
+	return ads, err
+}
+
+func (r *postgresAds) ListByOwner(nickname string, status string) ([]models.AdWithAuthor, error) {
+	query := adsWithAuthor(r.db).Select(adWithAuthorColumns).Where("ads.nickname = ?", nickname)
+	if status != "" {
+		query = query.Where("ads.status = ?", status)
+	} else {
+		query = query.Where("ads.status <> ?", models.AdStatusArchived)
+	}
+
+	var ads []models.AdWithAuthor
+	if err := query.Order("ads.bumped_at DESC").Find(&ads).Error; err != nil {
+		return nil, err
+	}
+	return ads, nil
+}
+
+func (r *postgresAds) Create(ad *models.Ad) error {
+	return r.db.Create(ad).Error
+}
+
+func (r *postgresAds) UpdateIfStatus(id uint, from string, fields map[string]interface{}) error {
+	return affected(r.db.Model(&models.Ad{}).Where("id = ? AND status = ?", id, from).Updates(fields))
+}
+
+func (r *postgresAds) IncrementViews(id uint) error {
+	return affected(r.db.Model(&models.Ad{}).Where("id = ?", id).UpdateColumn("views", gorm.Expr("views + 1")))
+}
+
+func (r *postgresAds) RecordView(nickname string, adID uint, at time.Time) error {
+	result := r.db.Model(&models.ViewedAd{}).
+		Where("user_nickname = ? AND ad_id = ?", nickname, adID).
+		Update("viewed_at", at)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	return r.db.Create(&models.ViewedAd{UserNickname: nickname, AdID: int(adID), ViewedAt: at}).Error
+}
+
+func (r *postgresAds) RenameOwner(oldNickname string, newNickname string) error {
+	return r.db.Model(&models.Ad{}).Where("nickname = ?", oldNickname).Update("nickname", newNickname).Error
+}
+
+func (r *postgresAds) AdjustCategoryCount(category string, delta int) error {
+	return r.db.Model(&models.Statistic{}).
+		Where("category_name = ?", category).
+		UpdateColumn("ad_count", gorm.Expr("GREATEST(ad_count + ?, 0)", delta)).Error
+}
+
+func (r *postgresAds) CategoryCount(category string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Statistic{}).
+		Select("ad_count").
+		Where("category_name = ?", category).
+		Scan(&count).Error
+	return count, err
+}
+
+func (r *postgresAds) RecountCategories(ctx context.Context) (map[string]int64, error) {
+	db := r.db.WithContext(ctx)
+
+	var stats []models.Statistic
+	if err := db.Find(&stats).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(stats))
+	for _, stat := range stats {
+		var count int64
+		if err := db.Table("ads").Where("category = ? AND status = ?", stat.CategoryName, models.AdStatusActive).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if err := db.Model(&models.Statistic{}).
+			Where("category_name = ?", stat.CategoryName).
+			Update("ad_count", count).Error; err != nil {
+			return nil, err
+		}
+		counts[stat.CategoryName] = count
+	}
+	return counts, nil
+}
+
+// filter навешивает AdFilter на запрос по таблице ads
+func (r *postgresAds) filter(query *gorm.DB, filter AdFilter) *gorm.DB {
+	if len(filter.Statuses) > 0 {
+		query = query.Where("ads.status IN ?", filter.Statuses)
+	} else {
+		query = query.Where("ads.status = ?", models.AdStatusActive)
+	}
+	if filter.Nickname != "" {
+		query = query.Where("ads.nickname = ?", filter.Nickname)
+	}
+	if filter.Category != "" {
+		query = query.Where("ads.category = ?", filter.Category)
+	}
+	if filter.Server != "" && filter.Server != "all" {
+		query = query.Where("ads.server_name = ?", filter.Server)
+	}
+	if filter.Type != "" {
+		query = query.Where("ads.type = ?", filter.Type)
+	}
+	if filter.PriceMin != nil {
+		query = query.Where("ads.price >= ?", *filter.PriceMin)
+	}
+	if filter.PriceMax != nil {
+		query = query.Where("ads.price <= ?", *filter.PriceMax)
+	}
+	if filter.Currency != "" {
+		query = query.Where("ads.currency = ?", filter.Currency)
+	}
+	return query
+}
+
+func (r *postgresAds) List(filter AdFilter, sort string, req PageRequest) ([]models.AdWithAuthor, *Page, error) {
+	ks := adKeyset(sort, req.Seed)
+	return paginate(r.filter(adsWithAuthor(r.db), filter), adWithAuthorColumns, ks, req, adKey(ks))
+}
+
+const headlineOptions = "StartSel=" + HighlightStart + ", StopSel=" + HighlightStop +
+	", MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// Search использует русский словарь PostgreSQL, так что "дома" найдет "дом"
+func (r *postgresAds) Search(text string, filter AdFilter, sort string, limit int, offset int) ([]models.AdSearchResult, int64, error) {
+	const tsQuery = "websearch_to_tsquery('russian', ?)"
+
+	filter.Statuses = nil
+	base := r.filter(adsWithAuthor(r.db), filter).
+		Where("ads.search_vector @@ "+tsQuery, text).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]models.AdSearchResult, 0)
+	if total == 0 {
+		return results, 0, nil
+	}
+
+	query := base.Select(
+		adWithAuthorColumns+", "+
+			"ts_rank_cd(ads.search_vector, "+tsQuery+") AS rank, "+
+			"ts_headline('russian', ads.title, "+tsQuery+", ?) AS title_highlight, "+
+			"ts_headline('russian', ads.description, "+tsQuery+", ?) AS description_highlight",
+		text, text, headlineOptions, text, headlineOptions,
+	)
+
+	if sort == "" || sort == "relevance" {
+		query = query.Order("rank DESC").Order("ads.bumped_at DESC")
+	} else {
+		query = query.Order(adKeyset(sort, "").order(false))
+	}
+
+	if err := query.Limit(limit).Offset(offset).Find(&results).Error; err != nil {
+		return nil, 0, err
+	}
+	return results, total, nil
+}
+
+func (r *postgresAds) Viewed(nickname string, req PageRequest) ([]models.ViewedAdWithAuthor, *Page, error) {
+	query := adsWithAuthor(r.db).
+		Joins("JOIN viewed_ads ON viewed_ads.ad_id = ads.id").
+		Where("viewed_ads.user_nickname = ?", nickname)
+
+	return paginate(query, adWithAuthorColumns+", viewed_ads.id as viewed_id, viewed_ads.user_nickname, viewed_ads.viewed_at", viewedKeyset, req, viewedKey)
+}
+
+func (r *postgresAds) ExpiringSoon(ctx context.Context, from time.Time, to time.Time) ([]models.Ad, error) {
+	var ads []models.Ad
+	err := r.db.WithContext(ctx).
+		Select("id, nickname, title, expires_at").
+		Where("status = ? AND expiry_notified_at IS NULL AND expires_at BETWEEN ? AND ?", models.AdStatusActive, from, to).
+		Find(&ads).Error
+	return ads, err
+}
+
+func (r *postgresAds) MarkExpiryNotified(ctx context.Context, id uint, at time.Time) error {
+	return affected(r.db.WithContext(ctx).Model(&models.Ad{}).
+		Where("id = ? AND expiry_notified_at IS NULL", id).
+		Update("expiry_notified_at", at))
+}
+
+func (r *postgresAds) Expire(ctx context.Context, now time.Time) ([]models.Ad, error) {
+	// RETURNING отдает ровно те объявления, что перевел этот запрос, даже если задача идет на двух серверах
+	var ads []models.Ad
+	err := r.db.WithContext(ctx).Model(&ads).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}, {Name: "category"}, {Name: "nickname"}, {Name: "title"}}}).
+		Where("status = ? AND expires_at < ?", models.AdStatusActive, now).
+		Updates(map[string]interface{}{
+			"status":            models.AdStatusExpired,
+			"status_changed_at": now,
+		}).Error
+	return ads, err
+}
+
+func (r *postgresAds) Archive(ctx context.Context, before time.Time, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Ad{}).
+		Where("status = ? AND status_changed_at < ?", models.AdStatusExpired, before).
+		Updates(map[string]interface{}{
+			"status":            models.AdStatusArchived,
+			"status_changed_at": now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// adKeyset - сортировки ленты объявлений. Цена без значения (договорная) считается нулем,
+// иначе NULL ломает сравнение по ключу
+func adKeyset(sort string, seed string) keyset {
+	switch sort {
+	case "random":
+		return randomAdKeyset(seed)
+	case "date_asc":
+		return keyset{name: sort, expr: "ads.bumped_at", id: "ads.id", kind: keyTime}
+	case "price_desc":
+		return keyset{name: sort, expr: "COALESCE(ads.price, 0)", id: "ads.id", kind: keyInt, desc: true}
+	case "price_asc":
+		return keyset{name: sort, expr: "COALESCE(ads.price, 0)", id: "ads.id", kind: keyInt}
+	case "views_desc":
+		return keyset{name: sort, expr: "ads.views", id: "ads.id", kind: keyInt, desc: true}
+	default:
+		return keyset{name: "date_desc", expr: "ads.bumped_at", id: "ads.id", kind: keyTime, desc: true}
+	}
+}
+
+// randomAdKeyset перемешивает объявления детерминированно по seed:
+// с одним seed порядок всегда один и тот же, так что страницы не повторяются.
+// seed подставляется прямо в SQL, поэтому должен пройти IsShuffleSeed
+func randomAdKeyset(seed string) keyset {
+	return keyset{
+		name: "random",
+		expr: "md5('" + seed + "' || ads.id::text)",
+		id:   "ads.id",
+		kind: keyText,
+		seed: seed,
+	}
+}
+
+// adKey - ключ сортировки ks для курсора, тот же, что считает SQL
+func adKey(ks keyset) func(ad *models.AdWithAuthor) (interface{}, uint) {
+	return func(ad *models.AdWithAuthor) (interface{}, uint) {
+		switch ks.name {
+		case "random":
+			return shuffleKey(ks.seed, ad.ID), ad.ID
+		case "price_asc", "price_desc":
+			if ad.Price == nil {
+				return int64(0), ad.ID
+			}
+			return *ad.Price, ad.ID
+		case "views_desc":
+			return int64(ad.Views), ad.ID
+		default:
+			var bumpedAt time.Time
+			if ad.BumpedAt != nil {
+				bumpedAt = *ad.BumpedAt
+			}
+			return bumpedAt, ad.ID
+		}
+	}
+}
+
+var viewedKeyset = keyset{name: "viewed", expr: "viewed_ads.viewed_at", id: "viewed_ads.id", kind: keyTime, desc: true}
+
+func viewedKey(row *models.ViewedAdWithAuthor) (interface{}, uint) {
+	return row.ViewedAt, row.ViewedID
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EmailChangeRepository - смены email: неподтвержденные ждут код с нового адреса,
+// подтвержденные хранят старый адрес, пока по ссылке из письма смену можно отменить
+type EmailChangeRepository interface {
+	// Replace удаляет прежнюю смену аккаунта вместе со счетчиком попыток и сохраняет change
+	Replace(change *models.EmailChange) error
+	// FindPending - неподтвержденная смена аккаунта. ErrNotFound, если ее нет
+	FindPending(accountID uint) (*models.EmailChange, error)
+	// FindByUndoToken - подтвержденная смена по хешу ссылки отмены. ErrNotFound, если ее нет
+	FindByUndoToken(tokenHash string) (*models.EmailChange, error)
+	// AddAttempt засчитывает попытку ввести код и возвращает, сколько их стало.
+	// ErrNotFound, если смена уже удалена
+	AddAttempt(id uint) (int, error)
+	// Delete - ErrNotFound, если смена уже удалена
+	Delete(id uint) error
+	// Confirm в одной транзакции ставит аккаунту новый адрес и сохраняет у смены confirmed_at
+	// и ссылку отмены. ErrNotFound, если смену успели подтвердить или удалить
+	Confirm(change *models.EmailChange, at time.Time) error
+	// Undo возвращает аккаунту старый адрес и удаляет смену. ErrNotFound, если ее уже отменили
+	Undo(change *models.EmailChange) error
+}
+
+type postgresEmailChanges struct {
+	db *gorm.DB
+}
+
+func (r *postgresEmailChanges) Replace(change *models.EmailChange) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", change.AccountID).Delete(&models.EmailChange{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+}
+
+func (r *postgresEmailChanges) FindPending(accountID uint) (*models.EmailChange, error) {
+	var change models.EmailChange
+	if err := r.db.Where("account_id = ? AND confirmed_at IS NULL", accountID).First(&change).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &change, nil
+}
+
+func (r *postgresEmailChanges) FindByUndoToken(tokenHash string) (*models.EmailChange, error) {
+	var change models.EmailChange
+	if err := r.db.Where("undo_token_hash = ? AND confirmed_at IS NOT NULL", tokenHash).First(&change).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &change, nil
+}
+
+func (r *postgresEmailChanges) AddAttempt(id uint) (int, error) {
+	// Счетчик растет в самом UPDATE, так что параллельные попытки посчитаются все
+	change := models.EmailChange{ID: id}
+	err := affected(r.db.Model(&change).
+		Where("confirmed_at IS NULL").
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "attempts"}}}).
+		Update("attempts", gorm.Expr("attempts + 1")))
+	return change.Attempts, err
+}
+
+func (r *postgresEmailChanges) Delete(id uint) error {
+	return affected(r.db.Where("id = ?", id).Delete(&models.EmailChange{}))
+}
+
+func (r *postgresEmailChanges) Confirm(change *models.EmailChange, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := affected(tx.Model(&models.EmailChange{}).
+			Where("id = ? AND confirmed_at IS NULL", change.ID).
+			Updates(map[string]interface{}{
+				"confirmed_at":    change.ConfirmedAt,
+				"undo_token_hash": change.UndoTokenHash,
+				"undo_expires_at": change.UndoExpiresAt,
+			})); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Account{}).
+			Where("id = ?", change.AccountID).
+			Updates(map[string]interface{}{
+				"email":                change.NewEmail,
+				"email_verified":       true,
+				"last_email_change":    &at,
+				"last_settings_change": &at,
+			}).Error
+	})
+}
+
+func (r *postgresEmailChanges) Undo(change *models.EmailChange) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := affected(tx.Where("id = ?", change.ID).Delete(&models.EmailChange{})); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Account{}).
+			Where("id = ?", change.AccountID).
+			Updates(map[string]interface{}{
+				"email":          change.OldEmail,
+				"email_verified": change.OldEmailVerified,
+			}).Error
+	})
+}
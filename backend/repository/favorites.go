@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// FavoriteRepository - избранные объявления и история цен тех объявлений, что кто-то добавил в избранное
+type FavoriteRepository interface {
+	// Create - ошибка дубликата, если объявление уже в избранном
+	Create(favorite *models.FavoriteAd) error
+	// Delete возвращает false, если объявления в избранном не было
+	Delete(nickname string, adID int) (bool, error)
+	// List - избранное пользователя, сверху добавленные последними
+	List(nickname string) ([]models.FavoriteAd, error)
+	CountByAd(adID uint) (int64, error)
+	AddPriceChange(change *models.AdPriceChange) error
+	// LastPriceChanges - последнее изменение цены по каждому из объявлений, у которых оно было
+	LastPriceChanges(adIDs []int) ([]models.AdPriceChange, error)
+}
+
+type postgresFavorites struct {
+	db *gorm.DB
+}
+
+func (r *postgresFavorites) Create(favorite *models.FavoriteAd) error {
+	return r.db.Create(favorite).Error
+}
+
+func (r *postgresFavorites) Delete(nickname string, adID int) (bool, error) {
+	result := r.db.Where("user_nickname = ? AND ad_id = ?", nickname, adID).Delete(&models.FavoriteAd{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *postgresFavorites) List(nickname string) ([]models.FavoriteAd, error) {
+	var favorites []models.FavoriteAd
+	err := r.db.Where("user_nickname = ?", nickname).Order("created_at DESC").Find(&favorites).Error
+	return favorites, err
+}
+
+func (r *postgresFavorites) CountByAd(adID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.FavoriteAd{}).Where("ad_id = ?", adID).Count(&count).Error
+	return count, err
+}
+
+func (r *postgresFavorites) AddPriceChange(change *models.AdPriceChange) error {
+	return r.db.Create(change).Error
+}
+
+func (r *postgresFavorites) LastPriceChanges(adIDs []int) ([]models.AdPriceChange, error) {
+	var changes []models.AdPriceChange
+	err := r.db.Raw(`
+		SELECT DISTINCT ON (ad_id) *
+		FROM ad_price_changes
+		WHERE ad_id IN ?
+		ORDER BY ad_id, changed_at DESC`, adIDs).
+		Scan(&changes).Error
+	return changes, err
+}
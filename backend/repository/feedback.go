@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// FeedbackRepository - отзывы о продавцах
+type FeedbackRepository interface {
+	FindByID(id uint) (*models.FeedbackAd, error)
+	// Exists - оставлял ли reviewer отзыв на это объявление
+	Exists(adID uint, reviewer string) (bool, error)
+	// Create сохраняет отзыв. Подтверждается он через ReputationRepository.ConfirmFeedback
+	Create(feedback *models.FeedbackAd) error
+	// ListConfirmed - подтвержденные отзывы о продавце с аватаром и рейтингом авторов, сверху новые
+	ListConfirmed(ownerNickname string, req PageRequest) ([]models.FeedbackWithReviewer, *Page, error)
+}
+
+type postgresFeedback struct {
+	db *gorm.DB
+}
+
+func (r *postgresFeedback) FindByID(id uint) (*models.FeedbackAd, error) {
+	var feedback models.FeedbackAd
+	if err := r.db.Where("id = ?", id).First(&feedback).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &feedback, nil
+}
+
+func (r *postgresFeedback) Exists(adID uint, reviewer string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.FeedbackAd{}).
+		Where("ad_id = ? AND reviewer_nickname = ?", adID, reviewer).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *postgresFeedback) Create(feedback *models.FeedbackAd) error {
+	return r.db.Create(feedback).Error
+}
+
+func (r *postgresFeedback) ListConfirmed(ownerNickname string, req PageRequest) ([]models.FeedbackWithReviewer, *Page, error) {
+	query := r.db.Table("feedback_ads").
+		Joins("LEFT JOIN accounts ON feedback_ads.reviewer_nickname = accounts.nickname").
+		Where("feedback_ads.ad_owner_nickname = ? AND feedback_ads.confirm_feedback = ?", ownerNickname, true)
+
+	return paginate(query, "feedback_ads.*, accounts.avatar as reviewer_avatar, accounts.rating as reviewer_rating", feedbackKeyset, req, feedbackKey)
+}
+
+var feedbackKeyset = keyset{name: "feedback", expr: "feedback_ads.created_at", id: "feedback_ads.id", kind: keyTime, desc: true}
+
+func feedbackKey(feedback *models.FeedbackWithReviewer) (interface{}, uint) {
+	return feedback.CreatedAt, feedback.ID
+}
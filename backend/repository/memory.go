@@ -0,0 +1,2070 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// Текст как у PostgreSQL, чтобы utils.IsDuplicateKeyError узнавал и эту ошибку
+var errDuplicate = errors.New("duplicate key value violates unique constraint")
+
+var memorySchemas sync.Map
+
+// memoryStore - общее хранилище для всех репозиториев в памяти: объявлениям
+// нужен автор из accounts, поэтому таблицы живут рядом под одним мьютексом
+type memoryStore struct {
+	mu sync.Mutex
+
+	accounts      []*models.Account
+	verifications []*models.EmailVerification
+	ads           []*models.Ad
+	views         []*models.ViewedAd
+	categoryCount map[string]int64
+	feedback      []*models.FeedbackAd
+	reports       []*models.Report
+	decisions     []*models.ModerationDecision
+	tokens        []*models.RefreshToken
+	notifications []*models.Notification
+	outbox        []*models.OutboxEmail
+	reputation    map[uint]*models.SellerReputation
+	challenges    map[string]*models.TwoFactorChallenge
+	twoFactor     []*models.TwoFactor
+	backupCodes   []*models.TwoFactorBackupCode
+	resets        []*models.PasswordReset
+	emailChanges  []*models.EmailChange
+	favorites     []*models.FavoriteAd
+	priceChanges  []*models.AdPriceChange
+	conversations []*models.Conversation
+	messages      []*models.Message
+	blocks        []*models.UserBlock
+
+	lastID uint
+}
+
+// NewMemory собирает репозитории, которые все держат в памяти процесса.
+// Нужны для тестов: ведут себя как PostgreSQL на тех запросах, что есть в интерфейсах
+func NewMemory() Repositories {
+	store := &memoryStore{
+		categoryCount: make(map[string]int64),
+		reputation:    make(map[uint]*models.SellerReputation),
+		challenges:    make(map[string]*models.TwoFactorChallenge),
+	}
+	return Repositories{
+		Accounts: &memoryAccounts{store},
+		Ads:      &memoryAds{store},
+		Feedback: &memoryFeedback{store},
+		Reports:  &memoryReports{store},
+		Tokens:   &memoryTokens{store},
+
+		Notifications:  &memoryNotifications{store},
+		Outbox:         &memoryOutbox{store},
+		Reputation:     &memoryReputation{store},
+		TwoFactor:      &memoryTwoFactor{store},
+		PasswordResets: &memoryPasswordResets{store},
+		EmailChanges:   &memoryEmailChanges{store},
+		Favorites:      &memoryFavorites{store},
+		Messages:       &memoryMessages{store},
+	}
+}
+
+// nextID - сквозной автоинкремент. Общий на все таблицы, тестам это не мешает
+func (s *memoryStore) nextID() uint {
+	s.lastID++
+	return s.lastID
+}
+
+func (s *memoryStore) accountByNickname(nickname string) *models.Account {
+	for _, account := range s.accounts {
+		if account.Nickname == nickname {
+			return account
+		}
+	}
+	return nil
+}
+
+// applyFields раскладывает map колонок по полям модели так же, как это делает gorm в Updates
+func applyFields(dest interface{}, fields map[string]interface{}) error {
+	s, err := schema.Parse(dest, &memorySchemas, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(dest)
+	for column, v := range fields {
+		field := s.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("unknown column %q in %s", column, s.Table)
+		}
+		if err := field.Set(context.Background(), value, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type memoryAccounts struct {
+	*memoryStore
+}
+
+func (r *memoryAccounts) FindByID(id uint) (*models.Account, error) {
+	return r.findBy(func(account *models.Account) bool { return account.ID == id })
+}
+
+func (r *memoryAccounts) FindByNickname(nickname string) (*models.Account, error) {
+	return r.findBy(func(account *models.Account) bool { return account.Nickname == nickname })
+}
+
+func (r *memoryAccounts) FindByEmail(email string) (*models.Account, error) {
+	return r.findBy(func(account *models.Account) bool { return account.Email == email })
+}
+
+func (r *memoryAccounts) findBy(match func(*models.Account) bool) (*models.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, account := range r.accounts {
+		if match(account) {
+			found := *account
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryAccounts) Create(account *models.Account) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.accounts {
+		if existing.Nickname == account.Nickname || (account.Email != "" && existing.Email == account.Email) {
+			return errDuplicate
+		}
+	}
+
+	account.ID = r.nextID()
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = time.Now()
+	}
+	stored := *account
+	r.accounts = append(r.accounts, &stored)
+	return nil
+}
+
+func (r *memoryAccounts) Update(nickname string, fields map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account := r.accountByNickname(nickname)
+	if account == nil {
+		return ErrNotFound
+	}
+	return applyFields(account, fields)
+}
+
+func (r *memoryAccounts) CountByRegIP(ip string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, account := range r.accounts {
+		if account.RegIP == ip {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryAccounts) SaveVerification(verification *models.EmailVerification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.verifications[:0]
+	for _, existing := range r.verifications {
+		if existing.Email != verification.Email && existing.Nickname != verification.Nickname {
+			kept = append(kept, existing)
+		}
+	}
+
+	verification.ID = r.nextID()
+	if verification.CreatedAt.IsZero() {
+		verification.CreatedAt = time.Now()
+	}
+	stored := *verification
+	r.verifications = append(kept, &stored)
+	return nil
+}
+
+func (r *memoryAccounts) FindVerification(email string) (*models.EmailVerification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := len(r.verifications) - 1; i >= 0; i-- {
+		if r.verifications[i].Email == email {
+			found := *r.verifications[i]
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryAccounts) DeleteVerification(email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.verifications[:0]
+	for _, existing := range r.verifications {
+		if existing.Email != email {
+			kept = append(kept, existing)
+		}
+	}
+	r.verifications = kept
+	return nil
+}
+
+type memoryAds struct {
+	*memoryStore
+}
+
+func (r *memoryAds) find(id uint) *models.Ad {
+	for _, ad := range r.ads {
+		if ad.ID == id {
+			return ad
+		}
+	}
+	return nil
+}
+
+func (r *memoryAds) withAuthor(ad *models.Ad) models.AdWithAuthor {
+	result := models.AdWithAuthor{Ad: *ad}
+	if author := r.accountByNickname(ad.Nickname); author != nil {
+		result.AuthorAvatar = author.Avatar
+		result.AuthorRating = author.Rating
+		result.OwnerTelegram = author.Telegram
+	}
+	return result
+}
+
+func (r *memoryAds) FindByID(id uint) (*models.Ad, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad := r.find(id)
+	if ad == nil {
+		return nil, ErrNotFound
+	}
+	found := *ad
+	return &found, nil
+}
+
+func (r *memoryAds) FindWithAuthor(ids []uint) ([]models.AdWithAuthor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ads := make([]models.AdWithAuthor, 0, len(ids))
+	for _, id := range ids {
+		if ad := r.find(id); ad != nil {
+			ads = append(ads, r.withAuthor(ad))
+		}
+	}
+	return ads, nil
+}
+
+func (r *memoryAds) ListByOwner(nickname string, status string) ([]models.AdWithAuthor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ads []models.AdWithAuthor
+	for _, ad := range r.ads {
+		if ad.Nickname != nickname {
+			continue
+		}
+		if (status != "" && ad.Status != status) || (status == "" && ad.Status == models.AdStatusArchived) {
+			continue
+		}
+		ads = append(ads, r.withAuthor(ad))
+	}
+
+	sort.SliceStable(ads, func(i, j int) bool {
+		a, b := ads[i].BumpedAt, ads[j].BumpedAt
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return a.After(*b)
+	})
+	return ads, nil
+}
+
+func (r *memoryAds) Create(ad *models.Ad) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad.ID = r.nextID()
+	if ad.Status == "" {
+		ad.Status = models.AdStatusActive
+	}
+	if ad.CreatedAt.IsZero() {
+		ad.CreatedAt = time.Now()
+	}
+	stored := *ad
+	r.ads = append(r.ads, &stored)
+	return nil
+}
+
+func (r *memoryAds) UpdateIfStatus(id uint, from string, fields map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad := r.find(id)
+	if ad == nil || ad.Status != from {
+		return ErrNotFound
+	}
+	return applyFields(ad, fields)
+}
+
+func (r *memoryAds) IncrementViews(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad := r.find(id)
+	if ad == nil {
+		return ErrNotFound
+	}
+	ad.Views++
+	return nil
+}
+
+func (r *memoryAds) RecordView(nickname string, adID uint, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, view := range r.views {
+		if view.UserNickname == nickname && view.AdID == int(adID) {
+			view.ViewedAt = at
+			return nil
+		}
+	}
+	r.views = append(r.views, &models.ViewedAd{ID: r.nextID(), UserNickname: nickname, AdID: int(adID), ViewedAt: at})
+	return nil
+}
+
+func (r *memoryAds) RenameOwner(oldNickname string, newNickname string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, ad := range r.ads {
+		if ad.Nickname == oldNickname {
+			ad.Nickname = newNickname
+		}
+	}
+	return nil
+}
+
+func (r *memoryAds) AdjustCategoryCount(category string, delta int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.categoryCount[category] + int64(delta)
+	if count < 0 {
+		count = 0
+	}
+	r.categoryCount[category] = count
+	return nil
+}
+
+func (r *memoryAds) CategoryCount(category string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.categoryCount[category], nil
+}
+
+func (r *memoryAds) RecountCategories(ctx context.Context) (map[string]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]int64, len(r.categoryCount))
+	for category := range r.categoryCount {
+		counts[category] = 0
+	}
+	for _, ad := range r.ads {
+		if _, ok := counts[ad.Category]; ok && ad.Status == models.AdStatusActive {
+			counts[ad.Category]++
+		}
+	}
+	for category, count := range counts {
+		r.categoryCount[category] = count
+	}
+	return counts, nil
+}
+
+// match - то же, что postgresAds.filter, для одного объявления
+func (r *memoryAds) match(ad *models.Ad, filter AdFilter) bool {
+	if len(filter.Statuses) > 0 {
+		if !slices.Contains(filter.Statuses, ad.Status) {
+			return false
+		}
+	} else if ad.Status != models.AdStatusActive {
+		return false
+	}
+
+	switch {
+	case filter.Nickname != "" && ad.Nickname != filter.Nickname,
+		filter.Category != "" && ad.Category != filter.Category,
+		filter.Server != "" && filter.Server != "all" && ad.ServerName != filter.Server,
+		filter.Type != "" && ad.Type != filter.Type,
+		filter.Currency != "" && (ad.Currency == nil || *ad.Currency != filter.Currency):
+		return false
+	}
+	// Как в SQL: договорная цена (NULL) не проходит ни один фильтр по цене
+	if filter.PriceMin != nil && (ad.Price == nil || float64(*ad.Price) < *filter.PriceMin) {
+		return false
+	}
+	if filter.PriceMax != nil && (ad.Price == nil || float64(*ad.Price) > *filter.PriceMax) {
+		return false
+	}
+	return true
+}
+
+func (r *memoryAds) List(filter AdFilter, sort string, req PageRequest) ([]models.AdWithAuthor, *Page, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ads []models.AdWithAuthor
+	for _, ad := range r.ads {
+		if r.match(ad, filter) {
+			ads = append(ads, r.withAuthor(ad))
+		}
+	}
+
+	ks := adKeyset(sort, req.Seed)
+	return paginateSlice(ads, ks, req, adKey(ks))
+}
+
+// Search в памяти без словаря: каждое слово запроса должно встретиться в названии или
+// описании как подстрока без учета регистра. rank - сколько раз встретились слова
+func (r *memoryAds) Search(text string, filter AdFilter, sort string, limit int, offset int) ([]models.AdSearchResult, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	words := strings.Fields(strings.ToLower(text))
+	filter.Statuses = nil
+
+	results := make([]models.AdSearchResult, 0)
+	for _, ad := range r.ads {
+		if len(words) == 0 || !r.match(ad, filter) {
+			continue
+		}
+		haystack := strings.ToLower(ad.Title + " " + ad.Description)
+		rank := 0
+		for _, word := range words {
+			count := strings.Count(haystack, word)
+			if count == 0 {
+				rank = 0
+				break
+			}
+			rank += count
+		}
+		if rank == 0 {
+			continue
+		}
+		results = append(results, models.AdSearchResult{
+			AdWithAuthor:         r.withAuthor(ad),
+			Rank:                 float32(rank),
+			TitleHighlight:       highlightWords(ad.Title, words),
+			DescriptionHighlight: highlightWords(ad.Description, words),
+		})
+	}
+
+	// По релевантности, а при равной - свежие выше, как rank DESC, bumped_at DESC в SQL
+	relevance := sort == "" || sort == "relevance"
+	ks := adKeyset(sort, "")
+	key := adKey(ks)
+	slices.SortStableFunc(results, func(a, b models.AdSearchResult) int {
+		if relevance && a.Rank != b.Rank {
+			return cmp.Compare(b.Rank, a.Rank)
+		}
+		av, aid := key(&a.AdWithAuthor)
+		bv, bid := key(&b.AdWithAuthor)
+		if ks.before(av, aid, bv, bid, false) {
+			return -1
+		}
+		return 1
+	})
+
+	total := int64(len(results))
+	results = results[min(offset, len(results)):]
+	return results[:min(limit, len(results))], total, nil
+}
+
+// highlightWords оборачивает маркерами слова текста, в которых есть слово запроса
+func highlightWords(text string, words []string) string {
+	fields := strings.Fields(text)
+	for i, field := range fields {
+		lower := strings.ToLower(field)
+		for _, word := range words {
+			if strings.Contains(lower, word) {
+				fields[i] = HighlightStart + field + HighlightStop
+				break
+			}
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+func (r *memoryAds) Viewed(nickname string, req PageRequest) ([]models.ViewedAdWithAuthor, *Page, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var rows []models.ViewedAdWithAuthor
+	for _, view := range r.views {
+		if view.UserNickname != nickname {
+			continue
+		}
+		ad := r.find(uint(view.AdID))
+		if ad == nil {
+			continue
+		}
+		rows = append(rows, models.ViewedAdWithAuthor{
+			AdWithAuthor: r.withAuthor(ad),
+			ViewedID:     view.ID,
+			UserNickname: view.UserNickname,
+			ViewedAt:     view.ViewedAt,
+		})
+	}
+	return paginateSlice(rows, viewedKeyset, req, viewedKey)
+}
+
+func (r *memoryAds) ExpiringSoon(ctx context.Context, from time.Time, to time.Time) ([]models.Ad, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ads []models.Ad
+	for _, ad := range r.ads {
+		if ad.Status == models.AdStatusActive && ad.ExpiryNotifiedAt == nil && ad.ExpiresAt != nil &&
+			!ad.ExpiresAt.Before(from) && !ad.ExpiresAt.After(to) {
+			ads = append(ads, *ad)
+		}
+	}
+	return ads, nil
+}
+
+func (r *memoryAds) MarkExpiryNotified(ctx context.Context, id uint, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad := r.find(id)
+	if ad == nil || ad.ExpiryNotifiedAt != nil {
+		return ErrNotFound
+	}
+	ad.ExpiryNotifiedAt = &at
+	return nil
+}
+
+func (r *memoryAds) Expire(ctx context.Context, now time.Time) ([]models.Ad, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired []models.Ad
+	for _, ad := range r.ads {
+		if ad.Status == models.AdStatusActive && ad.ExpiresAt != nil && ad.ExpiresAt.Before(now) {
+			ad.Status = models.AdStatusExpired
+			ad.StatusChangedAt = &now
+			expired = append(expired, *ad)
+		}
+	}
+	return expired, nil
+}
+
+func (r *memoryAds) Archive(ctx context.Context, before time.Time, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var archived int64
+	for _, ad := range r.ads {
+		if ad.Status == models.AdStatusExpired && ad.StatusChangedAt != nil && ad.StatusChangedAt.Before(before) {
+			ad.Status = models.AdStatusArchived
+			ad.StatusChangedAt = &now
+			archived++
+		}
+	}
+	return archived, nil
+}
+
+type memoryFeedback struct {
+	*memoryStore
+}
+
+func (r *memoryFeedback) find(id uint) *models.FeedbackAd {
+	for _, feedback := range r.feedback {
+		if feedback.ID == id {
+			return feedback
+		}
+	}
+	return nil
+}
+
+func (r *memoryFeedback) FindByID(id uint) (*models.FeedbackAd, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feedback := r.find(id)
+	if feedback == nil {
+		return nil, ErrNotFound
+	}
+	found := *feedback
+	return &found, nil
+}
+
+func (r *memoryFeedback) Exists(adID uint, reviewer string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, feedback := range r.feedback {
+		if feedback.AdID == int(adID) && feedback.ReviewerNickname == reviewer {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryFeedback) Create(feedback *models.FeedbackAd) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	feedback.ID = r.nextID()
+	if feedback.CreatedAt.IsZero() {
+		feedback.CreatedAt = time.Now()
+	}
+	stored := *feedback
+	r.feedback = append(r.feedback, &stored)
+	return nil
+}
+
+func (r *memoryFeedback) ListConfirmed(ownerNickname string, req PageRequest) ([]models.FeedbackWithReviewer, *Page, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var feedbacks []models.FeedbackWithReviewer
+	for _, feedback := range r.feedback {
+		if feedback.AdOwnerNickname != ownerNickname || !feedback.ConfirmFeedback {
+			continue
+		}
+		row := models.FeedbackWithReviewer{
+			ID:               feedback.ID,
+			AdID:             feedback.AdID,
+			ReviewerNickname: feedback.ReviewerNickname,
+			AdOwnerNickname:  feedback.AdOwnerNickname,
+			Rating:           feedback.Rating,
+			ReviewText:       feedback.ReviewText,
+			ProofImage:       feedback.ProofImage,
+			ConfirmFeedback:  feedback.ConfirmFeedback,
+			CreatedAt:        feedback.CreatedAt,
+		}
+		if reviewer := r.accountByNickname(feedback.ReviewerNickname); reviewer != nil {
+			row.ReviewerAvatar = reviewer.Avatar
+			row.ReviewerRating = reviewer.Rating
+		}
+		feedbacks = append(feedbacks, row)
+	}
+	return paginateSlice(feedbacks, feedbackKeyset, req, feedbackKey)
+}
+
+type memoryReports struct {
+	*memoryStore
+}
+
+func (r *memoryReports) Create(report *models.Report) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report.ID = r.nextID()
+	if report.Status == "" {
+		report.Status = "pending"
+	}
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = time.Now()
+	}
+	stored := *report
+	r.reports = append(r.reports, &stored)
+	return nil
+}
+
+func (r *memoryReports) FindByID(id uint) (*models.Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, report := range r.reports {
+		if report.ID == id {
+			found := *report
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryReports) ListByAd(adID uint) ([]models.Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var reports []models.Report
+	for i := len(r.reports) - 1; i >= 0; i-- {
+		if r.reports[i].AdID == adID {
+			reports = append(reports, *r.reports[i])
+		}
+	}
+	return reports, nil
+}
+
+func (r *memoryReports) Queue(status string, limit int, offset int) ([]models.ReportedAd, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byAd := make(map[uint]*models.ReportedAd)
+	reasons := make(map[uint]map[string]int64)
+	for _, report := range r.reports {
+		if report.Status != status {
+			continue
+		}
+		item, ok := byAd[report.AdID]
+		if !ok {
+			item = &models.ReportedAd{AdID: report.AdID}
+			byAd[report.AdID] = item
+			reasons[report.AdID] = make(map[string]int64)
+		}
+		item.ReportCount++
+		if report.CreatedAt.After(item.LastReportedAt) {
+			item.LastReportedAt = report.CreatedAt
+		}
+		reasons[report.AdID][report.Reason]++
+	}
+
+	queue := make([]models.ReportedAd, 0, len(byAd))
+	for adID, item := range byAd {
+		for reason, count := range reasons[adID] {
+			item.Reasons = append(item.Reasons, models.ReportReasonCount{Reason: reason, Count: count})
+		}
+		slices.SortFunc(item.Reasons, func(a, b models.ReportReasonCount) int {
+			return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Reason, b.Reason))
+		})
+		queue = append(queue, *item)
+	}
+	slices.SortFunc(queue, func(a, b models.ReportedAd) int {
+		return cmp.Or(cmp.Compare(b.ReportCount, a.ReportCount), b.LastReportedAt.Compare(a.LastReportedAt))
+	})
+	queue = queue[min(offset, len(queue)):]
+	return queue[:min(limit, len(queue))], nil
+}
+
+func (r *memoryReports) Decisions(adID uint, moderator string, limit int, offset int) ([]models.ModerationDecision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	decisions := make([]models.ModerationDecision, 0)
+	for i := len(r.decisions) - 1; i >= 0; i-- {
+		decision := r.decisions[i]
+		if (adID == 0 || decision.AdID == adID) && (moderator == "" || decision.ModeratorNickname == moderator) {
+			decisions = append(decisions, *decision)
+		}
+	}
+	decisions = decisions[min(offset, len(decisions)):]
+	return decisions[:min(limit, len(decisions))], nil
+}
+
+func (r *memoryReports) Dismiss(decision *models.ModerationDecision, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, report := range r.reports {
+		if report.ID == *decision.ReportID && report.Status == models.ReportStatusPending {
+			r.resolve(report, models.ReportStatusDismissed, decision.ModeratorNickname, at)
+			r.addDecision(decision)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryReports) Resolve(decision *models.ModerationDecision, adFields map[string]interface{}, at time.Time) ([]models.Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if adFields != nil {
+		for _, ad := range r.ads {
+			if ad.ID == decision.AdID {
+				if err := applyFields(ad, adFields); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	var resolved []models.Report
+	for _, report := range r.reports {
+		if report.AdID == decision.AdID && report.Status == models.ReportStatusPending {
+			r.resolve(report, models.ReportStatusResolved, decision.ModeratorNickname, at)
+			resolved = append(resolved, *report)
+		}
+	}
+	r.addDecision(decision)
+	return resolved, nil
+}
+
+func (r *memoryReports) resolve(report *models.Report, status string, moderator string, at time.Time) {
+	report.Status = status
+	report.ResolvedBy = &moderator
+	report.ResolvedAt = &at
+}
+
+func (r *memoryReports) addDecision(decision *models.ModerationDecision) {
+	decision.ID = r.nextID()
+	if decision.CreatedAt.IsZero() {
+		decision.CreatedAt = time.Now()
+	}
+	stored := *decision
+	r.decisions = append(r.decisions, &stored)
+}
+
+type memoryNotifications struct {
+	*memoryStore
+}
+
+func (r *memoryNotifications) Create(notification *models.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notification.ID = r.nextID()
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	stored := *notification
+	r.notifications = append(r.notifications, &stored)
+	return nil
+}
+
+func (r *memoryNotifications) List(nickname string, unreadOnly bool, limit int, offset int) ([]models.Notification, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notifications := make([]models.Notification, 0)
+	for i := len(r.notifications) - 1; i >= 0; i-- {
+		notification := r.notifications[i]
+		if notification.UserNickname == nickname && (!unreadOnly || notification.ReadAt == nil) {
+			notifications = append(notifications, *notification)
+		}
+	}
+	sort.SliceStable(notifications, func(i, j int) bool {
+		return notifications[i].CreatedAt.After(notifications[j].CreatedAt)
+	})
+
+	total := int64(len(notifications))
+	notifications = notifications[min(offset, len(notifications)):]
+	return notifications[:min(limit, len(notifications))], total, nil
+}
+
+func (r *memoryNotifications) CountUnread(nickname string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, notification := range r.notifications {
+		if notification.UserNickname == nickname && notification.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryNotifications) MarkRead(id uint, nickname string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, notification := range r.notifications {
+		if notification.ID == id && notification.UserNickname == nickname {
+			if notification.ReadAt == nil {
+				notification.ReadAt = &at
+			}
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryNotifications) MarkAllRead(nickname string, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var marked int64
+	for _, notification := range r.notifications {
+		if notification.UserNickname == nickname && notification.ReadAt == nil {
+			notification.ReadAt = &at
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (r *memoryNotifications) RenameUser(oldNickname string, newNickname string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, notification := range r.notifications {
+		if notification.UserNickname == oldNickname {
+			notification.UserNickname = newNickname
+		}
+	}
+	return nil
+}
+
+type memoryOutbox struct {
+	*memoryStore
+}
+
+func (r *memoryOutbox) Enqueue(email *models.OutboxEmail) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	email.ID = r.nextID()
+	if email.Status == "" {
+		email.Status = models.OutboxPending
+	}
+	if email.CreatedAt.IsZero() {
+		email.CreatedAt = time.Now()
+	}
+	stored := *email
+	r.outbox = append(r.outbox, &stored)
+	return nil
+}
+
+func (r *memoryOutbox) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEmail, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []*models.OutboxEmail
+	for _, email := range r.outbox {
+		if email.Status == models.OutboxPending && !email.NextAttemptAt.After(now) {
+			due = append(due, email)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	emails := make([]models.OutboxEmail, len(due))
+	for i, email := range due {
+		emails[i] = *email
+		email.NextAttemptAt = now.Add(lease)
+	}
+	return emails, nil
+}
+
+func (r *memoryOutbox) find(id uint) *models.OutboxEmail {
+	for _, email := range r.outbox {
+		if email.ID == id {
+			return email
+		}
+	}
+	return nil
+}
+
+func (r *memoryOutbox) MarkSent(id uint, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	email := r.find(id)
+	if email == nil {
+		return ErrNotFound
+	}
+	email.Status = models.OutboxSent
+	email.Attempts++
+	email.SentAt = &at
+	email.HTML, email.Text = "", ""
+	return nil
+}
+
+func (r *memoryOutbox) MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	email := r.find(id)
+	if email == nil {
+		return ErrNotFound
+	}
+	email.Attempts = attempts
+	email.NextAttemptAt = nextAttemptAt
+	email.LastError = lastError
+	return nil
+}
+
+func (r *memoryOutbox) MarkDead(id uint, attempts int, lastError string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	email := r.find(id)
+	if email == nil {
+		return ErrNotFound
+	}
+	email.Status = models.OutboxDead
+	email.Attempts = attempts
+	email.LastError = lastError
+	email.HTML, email.Text = "", ""
+	return nil
+}
+
+func (r *memoryOutbox) DeleteFinished(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	kept := r.outbox[:0]
+	for _, email := range r.outbox {
+		if email.Status != models.OutboxPending && email.CreatedAt.Before(before) {
+			deleted++
+			continue
+		}
+		kept = append(kept, email)
+	}
+	r.outbox = kept
+	return deleted, nil
+}
+
+type memoryTokens struct {
+	*memoryStore
+}
+
+func (r *memoryTokens) Create(token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.create(token)
+}
+
+func (r *memoryTokens) create(token *models.RefreshToken) error {
+	for _, existing := range r.tokens {
+		if existing.Token == token.Token {
+			return errDuplicate
+		}
+	}
+
+	token.ID = r.nextID()
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	stored := *token
+	r.tokens = append(r.tokens, &stored)
+	return nil
+}
+
+func (r *memoryTokens) FindByToken(token string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.tokens {
+		if stored.Token == token {
+			found := *stored
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryTokens) Rotate(token string, accountID uint, now time.Time, next RotateFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stored *models.RefreshToken
+	for _, candidate := range r.tokens {
+		if candidate.Token == token && candidate.AccountID == accountID && candidate.ExpiresAt.After(now) {
+			stored = candidate
+			break
+		}
+	}
+	if stored == nil {
+		return ErrNotFound
+	}
+
+	current := *stored
+	replacement, err := next(&current)
+	if err != nil || replacement == nil {
+		return err
+	}
+
+	if err := r.create(replacement); err != nil {
+		return err
+	}
+	stored.RotatedAt = &now
+	return nil
+}
+
+func (r *memoryTokens) ListActive(accountID uint, now time.Time) ([]models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var tokens []models.RefreshToken
+	for _, token := range r.tokens {
+		if token.AccountID == accountID && token.RotatedAt == nil && token.ExpiresAt.After(now) {
+			tokens = append(tokens, *token)
+		}
+	}
+
+	sort.SliceStable(tokens, func(i, j int) bool {
+		if !tokens[i].LastUsedAt.Equal(tokens[j].LastUsedAt) {
+			return tokens[i].LastUsedAt.After(tokens[j].LastUsedAt)
+		}
+		return tokens[i].ID > tokens[j].ID
+	})
+	return tokens, nil
+}
+
+func (r *memoryTokens) DeleteFamily(accountID uint, familyID string) (int64, error) {
+	return r.deleteWhere(func(token *models.RefreshToken) bool {
+		return token.AccountID == accountID && token.FamilyID == familyID
+	}, nil)
+}
+
+func (r *memoryTokens) DeleteAllExcept(accountID uint, exceptFamily string, now time.Time) (int64, error) {
+	return r.deleteWhere(func(token *models.RefreshToken) bool {
+		return token.AccountID == accountID && (exceptFamily == "" || token.FamilyID != exceptFamily)
+	}, func(token *models.RefreshToken) bool {
+		return token.RotatedAt == nil && token.ExpiresAt.After(now)
+	})
+}
+
+func (r *memoryTokens) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	return r.deleteWhere(func(token *models.RefreshToken) bool {
+		return token.ExpiresAt.Before(now)
+	}, nil)
+}
+
+// deleteWhere удаляет токены по match. Если передан counted, возвращает сколько
+// удаленных ему соответствовали, иначе просто сколько удалено
+func (r *memoryTokens) deleteWhere(match func(*models.RefreshToken) bool, counted func(*models.RefreshToken) bool) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	kept := r.tokens[:0]
+	for _, token := range r.tokens {
+		if !match(token) {
+			kept = append(kept, token)
+			continue
+		}
+		if counted == nil || counted(token) {
+			count++
+		}
+	}
+	r.tokens = kept
+	return count, nil
+}
+
+type memoryReputation struct {
+	*memoryStore
+}
+
+func (r *memoryReputation) Find(sellerID uint) (*models.SellerReputation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rep, ok := r.reputation[sellerID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	found := *rep
+	return &found, nil
+}
+
+// reviews собирает то же, что JOIN в postgresReputation.reviews. keep отбирает отзывы
+func (r *memoryReputation) reviews(sellerID uint, keep func(feedback *models.FeedbackAd) bool) []models.ReputationReview {
+	var seller *models.Account
+	for _, account := range r.accounts {
+		if account.ID == sellerID {
+			seller = account
+		}
+	}
+	if seller == nil {
+		return nil
+	}
+
+	var reviews []models.ReputationReview
+	for _, feedback := range r.feedback {
+		if feedback.AdOwnerNickname != seller.Nickname || !feedback.ConfirmFeedback || !keep(feedback) {
+			continue
+		}
+		review := models.ReputationReview{
+			FeedbackID:  feedback.ID,
+			Rating:      feedback.Rating,
+			CreatedAt:   feedback.CreatedAt,
+			ConfirmedAt: feedback.CreatedAt,
+		}
+		if feedback.ConfirmedAt != nil {
+			review.ConfirmedAt = *feedback.ConfirmedAt
+		}
+		if reviewer := r.accountByNickname(feedback.ReviewerNickname); reviewer != nil {
+			review.ReviewerRating = reviewer.Rating
+			review.ReviewerDeals = reviewer.SuccessTransactions
+		}
+		reviews = append(reviews, review)
+	}
+	sort.SliceStable(reviews, func(i, j int) bool { return reviews[i].CreatedAt.Before(reviews[j].CreatedAt) })
+	return reviews
+}
+
+func (r *memoryReputation) Reviews(sellerID uint, since time.Time) ([]models.ReputationReview, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	all := r.reviews(sellerID, func(*models.FeedbackAd) bool { return true })
+	var reviews []models.ReputationReview
+	for _, review := range all {
+		if !review.ConfirmedAt.Before(since) {
+			reviews = append(reviews, review)
+		}
+	}
+	return reviews, nil
+}
+
+// update работает с копией записи и сохраняет ее только после успешного apply, как откат транзакции
+func (r *memoryReputation) update(sellerID uint, reviews func(created bool) []models.ReputationReview, apply ReputationApply) error {
+	rep := models.SellerReputation{AccountID: sellerID}
+	stored, ok := r.reputation[sellerID]
+	if ok {
+		rep = *stored
+	}
+
+	if err := apply(&rep, reviews(!ok)); err != nil {
+		return err
+	}
+	r.save(&rep)
+	return nil
+}
+
+func (r *memoryReputation) save(rep *models.SellerReputation) {
+	rep.UpdatedAt = time.Now()
+	stored := *rep
+	r.reputation[rep.AccountID] = &stored
+
+	for _, account := range r.accounts {
+		if account.ID == rep.AccountID {
+			account.Rating = float32(rep.Rating)
+			account.SuccessTransactions = rep.Count()
+		}
+	}
+}
+
+func (r *memoryReputation) ConfirmFeedback(feedbackID uint, sellerID uint, at time.Time, apply ReputationApply) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var feedback *models.FeedbackAd
+	for _, candidate := range r.feedback {
+		if candidate.ID == feedbackID {
+			feedback = candidate
+		}
+	}
+	if feedback == nil || feedback.ConfirmFeedback {
+		return ErrNotFound
+	}
+
+	// Отзыв помечается подтвержденным только на время выборки: если apply упадет, все вернется как было
+	feedback.ConfirmFeedback, feedback.ConfirmedAt = true, &at
+	err := r.update(sellerID, func(created bool) []models.ReputationReview {
+		return r.reviews(sellerID, func(candidate *models.FeedbackAd) bool {
+			return created || candidate.ID == feedbackID
+		})
+	}, apply)
+	if err != nil {
+		feedback.ConfirmFeedback, feedback.ConfirmedAt = false, nil
+	}
+	return err
+}
+
+func (r *memoryReputation) Rebuild(sellerID uint, apply ReputationApply) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.update(sellerID, func(bool) []models.ReputationReview {
+		return r.reviews(sellerID, func(*models.FeedbackAd) bool { return true })
+	}, apply)
+}
+
+func (r *memoryReputation) Update(sellerID uint, apply func(rep *models.SellerReputation) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.reputation[sellerID]
+	if !ok {
+		return ErrNotFound
+	}
+	rep := *stored
+	if err := apply(&rep); err != nil {
+		return err
+	}
+	r.save(&rep)
+	return nil
+}
+
+func (r *memoryReputation) Sellers(afterID uint, limit int) ([]uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []uint
+	for id := range r.reputation {
+		if id > afterID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+func (r *memoryReputation) Unbuilt(limit int) ([]uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []uint
+	for _, account := range r.accounts {
+		if _, ok := r.reputation[account.ID]; ok {
+			continue
+		}
+		for _, feedback := range r.feedback {
+			if feedback.AdOwnerNickname == account.Nickname && feedback.ConfirmFeedback {
+				ids = append(ids, account.ID)
+				break
+			}
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+type memoryTwoFactor struct {
+	*memoryStore
+}
+
+func (r *memoryTwoFactor) find(accountID uint) *models.TwoFactor {
+	for _, tf := range r.twoFactor {
+		if tf.AccountID == accountID {
+			return tf
+		}
+	}
+	return nil
+}
+
+func (r *memoryTwoFactor) Find(accountID uint) (*models.TwoFactor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tf := r.find(accountID)
+	if tf == nil {
+		return nil, ErrNotFound
+	}
+	found := *tf
+	return &found, nil
+}
+
+func (r *memoryTwoFactor) Enabled(accountID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tf := r.find(accountID)
+	return tf != nil && tf.Enabled, nil
+}
+
+func (r *memoryTwoFactor) CountBackupCodes(accountID uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, code := range r.backupCodes {
+		if code.AccountID == accountID && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryTwoFactor) SaveSecret(accountID uint, secret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tf := r.find(accountID)
+	if tf == nil {
+		r.twoFactor = append(r.twoFactor, &models.TwoFactor{ID: r.nextID(), AccountID: accountID, Secret: secret, CreatedAt: time.Now()})
+		return nil
+	}
+	if tf.Enabled {
+		return ErrNotFound
+	}
+	tf.Secret = secret
+	return nil
+}
+
+func (r *memoryTwoFactor) Enable(accountID uint, secret string, step int64, at time.Time, codes []models.TwoFactorBackupCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tf := r.find(accountID)
+	if tf == nil || tf.Enabled || tf.Secret != secret {
+		return ErrNotFound
+	}
+	tf.Enabled, tf.EnabledAt, tf.LastUsedStep = true, &at, step
+	r.replaceBackupCodes(accountID, codes)
+	return nil
+}
+
+func (r *memoryTwoFactor) UseStep(accountID uint, step int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tf := r.find(accountID)
+	if tf == nil || !tf.Enabled || tf.LastUsedStep >= step {
+		return ErrNotFound
+	}
+	tf.LastUsedStep = step
+	return nil
+}
+
+func (r *memoryTwoFactor) UnusedBackupCodes(accountID uint) ([]models.TwoFactorBackupCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var codes []models.TwoFactorBackupCode
+	for _, code := range r.backupCodes {
+		if code.AccountID == accountID && code.UsedAt == nil {
+			codes = append(codes, *code)
+		}
+	}
+	return codes, nil
+}
+
+func (r *memoryTwoFactor) UseBackupCode(id uint, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, code := range r.backupCodes {
+		if code.ID == id && code.UsedAt == nil {
+			code.UsedAt = &at
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryTwoFactor) ReplaceBackupCodes(accountID uint, codes []models.TwoFactorBackupCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.replaceBackupCodes(accountID, codes)
+	return nil
+}
+
+func (r *memoryTwoFactor) replaceBackupCodes(accountID uint, codes []models.TwoFactorBackupCode) {
+	r.deleteBackupCodes(accountID)
+	for i := range codes {
+		codes[i].ID = r.nextID()
+		if codes[i].CreatedAt.IsZero() {
+			codes[i].CreatedAt = time.Now()
+		}
+		stored := codes[i]
+		r.backupCodes = append(r.backupCodes, &stored)
+	}
+}
+
+func (r *memoryTwoFactor) deleteBackupCodes(accountID uint) {
+	kept := r.backupCodes[:0]
+	for _, code := range r.backupCodes {
+		if code.AccountID != accountID {
+			kept = append(kept, code)
+		}
+	}
+	r.backupCodes = kept
+}
+
+func (r *memoryTwoFactor) Delete(accountID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deleteBackupCodes(accountID)
+	r.twoFactor = slices.DeleteFunc(r.twoFactor, func(tf *models.TwoFactor) bool { return tf.AccountID == accountID })
+	return nil
+}
+
+func (r *memoryTwoFactor) ReserveAttempt(challenge models.TwoFactorChallenge, now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for jti, existing := range r.challenges {
+		if existing.AccountID == challenge.AccountID && !existing.ExpiresAt.After(now) {
+			delete(r.challenges, jti)
+		}
+	}
+
+	stored, ok := r.challenges[challenge.JTI]
+	if !ok {
+		challenge.Attempts = 0
+		stored = &challenge
+		r.challenges[challenge.JTI] = stored
+	}
+	stored.Attempts++
+	return stored.Attempts, nil
+}
+
+func (r *memoryTwoFactor) DeleteChallenge(jti string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.challenges, jti)
+	return nil
+}
+
+type memoryPasswordResets struct {
+	*memoryStore
+}
+
+func (r *memoryPasswordResets) Find(accountID uint) (*models.PasswordReset, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, reset := range r.resets {
+		if reset.AccountID == accountID {
+			found := *reset
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryPasswordResets) Replace(reset *models.PasswordReset) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resets = slices.DeleteFunc(r.resets, func(existing *models.PasswordReset) bool { return existing.AccountID == reset.AccountID })
+	reset.ID = r.nextID()
+	if reset.CreatedAt.IsZero() {
+		reset.CreatedAt = time.Now()
+	}
+	stored := *reset
+	r.resets = append(r.resets, &stored)
+	return nil
+}
+
+func (r *memoryPasswordResets) AddAttempt(id uint) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, reset := range r.resets {
+		if reset.ID == id {
+			reset.Attempts++
+			return reset.Attempts, nil
+		}
+	}
+	return 0, ErrNotFound
+}
+
+func (r *memoryPasswordResets) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before := len(r.resets)
+	r.resets = slices.DeleteFunc(r.resets, func(reset *models.PasswordReset) bool { return reset.ID == id })
+	if len(r.resets) == before {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type memoryEmailChanges struct {
+	*memoryStore
+}
+
+func (r *memoryEmailChanges) find(match func(change *models.EmailChange) bool) (*models.EmailChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, change := range r.emailChanges {
+		if match(change) {
+			found := *change
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryEmailChanges) Replace(change *models.EmailChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.emailChanges = slices.DeleteFunc(r.emailChanges, func(existing *models.EmailChange) bool { return existing.AccountID == change.AccountID })
+	change.ID = r.nextID()
+	if change.CreatedAt.IsZero() {
+		change.CreatedAt = time.Now()
+	}
+	stored := *change
+	r.emailChanges = append(r.emailChanges, &stored)
+	return nil
+}
+
+func (r *memoryEmailChanges) FindPending(accountID uint) (*models.EmailChange, error) {
+	return r.find(func(change *models.EmailChange) bool {
+		return change.AccountID == accountID && change.ConfirmedAt == nil
+	})
+}
+
+func (r *memoryEmailChanges) FindByUndoToken(tokenHash string) (*models.EmailChange, error) {
+	return r.find(func(change *models.EmailChange) bool {
+		return change.UndoTokenHash == tokenHash && change.ConfirmedAt != nil
+	})
+}
+
+func (r *memoryEmailChanges) AddAttempt(id uint) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, change := range r.emailChanges {
+		if change.ID == id && change.ConfirmedAt == nil {
+			change.Attempts++
+			return change.Attempts, nil
+		}
+	}
+	return 0, ErrNotFound
+}
+
+func (r *memoryEmailChanges) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before := len(r.emailChanges)
+	r.emailChanges = slices.DeleteFunc(r.emailChanges, func(change *models.EmailChange) bool { return change.ID == id })
+	if len(r.emailChanges) == before {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// setEmail меняет адрес аккаунта с той же проверкой уникальности, что и индекс в PostgreSQL
+func (r *memoryEmailChanges) setEmail(accountID uint, email string, fields map[string]interface{}) error {
+	var account *models.Account
+	for _, candidate := range r.accounts {
+		if candidate.ID == accountID {
+			account = candidate
+		} else if email != "" && candidate.Email == email {
+			return errDuplicate
+		}
+	}
+	if account == nil {
+		return nil
+	}
+	return applyFields(account, fields)
+}
+
+func (r *memoryEmailChanges) Confirm(change *models.EmailChange, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := slices.IndexFunc(r.emailChanges, func(stored *models.EmailChange) bool {
+		return stored.ID == change.ID && stored.ConfirmedAt == nil
+	})
+	if i < 0 {
+		return ErrNotFound
+	}
+
+	if err := r.setEmail(change.AccountID, change.NewEmail, map[string]interface{}{
+		"email":                change.NewEmail,
+		"email_verified":       true,
+		"last_email_change":    &at,
+		"last_settings_change": &at,
+	}); err != nil {
+		return err
+	}
+
+	stored := r.emailChanges[i]
+	stored.ConfirmedAt, stored.UndoTokenHash, stored.UndoExpiresAt = change.ConfirmedAt, change.UndoTokenHash, change.UndoExpiresAt
+	return nil
+}
+
+func (r *memoryEmailChanges) Undo(change *models.EmailChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := slices.IndexFunc(r.emailChanges, func(stored *models.EmailChange) bool { return stored.ID == change.ID })
+	if i < 0 {
+		return ErrNotFound
+	}
+
+	if err := r.setEmail(change.AccountID, change.OldEmail, map[string]interface{}{
+		"email":          change.OldEmail,
+		"email_verified": change.OldEmailVerified,
+	}); err != nil {
+		return err
+	}
+	r.emailChanges = slices.Delete(r.emailChanges, i, i+1)
+	return nil
+}
+
+type memoryFavorites struct {
+	*memoryStore
+}
+
+func (r *memoryFavorites) Create(favorite *models.FavoriteAd) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.favorites {
+		if existing.UserNickname == favorite.UserNickname && existing.AdID == favorite.AdID {
+			return errDuplicate
+		}
+	}
+
+	favorite.ID = r.nextID()
+	if favorite.CreatedAt.IsZero() {
+		favorite.CreatedAt = time.Now()
+	}
+	stored := *favorite
+	r.favorites = append(r.favorites, &stored)
+	return nil
+}
+
+func (r *memoryFavorites) Delete(nickname string, adID int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before := len(r.favorites)
+	r.favorites = slices.DeleteFunc(r.favorites, func(favorite *models.FavoriteAd) bool {
+		return favorite.UserNickname == nickname && favorite.AdID == adID
+	})
+	return len(r.favorites) < before, nil
+}
+
+func (r *memoryFavorites) List(nickname string) ([]models.FavoriteAd, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var favorites []models.FavoriteAd
+	for i := len(r.favorites) - 1; i >= 0; i-- {
+		if r.favorites[i].UserNickname == nickname {
+			favorites = append(favorites, *r.favorites[i])
+		}
+	}
+	sort.SliceStable(favorites, func(i, j int) bool { return favorites[i].CreatedAt.After(favorites[j].CreatedAt) })
+	return favorites, nil
+}
+
+func (r *memoryFavorites) CountByAd(adID uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, favorite := range r.favorites {
+		if favorite.AdID == int(adID) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryFavorites) AddPriceChange(change *models.AdPriceChange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change.ID = r.nextID()
+	if change.ChangedAt.IsZero() {
+		change.ChangedAt = time.Now()
+	}
+	stored := *change
+	r.priceChanges = append(r.priceChanges, &stored)
+	return nil
+}
+
+func (r *memoryFavorites) LastPriceChanges(adIDs []int) ([]models.AdPriceChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := make(map[int]models.AdPriceChange)
+	for _, change := range r.priceChanges {
+		if !slices.Contains(adIDs, change.AdID) {
+			continue
+		}
+		if current, ok := last[change.AdID]; !ok || !change.ChangedAt.Before(current.ChangedAt) {
+			last[change.AdID] = *change
+		}
+	}
+
+	changes := make([]models.AdPriceChange, 0, len(last))
+	for _, change := range last {
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+type memoryMessages struct {
+	*memoryStore
+}
+
+func (r *memoryMessages) findConversation(match func(conversation *models.Conversation) bool) (*models.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, conversation := range r.conversations {
+		if match(conversation) {
+			found := *conversation
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryMessages) FindConversation(id uint) (*models.Conversation, error) {
+	return r.findConversation(func(conversation *models.Conversation) bool { return conversation.ID == id })
+}
+
+func (r *memoryMessages) FindConversationByAd(adID uint, buyer string) (*models.Conversation, error) {
+	return r.findConversation(func(conversation *models.Conversation) bool {
+		return conversation.AdID == adID && conversation.BuyerNickname == buyer
+	})
+}
+
+func (r *memoryMessages) CreateConversation(conversation *models.Conversation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.conversations {
+		if existing.AdID == conversation.AdID && existing.BuyerNickname == conversation.BuyerNickname {
+			return errDuplicate
+		}
+	}
+
+	conversation.ID = r.nextID()
+	if conversation.CreatedAt.IsZero() {
+		conversation.CreatedAt = time.Now()
+	}
+	stored := *conversation
+	r.conversations = append(r.conversations, &stored)
+	return nil
+}
+
+func (r *memoryMessages) ListConversations(nickname string) ([]models.ConversationSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversations := make([]models.ConversationSummary, 0)
+	for _, conversation := range r.conversations {
+		if !conversation.HasMember(nickname) {
+			continue
+		}
+
+		summary := models.ConversationSummary{Conversation: *conversation}
+		summary.CompanionNickname = conversation.Companion(nickname)
+		if companion := r.accountByNickname(summary.CompanionNickname); companion != nil {
+			summary.CompanionAvatar = companion.Avatar
+		}
+		for _, ad := range r.ads {
+			if ad.ID == conversation.AdID {
+				summary.AdTitle, summary.AdImage, summary.AdStatus = ad.Title, ad.Image, ad.Status
+			}
+		}
+		for _, message := range r.messages {
+			if message.ConversationID != conversation.ID {
+				continue
+			}
+			body, sender := message.Body, message.SenderNickname
+			summary.LastMessage, summary.LastSenderNickname = &body, &sender
+			if message.SenderNickname != nickname && message.ReadAt == nil {
+				summary.UnreadCount++
+			}
+		}
+		conversations = append(conversations, summary)
+	}
+
+	sort.SliceStable(conversations, func(i, j int) bool {
+		return conversations[i].LastMessageAt.After(conversations[j].LastMessageAt)
+	})
+	return conversations, nil
+}
+
+func (r *memoryMessages) ListConversationsByAd(adID uint) ([]models.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var conversations []models.Conversation
+	for _, conversation := range r.conversations {
+		if conversation.AdID == adID {
+			conversations = append(conversations, *conversation)
+		}
+	}
+	return conversations, nil
+}
+
+func (r *memoryMessages) AddMessage(message *models.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	message.ID = r.nextID()
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now()
+	}
+	stored := *message
+	r.messages = append(r.messages, &stored)
+
+	for _, conversation := range r.conversations {
+		if conversation.ID == message.ConversationID {
+			conversation.LastMessageAt = message.CreatedAt
+		}
+	}
+	return nil
+}
+
+func (r *memoryMessages) ListMessages(conversationID uint, limit int, beforeID uint) ([]models.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	messages := make([]models.Message, 0)
+	for i := len(r.messages) - 1; i >= 0 && len(messages) < limit; i-- {
+		message := r.messages[i]
+		if message.ConversationID == conversationID && (beforeID == 0 || message.ID < beforeID) {
+			messages = append(messages, *message)
+		}
+	}
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+func (r *memoryMessages) MessagesOf(conversationIDs []uint) ([]models.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var messages []models.Message
+	for _, message := range r.messages {
+		if slices.Contains(conversationIDs, message.ConversationID) {
+			messages = append(messages, *message)
+		}
+	}
+	return messages, nil
+}
+
+func (r *memoryMessages) CountUnread(nickname string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, conversation := range r.conversations {
+		if !conversation.HasMember(nickname) {
+			continue
+		}
+		for _, message := range r.messages {
+			if message.ConversationID == conversation.ID && message.SenderNickname != nickname && message.ReadAt == nil {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryMessages) MarkRead(conversationID uint, reader string, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var marked int64
+	for _, message := range r.messages {
+		if message.ConversationID == conversationID && message.SenderNickname != reader && message.ReadAt == nil {
+			message.ReadAt = &at
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (r *memoryMessages) Blocked(first string, second string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, block := range r.blocks {
+		if (block.BlockerNickname == first && block.BlockedNickname == second) ||
+			(block.BlockerNickname == second && block.BlockedNickname == first) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryMessages) Block(block *models.UserBlock) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.blocks {
+		if existing.BlockerNickname == block.BlockerNickname && existing.BlockedNickname == block.BlockedNickname {
+			return errDuplicate
+		}
+	}
+
+	block.ID = r.nextID()
+	if block.CreatedAt.IsZero() {
+		block.CreatedAt = time.Now()
+	}
+	stored := *block
+	r.blocks = append(r.blocks, &stored)
+	return nil
+}
+
+func (r *memoryMessages) Unblock(blocker string, blocked string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	before := len(r.blocks)
+	r.blocks = slices.DeleteFunc(r.blocks, func(block *models.UserBlock) bool {
+		return block.BlockerNickname == blocker && block.BlockedNickname == blocked
+	})
+	return len(r.blocks) < before, nil
+}
+
+func (r *memoryMessages) ListBlocked(blocker string) ([]models.UserBlock, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	blocks := make([]models.UserBlock, 0)
+	for i := len(r.blocks) - 1; i >= 0; i-- {
+		if r.blocks[i].BlockerNickname == blocker {
+			blocks = append(blocks, *r.blocks[i])
+		}
+	}
+	return blocks, nil
+}
+
+func (r *memoryMessages) RenameUser(oldNickname string, newNickname string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rename := func(nickname *string) {
+		if *nickname == oldNickname {
+			*nickname = newNickname
+		}
+	}
+	for _, conversation := range r.conversations {
+		rename(&conversation.SellerNickname)
+		rename(&conversation.BuyerNickname)
+	}
+	for _, message := range r.messages {
+		rename(&message.SenderNickname)
+	}
+	for _, block := range r.blocks {
+		rename(&block.BlockerNickname)
+		rename(&block.BlockedNickname)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MessageRepository - переписки покупателей с продавцами, сообщения в них и блокировки пользователей
+type MessageRepository interface {
+	FindConversation(id uint) (*models.Conversation, error)
+	// FindConversationByAd - переписка покупателя по объявлению. ErrNotFound, если ее еще нет
+	FindConversationByAd(adID uint, buyer string) (*models.Conversation, error)
+	// CreateConversation - ошибка дубликата, если у покупателя уже есть переписка по этому объявлению
+	CreateConversation(conversation *models.Conversation) error
+	// ListConversations - переписки пользователя с последним сообщением и числом непрочитанных,
+	// сверху самые свежие
+	ListConversations(nickname string) ([]models.ConversationSummary, error)
+	// ListConversationsByAd - все переписки по объявлению, от старых к новым
+	ListConversationsByAd(adID uint) ([]models.Conversation, error)
+
+	// AddMessage сохраняет сообщение и сдвигает last_message_at переписки
+	AddMessage(message *models.Message) error
+	// ListMessages - последние limit сообщений переписки до beforeID (0 - самые последние),
+	// от старых к новым
+	ListMessages(conversationID uint, limit int, beforeID uint) ([]models.Message, error)
+	// MessagesOf - все сообщения переписок, от старых к новым
+	MessagesOf(conversationIDs []uint) ([]models.Message, error)
+	// CountUnread - непрочитанные входящие пользователя во всех его переписках
+	CountUnread(nickname string) (int64, error)
+	// MarkRead помечает прочитанными входящие сообщения переписки, возвращает сколько
+	MarkRead(conversationID uint, reader string, at time.Time) (int64, error)
+
+	// Blocked проверяет, заблокировал ли кто-то из двух пользователей другого
+	Blocked(first string, second string) (bool, error)
+	// Block - ошибка дубликата, если пользователь уже заблокирован
+	Block(block *models.UserBlock) error
+	// Unblock возвращает false, если блокировки не было
+	Unblock(blocker string, blocked string) (bool, error)
+	// ListBlocked - кого заблокировал пользователь, сверху последние
+	ListBlocked(blocker string) ([]models.UserBlock, error)
+
+	// RenameUser переносит переписки, сообщения и блокировки на новый ник
+	RenameUser(oldNickname string, newNickname string) error
+}
+
+type postgresMessages struct {
+	db *gorm.DB
+}
+
+func (r *postgresMessages) FindConversation(id uint) (*models.Conversation, error) {
+	var conversation models.Conversation
+	if err := r.db.Where("id = ?", id).First(&conversation).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &conversation, nil
+}
+
+func (r *postgresMessages) FindConversationByAd(adID uint, buyer string) (*models.Conversation, error) {
+	var conversation models.Conversation
+	if err := r.db.Where("ad_id = ? AND buyer_nickname = ?", adID, buyer).First(&conversation).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &conversation, nil
+}
+
+func (r *postgresMessages) CreateConversation(conversation *models.Conversation) error {
+	return r.db.Create(conversation).Error
+}
+
+func (r *postgresMessages) ListConversations(nickname string) ([]models.ConversationSummary, error) {
+	conversations := make([]models.ConversationSummary, 0)
+
+	err := r.db.Raw(`
+		WITH c AS (
+			SELECT *,
+				CASE WHEN seller_nickname = @nickname THEN buyer_nickname ELSE seller_nickname END AS companion_nickname
+			FROM conversations
+			WHERE seller_nickname = @nickname OR buyer_nickname = @nickname
+		)
+		SELECT c.*,
+			COALESCE(ads.title, '') AS ad_title,
+			COALESCE(ads.image, '') AS ad_image,
+			COALESCE(ads.status, '') AS ad_status,
+			COALESCE(companions.avatar, '') AS companion_avatar,
+			last.body AS last_message,
+			last.sender_nickname AS last_sender_nickname,
+			(SELECT COUNT(*) FROM messages m
+				WHERE m.conversation_id = c.id AND m.sender_nickname <> @nickname AND m.read_at IS NULL) AS unread_count
+		FROM c
+		LEFT JOIN ads ON ads.id = c.ad_id
+		LEFT JOIN accounts companions ON companions.nickname = c.companion_nickname
+		LEFT JOIN LATERAL (
+			SELECT body, sender_nickname FROM messages
+			WHERE conversation_id = c.id
+			ORDER BY id DESC
+			LIMIT 1
+		) last ON true
+		ORDER BY c.last_message_at DESC`, map[string]interface{}{"nickname": nickname}).
+		Scan(&conversations).Error
+	return conversations, err
+}
+
+func (r *postgresMessages) ListConversationsByAd(adID uint) ([]models.Conversation, error) {
+	var conversations []models.Conversation
+	err := r.db.Where("ad_id = ?", adID).Order("created_at ASC").Find(&conversations).Error
+	return conversations, err
+}
+
+func (r *postgresMessages) AddMessage(message *models.Message) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Conversation{}).
+			Where("id = ?", message.ConversationID).
+			Update("last_message_at", message.CreatedAt).Error
+	})
+}
+
+func (r *postgresMessages) ListMessages(conversationID uint, limit int, beforeID uint) ([]models.Message, error) {
+	messages := make([]models.Message, 0)
+
+	query := r.db.Where("conversation_id = ?", conversationID)
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+	if err := query.Order("id DESC").Limit(limit).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+func (r *postgresMessages) MessagesOf(conversationIDs []uint) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.db.Where("conversation_id IN ?", conversationIDs).Order("id ASC").Find(&messages).Error
+	return messages, err
+}
+
+func (r *postgresMessages) CountUnread(nickname string) (int64, error) {
+	var count int64
+	err := r.db.Table("messages").
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+		Where("conversations.seller_nickname = ? OR conversations.buyer_nickname = ?", nickname, nickname).
+		Where("messages.sender_nickname <> ? AND messages.read_at IS NULL", nickname).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *postgresMessages) MarkRead(conversationID uint, reader string, at time.Time) (int64, error) {
+	result := r.db.Model(&models.Message{}).
+		Where("conversation_id = ? AND sender_nickname <> ? AND read_at IS NULL", conversationID, reader).
+		Update("read_at", at)
+	return result.RowsAffected, result.Error
+}
+
+func (r *postgresMessages) Blocked(first string, second string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.UserBlock{}).
+		Where("(blocker_nickname = ? AND blocked_nickname = ?) OR (blocker_nickname = ? AND blocked_nickname = ?)",
+			first, second, second, first).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *postgresMessages) Block(block *models.UserBlock) error {
+	return r.db.Create(block).Error
+}
+
+func (r *postgresMessages) Unblock(blocker string, blocked string) (bool, error) {
+	result := r.db.Where("blocker_nickname = ? AND blocked_nickname = ?", blocker, blocked).Delete(&models.UserBlock{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *postgresMessages) ListBlocked(blocker string) ([]models.UserBlock, error) {
+	blocks := make([]models.UserBlock, 0)
+	err := r.db.Where("blocker_nickname = ?", blocker).Order("created_at DESC").Find(&blocks).Error
+	return blocks, err
+}
+
+func (r *postgresMessages) RenameUser(oldNickname string, newNickname string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		updates := []struct {
+			model  interface{}
+			column string
+		}{
+			{&models.Conversation{}, "seller_nickname"},
+			{&models.Conversation{}, "buyer_nickname"},
+			{&models.Message{}, "sender_nickname"},
+			{&models.UserBlock{}, "blocker_nickname"},
+			{&models.UserBlock{}, "blocked_nickname"},
+		}
+
+		for _, u := range updates {
+			if err := tx.Model(u.model).Where(u.column+" = ?", oldNickname).Update(u.column, newNickname).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NotificationRepository - уведомления пользователей
+type NotificationRepository interface {
+	Create(notification *models.Notification) error
+	// List - уведомления пользователя, сверху новые, и сколько их всего
+	List(nickname string, unreadOnly bool, limit int, offset int) ([]models.Notification, int64, error)
+	CountUnread(nickname string) (int64, error)
+	// MarkRead помечает уведомление прочитанным, если оно еще не прочитано.
+	// ErrNotFound, если у пользователя такого уведомления нет
+	MarkRead(id uint, nickname string, at time.Time) error
+	// MarkAllRead возвращает, сколько уведомлений стало прочитанными
+	MarkAllRead(nickname string, at time.Time) (int64, error)
+	RenameUser(oldNickname string, newNickname string) error
+}
+
+type postgresNotifications struct {
+	db *gorm.DB
+}
+
+func (r *postgresNotifications) Create(notification *models.Notification) error {
+	return r.db.Create(notification).Error
+}
+
+func (r *postgresNotifications) List(nickname string, unreadOnly bool, limit int, offset int) ([]models.Notification, int64, error) {
+	notifications := make([]models.Notification, 0)
+
+	query := r.db.Model(&models.Notification{}).Where("user_nickname = ?", nickname)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
+
+func (r *postgresNotifications) CountUnread(nickname string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Notification{}).
+		Where("user_nickname = ? AND read_at IS NULL", nickname).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *postgresNotifications) MarkRead(id uint, nickname string, at time.Time) error {
+	var notification models.Notification
+	if err := r.db.Where("id = ? AND user_nickname = ?", id, nickname).First(&notification).Error; err != nil {
+		return notFound(err)
+	}
+
+	return r.db.Model(&notification).Where("read_at IS NULL").Update("read_at", at).Error
+}
+
+func (r *postgresNotifications) MarkAllRead(nickname string, at time.Time) (int64, error) {
+	result := r.db.Model(&models.Notification{}).
+		Where("user_nickname = ? AND read_at IS NULL", nickname).
+		Update("read_at", at)
+	return result.RowsAffected, result.Error
+}
+
+func (r *postgresNotifications) RenameUser(oldNickname string, newNickname string) error {
+	return r.db.Model(&models.Notification{}).
+		Where("user_nickname = ?", oldNickname).
+		Update("user_nickname", newNickname).Error
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxRepository - очередь писем email_outbox
+type OutboxRepository interface {
+	Enqueue(email *models.OutboxEmail) error
+	// ClaimDue забирает до limit pending писем, которым пора уходить, и сдвигает им
+	// next_attempt_at на lease. Пока письмо отправляется, другой воркер его не возьмет,
+	// а если процесс упадет посередине, письмо вернется в работу после lease
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEmail, error)
+	// MarkSent и MarkDead стирают тело письма: в нем коды подтверждения и ссылки отмены,
+	// которые в своих таблицах хранятся только хешами
+	MarkSent(id uint, at time.Time) error
+	// MarkFailed записывает неудачную попытку и время следующей
+	MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error
+	// MarkDead убирает письмо из очереди насовсем
+	MarkDead(id uint, attempts int, lastError string) error
+	// DeleteFinished удаляет отправленные и dead письма, поставленные в очередь раньше before
+	DeleteFinished(ctx context.Context, before time.Time) (int64, error)
+}
+
+type postgresOutbox struct {
+	db *gorm.DB
+}
+
+func (r *postgresOutbox) Enqueue(email *models.OutboxEmail) error {
+	return r.db.Create(email).Error
+}
+
+func (r *postgresOutbox) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEmail, error) {
+	var emails []models.OutboxEmail
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxPending, now).
+			Order("next_attempt_at, id").
+			Limit(limit).
+			Find(&emails).Error; err != nil {
+			return err
+		}
+		if len(emails) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(emails))
+		for i, email := range emails {
+			ids[i] = email.ID
+		}
+		return tx.Model(&models.OutboxEmail{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return emails, err
+}
+
+func (r *postgresOutbox) MarkSent(id uint, at time.Time) error {
+	return affected(r.db.Model(&models.OutboxEmail{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":   models.OutboxSent,
+		"attempts": gorm.Expr("attempts + 1"),
+		"sent_at":  at,
+		"html":     "",
+		"text":     "",
+	}))
+}
+
+func (r *postgresOutbox) MarkFailed(id uint, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return affected(r.db.Model(&models.OutboxEmail{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":        attempts,
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError,
+	}))
+}
+
+func (r *postgresOutbox) MarkDead(id uint, attempts int, lastError string) error {
+	return affected(r.db.Model(&models.OutboxEmail{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.OutboxDead,
+		"attempts":   attempts,
+		"last_error": lastError,
+		"html":       "",
+		"text":       "",
+	}))
+}
+
+func (r *postgresOutbox) DeleteFinished(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status IN ? AND created_at < ?", []string{models.OutboxSent, models.OutboxDead}, before).
+		Delete(&models.OutboxEmail{})
+	return result.RowsAffected, result.Error
+}
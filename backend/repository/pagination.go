@@ -0,0 +1,354 @@
+package repository
+
+import (
+	"cmp"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// PageRequest - какую страницу хочет клиент. Пустой Cursor означает первую страницу.
+// Offset оставлен для старых клиентов и учитывается только без курсора
+type PageRequest struct {
+	Limit  int
+	Cursor string
+	Offset int
+	Seed   string
+}
+
+// Page - что отдаем клиенту вместе со списком. Курсоры непрозрачные,
+// клиент просто передает их обратно в параметре cursor
+type Page struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Total      int64  `json:"total"`
+	Seed       string `json:"seed,omitempty"`
+
+	// Start и Count нужны только для заголовка Content-Range
+	Start int64 `json:"-"`
+	Count int   `json:"-"`
+}
+
+// cursor - то, что лежит внутри курсора. Ключ сортировки хранится строкой,
+// тип восстанавливается по keyset
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+	Prev  bool   `json:"p,omitempty"`
+	Seed  string `json:"r,omitempty"`
+}
+
+type keyKind int
+
+const (
+	keyTime keyKind = iota
+	keyInt
+	keyText
+)
+
+// keyset описывает сортировку для пагинации по ключу: (expr, id) строго монотонны,
+// поэтому новые записи не сдвигают уже открытые страницы
+type keyset struct {
+	name string
+	expr string
+	id   string
+	kind keyKind
+	desc bool
+	seed string
+}
+
+func (k keyset) direction(prev bool) string {
+	if k.desc != prev {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+func (k keyset) order(prev bool) string {
+	dir := k.direction(prev)
+	return fmt.Sprintf("%s %s, %s %s", k.expr, dir, k.id, dir)
+}
+
+// after - условие "строго после ключа" в выбранном направлении обхода
+func (k keyset) after(prev bool) string {
+	cmp := ">"
+	if k.direction(prev) == "DESC" {
+		cmp = "<"
+	}
+	return fmt.Sprintf("(%s, %s) %s (?, ?)", k.expr, k.id, cmp)
+}
+
+func (k keyset) encode(value interface{}, id uint, prev bool) string {
+	c := cursor{Sort: k.name, ID: id, Prev: prev, Seed: k.seed}
+	switch v := value.(type) {
+	case time.Time:
+		c.Value = v.UTC().Format(time.RFC3339Nano)
+	case int64:
+		c.Value = strconv.FormatInt(v, 10)
+	case string:
+		c.Value = v
+	}
+
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func (k keyset) decode(token string) (*cursor, interface{}, error) {
+	c, err := parseCursor(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.Sort != k.name || c.Seed != k.seed {
+		return nil, nil, ErrInvalidCursor
+	}
+
+	switch k.kind {
+	case keyTime:
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, nil, ErrInvalidCursor
+		}
+		return c, t, nil
+	case keyInt:
+		n, err := strconv.ParseInt(c.Value, 10, 64)
+		if err != nil {
+			return nil, nil, ErrInvalidCursor
+		}
+		return c, n, nil
+	default:
+		return c, c.Value, nil
+	}
+}
+
+func parseCursor(token string) (*cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// CursorSeed достает seed перемешивания из курсора, чтобы продолжить ленту в том же порядке
+func CursorSeed(token string) string {
+	if token == "" {
+		return ""
+	}
+	c, err := parseCursor(token)
+	if err != nil {
+		return ""
+	}
+	return c.Seed
+}
+
+// NewShuffleSeed генерирует seed для случайной ленты
+func NewShuffleSeed() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// IsShuffleSeed проверяет seed от клиента: только hex, не длиннее 32 символов
+func IsShuffleSeed(seed string) bool {
+	if seed == "" || len(seed) > 32 {
+		return false
+	}
+	_, err := hex.DecodeString(seed)
+	return err == nil
+}
+
+// shuffleKey считает тот же ключ, что и md5(seed || id) в PostgreSQL
+func shuffleKey(seed string, id uint) string {
+	sum := md5.Sum([]byte(seed + strconv.FormatUint(uint64(id), 10)))
+	return hex.EncodeToString(sum[:])
+}
+
+// paginate выбирает одну страницу по keyset. base - запрос с фильтрами без select и order,
+// columns - что выбирать, keyOf - ключ сортировки и id записи для курсоров
+func paginate[T any](base *gorm.DB, columns string, ks keyset, req PageRequest, keyOf func(*T) (interface{}, uint)) ([]T, *Page, error) {
+	base = base.Session(&gorm.Session{})
+	page := &Page{Seed: ks.seed}
+
+	if err := base.Count(&page.Total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	cur, curValue, err := ks.start(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	prev := cur != nil && cur.Prev
+
+	query := base.Select(columns).Order(ks.order(prev)).Limit(req.Limit + 1)
+	if cur != nil {
+		query = query.Where(ks.after(prev), curValue, cur.ID)
+	} else if req.Offset > 0 {
+		query = query.Offset(req.Offset)
+	}
+
+	items := make([]T, 0, req.Limit+1)
+	if err := query.Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+
+	items, first := finishPage(ks, page, items, cur, req, keyOf)
+	if first == nil || cur == nil {
+		return items, page, nil
+	}
+
+	// Start - сколько записей стоит перед первой в обычном порядке
+	if err := base.Where(ks.after(true), first.value, first.id).Count(&page.Start).Error; err != nil {
+		return nil, nil, err
+	}
+	return items, page, nil
+}
+
+// paginateSlice - то же, что paginate, для уже отфильтрованных записей в памяти
+func paginateSlice[T any](all []T, ks keyset, req PageRequest, keyOf func(*T) (interface{}, uint)) ([]T, *Page, error) {
+	page := &Page{Seed: ks.seed, Total: int64(len(all))}
+
+	cur, curValue, err := ks.start(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	prev := cur != nil && cur.Prev
+
+	// less - порядок обхода: обычный или обратный для страницы назад
+	less := func(a, b *T) bool {
+		av, aid := keyOf(a)
+		bv, bid := keyOf(b)
+		return ks.before(av, aid, bv, bid, prev)
+	}
+	ordered := append([]T(nil), all...)
+	sort.SliceStable(ordered, func(i, j int) bool { return less(&ordered[i], &ordered[j]) })
+
+	items := make([]T, 0, req.Limit+1)
+	skipped := 0
+	for i := range ordered {
+		if cur != nil {
+			value, id := keyOf(&ordered[i])
+			if !ks.before(curValue, cur.ID, value, id, prev) {
+				continue
+			}
+		} else if skipped < req.Offset {
+			skipped++
+			continue
+		}
+		if len(items) == req.Limit+1 {
+			break
+		}
+		items = append(items, ordered[i])
+	}
+
+	items, first := finishPage(ks, page, items, cur, req, keyOf)
+	if first == nil || cur == nil {
+		return items, page, nil
+	}
+
+	for i := range all {
+		value, id := keyOf(&all[i])
+		if ks.before(value, id, first.value, first.id, false) {
+			page.Start++
+		}
+	}
+	return items, page, nil
+}
+
+// start разбирает курсор запроса. Без курсора cur пустой
+func (k keyset) start(req PageRequest) (*cursor, interface{}, error) {
+	if req.Cursor == "" {
+		return nil, nil, nil
+	}
+	return k.decode(req.Cursor)
+}
+
+type pageKey struct {
+	value interface{}
+	id    uint
+}
+
+// finishPage - общая часть обеих реализаций. items - до req.Limit+1 записей в порядке обхода:
+// лишняя запись отрезается, страница назад разворачивается, ставятся курсоры.
+// Возвращает ключ первой записи, по нему считается Page.Start, если страница открыта по курсору
+func finishPage[T any](ks keyset, page *Page, items []T, cur *cursor, req PageRequest, keyOf func(*T) (interface{}, uint)) ([]T, *pageKey) {
+	prev := cur != nil && cur.Prev
+
+	hasMore := len(items) > req.Limit
+	if hasMore {
+		items = items[:req.Limit]
+	}
+	if prev {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	page.Count = len(items)
+	if len(items) == 0 {
+		page.Start = page.Total
+		return items, nil
+	}
+
+	firstValue, firstID := keyOf(&items[0])
+	lastValue, lastID := keyOf(&items[len(items)-1])
+
+	if (prev && hasMore) || (!prev && (cur != nil || req.Offset > 0)) {
+		page.PrevCursor = ks.encode(firstValue, firstID, true)
+	}
+	if prev || hasMore {
+		page.NextCursor = ks.encode(lastValue, lastID, false)
+	}
+	if cur == nil {
+		page.Start = int64(req.Offset)
+	}
+
+	return items, &pageKey{value: firstValue, id: firstID}
+}
+
+// before - идет ли ключ (a, aID) строго раньше (b, bID) в направлении обхода.
+// Для реализации в памяти, в SQL то же самое делает after
+func (k keyset) before(a interface{}, aID uint, b interface{}, bID uint, prev bool) bool {
+	order := compareKeys(a, b)
+	if order == 0 {
+		order = cmp.Compare(aID, bID)
+	}
+	if k.direction(prev) == "DESC" {
+		return order > 0
+	}
+	return order < 0
+}
+
+func compareKeys(a interface{}, b interface{}) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case int64:
+		return cmp.Compare(a, b.(int64))
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
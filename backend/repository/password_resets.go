@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PasswordResetRepository - запросы на сброс пароля, не больше одного на аккаунт
+type PasswordResetRepository interface {
+	// Find - ErrNotFound, если сброс не запрашивали
+	Find(accountID uint) (*models.PasswordReset, error)
+	// Replace удаляет прежний запрос аккаунта вместе со счетчиком попыток и сохраняет reset
+	Replace(reset *models.PasswordReset) error
+	// AddAttempt засчитывает попытку ввести код и возвращает, сколько их стало.
+	// ErrNotFound, если запрос уже удален
+	AddAttempt(id uint) (int, error)
+	// Delete - ErrNotFound, если запрос уже удален
+	Delete(id uint) error
+}
+
+type postgresPasswordResets struct {
+	db *gorm.DB
+}
+
+func (r *postgresPasswordResets) Find(accountID uint) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	if err := r.db.Where("account_id = ?", accountID).First(&reset).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &reset, nil
+}
+
+func (r *postgresPasswordResets) Replace(reset *models.PasswordReset) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", reset.AccountID).Delete(&models.PasswordReset{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(reset).Error
+	})
+}
+
+func (r *postgresPasswordResets) AddAttempt(id uint) (int, error) {
+	// Счетчик растет в самом UPDATE, так что параллельные попытки посчитаются все
+	reset := models.PasswordReset{ID: id}
+	err := affected(r.db.Model(&reset).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "attempts"}}}).
+		Update("attempts", gorm.Expr("attempts + 1")))
+	return reset.Attempts, err
+}
+
+func (r *postgresPasswordResets) Delete(id uint) error {
+	return affected(r.db.Where("id = ?", id).Delete(&models.PasswordReset{}))
+}
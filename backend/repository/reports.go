@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReportRepository - жалобы на объявления и решения модераторов по ним
+type ReportRepository interface {
+	Create(report *models.Report) error
+	FindByID(id uint) (*models.Report, error)
+	// ListByAd - все жалобы на объявление, сверху новые
+	ListByAd(adID uint) ([]models.Report, error)
+	// Queue - жалобы в статусе status, сгруппированные по объявлениям: сверху объявления
+	// с наибольшим количеством жалоб. Ad в строках не заполнен
+	Queue(status string, limit int, offset int) ([]models.ReportedAd, error)
+
+	// Decisions - журнал решений, сверху новые. Нулевой adID и пустой moderator - без фильтра
+	Decisions(adID uint, moderator string, limit int, offset int) ([]models.ModerationDecision, error)
+	// Dismiss отклоняет жалобу decision.ReportID и записывает решение.
+	// ErrNotFound, если жалобы нет или ее уже рассмотрели
+	Dismiss(decision *models.ModerationDecision, at time.Time) error
+	// Resolve закрывает все открытые жалобы на объявление decision.AdID, меняет колонки
+	// объявления adFields (nil - объявление не трогаем) и записывает решение.
+	// Возвращает закрытые жалобы
+	Resolve(decision *models.ModerationDecision, adFields map[string]interface{}, at time.Time) ([]models.Report, error)
+}
+
+type postgresReports struct {
+	db *gorm.DB
+}
+
+func (r *postgresReports) Create(report *models.Report) error {
+	return r.db.Create(report).Error
+}
+
+func (r *postgresReports) FindByID(id uint) (*models.Report, error) {
+	var report models.Report
+	if err := r.db.Where("id = ?", id).First(&report).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &report, nil
+}
+
+func (r *postgresReports) ListByAd(adID uint) ([]models.Report, error) {
+	var reports []models.Report
+	if err := r.db.Where("ad_id = ?", adID).Order("created_at DESC").Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (r *postgresReports) Queue(status string, limit int, offset int) ([]models.ReportedAd, error) {
+	var groups []struct {
+		AdID           uint
+		ReportCount    int64
+		LastReportedAt time.Time
+	}
+	if err := r.db.Table("reports").
+		Select("ad_id, COUNT(*) as report_count, MAX(created_at) as last_reported_at").
+		Where("status = ?", status).
+		Group("ad_id").
+		Order("report_count DESC, last_reported_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	queue := make([]models.ReportedAd, 0, len(groups))
+	if len(groups) == 0 {
+		return queue, nil
+	}
+
+	adIDs := make([]uint, 0, len(groups))
+	for _, group := range groups {
+		adIDs = append(adIDs, group.AdID)
+	}
+
+	var reasons []struct {
+		AdID   uint
+		Reason string
+		Count  int64
+	}
+	if err := r.db.Table("reports").
+		Select("ad_id, reason, COUNT(*) as count").
+		Where("status = ? AND ad_id IN ?", status, adIDs).
+		Group("ad_id, reason").
+		Order("count DESC").
+		Scan(&reasons).Error; err != nil {
+		return nil, err
+	}
+
+	reasonsByAd := make(map[uint][]models.ReportReasonCount)
+	for _, reason := range reasons {
+		reasonsByAd[reason.AdID] = append(reasonsByAd[reason.AdID], models.ReportReasonCount{Reason: reason.Reason, Count: reason.Count})
+	}
+	for _, group := range groups {
+		queue = append(queue, models.ReportedAd{
+			AdID:           group.AdID,
+			ReportCount:    group.ReportCount,
+			Reasons:        reasonsByAd[group.AdID],
+			LastReportedAt: group.LastReportedAt,
+		})
+	}
+	return queue, nil
+}
+
+func (r *postgresReports) Decisions(adID uint, moderator string, limit int, offset int) ([]models.ModerationDecision, error) {
+	var decisions []models.ModerationDecision
+
+	query := r.db.Model(&models.ModerationDecision{})
+	if adID != 0 {
+		query = query.Where("ad_id = ?", adID)
+	}
+	if moderator != "" {
+		query = query.Where("moderator_nickname = ?", moderator)
+	}
+
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&decisions).Error
+	return decisions, err
+}
+
+func (r *postgresReports) Dismiss(decision *models.ModerationDecision, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Статус проверяется в самом UPDATE: два модератора не закроют одну жалобу дважды
+		if err := affected(tx.Model(&models.Report{}).
+			Where("id = ? AND status = ?", *decision.ReportID, models.ReportStatusPending).
+			Updates(map[string]interface{}{
+				"status":      models.ReportStatusDismissed,
+				"resolved_by": decision.ModeratorNickname,
+				"resolved_at": &at,
+			})); err != nil {
+			return err
+		}
+		return tx.Create(decision).Error
+	})
+}
+
+func (r *postgresReports) Resolve(decision *models.ModerationDecision, adFields map[string]interface{}, at time.Time) ([]models.Report, error) {
+	var resolved []models.Report
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&resolved).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}, {Name: "reporter_nickname"}}}).
+			Where("ad_id = ? AND status = ?", decision.AdID, models.ReportStatusPending).
+			Updates(map[string]interface{}{
+				"status":      models.ReportStatusResolved,
+				"resolved_by": decision.ModeratorNickname,
+				"resolved_at": &at,
+			}).Error; err != nil {
+			return err
+		}
+
+		if adFields != nil {
+			if err := tx.Model(&models.Ad{}).Where("id = ?", decision.AdID).Updates(adFields).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(decision).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
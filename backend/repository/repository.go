@@ -0,0 +1,72 @@
+// Package repository - доступ к данным за интерфейсами. Сервисы получают репозитории
+// через конструкторы и не знают, что лежит внутри: PostgreSQL через gorm (NewPostgres)
+// или обычные map в памяти (NewMemory), на которых гоняются тесты без базы.
+//
+// Ленты с курсорной пагинацией (pagination.go) и поиск тоже здесь: в PostgreSQL это keyset
+// запросы и tsvector, в памяти - сортировка среза по тому же ключу и поиск подстроки
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound - записи нет. Обе реализации возвращают именно его, а не gorm.ErrRecordNotFound
+var ErrNotFound = errors.New("record not found")
+
+// Repositories - набор всех репозиториев одной реализации
+type Repositories struct {
+	Accounts AccountRepository
+	Ads      AdRepository
+	Feedback FeedbackRepository
+	Reports  ReportRepository
+	Tokens   TokenRepository
+
+	Notifications  NotificationRepository
+	Outbox         OutboxRepository
+	Reputation     ReputationRepository
+	TwoFactor      TwoFactorRepository
+	PasswordResets PasswordResetRepository
+	EmailChanges   EmailChangeRepository
+	Favorites      FavoriteRepository
+	Messages       MessageRepository
+}
+
+// NewPostgres собирает репозитории поверх открытого соединения gorm
+func NewPostgres(db *gorm.DB) Repositories {
+	return Repositories{
+		Accounts: &postgresAccounts{db: db},
+		Ads:      &postgresAds{db: db},
+		Feedback: &postgresFeedback{db: db},
+		Reports:  &postgresReports{db: db},
+		Tokens:   &postgresTokens{db: db},
+
+		Notifications:  &postgresNotifications{db: db},
+		Outbox:         &postgresOutbox{db: db},
+		Reputation:     &postgresReputation{db: db},
+		TwoFactor:      &postgresTwoFactor{db: db},
+		PasswordResets: &postgresPasswordResets{db: db},
+		EmailChanges:   &postgresEmailChanges{db: db},
+		Favorites:      &postgresFavorites{db: db},
+		Messages:       &postgresMessages{db: db},
+	}
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// affected превращает "ничего не обновили" в ErrNotFound
+func affected(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReputationApply дописывает отзывы в репутацию продавца. Вызывается внутри транзакции,
+// запись уже заблокирована. Если apply вернул ошибку, не сохраняется ничего
+type ReputationApply func(rep *models.SellerReputation, reviews []models.ReputationReview) error
+
+// ReputationRepository - накопленная репутация продавцов (seller_reputation).
+// Каждое изменение записи в той же транзакции копируется в accounts.rating и accounts.success_transactions
+type ReputationRepository interface {
+	// Find - ErrNotFound, если подтвержденных отзывов о продавце еще не было
+	Find(sellerID uint) (*models.SellerReputation, error)
+	// Reviews - подтвержденные отзывы о продавце, подтвержденные не раньше since
+	Reviews(sellerID uint, since time.Time) ([]models.ReputationReview, error)
+	// ConfirmFeedback подтверждает отзыв и отдает его в apply. Если записи репутации еще не было
+	// (продавец с отзывами из времен до нее), в apply приходят все его подтвержденные отзывы.
+	// ErrNotFound, если отзыва нет или он уже подтвержден
+	ConfirmFeedback(feedbackID uint, sellerID uint, at time.Time, apply ReputationApply) error
+	// Rebuild отдает в apply все подтвержденные отзывы продавца, чтобы собрать запись заново
+	Rebuild(sellerID uint, apply ReputationApply) error
+	// Update меняет существующую запись без новых отзывов. ErrNotFound, если записи нет
+	Update(sellerID uint, apply func(rep *models.SellerReputation) error) error
+	// Sellers - ID продавцов с записью репутации больше afterID, по возрастанию, не больше limit
+	Sellers(afterID uint, limit int) ([]uint, error)
+	// Unbuilt - продавцы с подтвержденными отзывами, у которых записи репутации еще нет
+	Unbuilt(limit int) ([]uint, error)
+}
+
+type postgresReputation struct {
+	db *gorm.DB
+}
+
+func (r *postgresReputation) Find(sellerID uint) (*models.SellerReputation, error) {
+	var rep models.SellerReputation
+	if err := r.db.Where("account_id = ?", sellerID).First(&rep).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &rep, nil
+}
+
+// reviews - выборка отзывов о продавце с рейтингом и сделками их авторов
+func (r *postgresReputation) reviews(db *gorm.DB, sellerID uint) *gorm.DB {
+	return db.Table("feedback_ads").
+		Select("feedback_ads.id AS feedback_id, feedback_ads.rating, feedback_ads.created_at, "+
+			"COALESCE(feedback_ads.confirmed_at, feedback_ads.created_at) AS confirmed_at, "+
+			"COALESCE(reviewers.rating, 0) AS reviewer_rating, COALESCE(reviewers.success_transactions, 0) AS reviewer_deals").
+		Joins("JOIN accounts sellers ON sellers.nickname = feedback_ads.ad_owner_nickname").
+		Joins("LEFT JOIN accounts reviewers ON reviewers.nickname = feedback_ads.reviewer_nickname").
+		Where("sellers.id = ? AND feedback_ads.confirm_feedback = ?", sellerID, true).
+		Order("feedback_ads.created_at, feedback_ads.id")
+}
+
+func (r *postgresReputation) Reviews(sellerID uint, since time.Time) ([]models.ReputationReview, error) {
+	var reviews []models.ReputationReview
+	err := r.reviews(r.db, sellerID).
+		Where("COALESCE(feedback_ads.confirmed_at, feedback_ads.created_at) >= ?", since).
+		Scan(&reviews).Error
+	return reviews, err
+}
+
+// update - общая часть всех изменений: блокирует запись продавца (создает пустую, если ее нет),
+// load выбирает отзывы для apply, а результат сохраняется вместе с колонками accounts
+func (r *postgresReputation) update(db *gorm.DB, sellerID uint, load func(tx *gorm.DB, created bool) ([]models.ReputationReview, error), apply ReputationApply) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		seed := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.SellerReputation{AccountID: sellerID})
+		if seed.Error != nil {
+			return seed.Error
+		}
+
+		var rep models.SellerReputation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("account_id = ?", sellerID).First(&rep).Error; err != nil {
+			return err
+		}
+
+		reviews, err := load(tx, seed.RowsAffected > 0)
+		if err != nil {
+			return err
+		}
+		if err := apply(&rep, reviews); err != nil {
+			return err
+		}
+		return r.save(tx, &rep)
+	})
+}
+
+func (r *postgresReputation) save(tx *gorm.DB, rep *models.SellerReputation) error {
+	if err := tx.Save(rep).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Account{}).Where("id = ?", rep.AccountID).Updates(map[string]interface{}{
+		"rating":               float32(rep.Rating),
+		"success_transactions": rep.Count(),
+	}).Error
+}
+
+func (r *postgresReputation) ConfirmFeedback(feedbackID uint, sellerID uint, at time.Time, apply ReputationApply) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Условие на confirm_feedback не дает посчитать один отзыв дважды при параллельных запросах
+		err := affected(tx.Model(&models.FeedbackAd{}).
+			Where("id = ? AND confirm_feedback = ?", feedbackID, false).
+			Updates(map[string]interface{}{"confirm_feedback": true, "confirmed_at": at}))
+		if err != nil {
+			return err
+		}
+
+		return r.update(tx, sellerID, func(tx *gorm.DB, created bool) ([]models.ReputationReview, error) {
+			query := r.reviews(tx, sellerID)
+			if !created {
+				query = query.Where("feedback_ads.id = ?", feedbackID)
+			}
+			var reviews []models.ReputationReview
+			err := query.Scan(&reviews).Error
+			return reviews, err
+		}, apply)
+	})
+}
+
+func (r *postgresReputation) Rebuild(sellerID uint, apply ReputationApply) error {
+	return r.update(r.db, sellerID, func(tx *gorm.DB, created bool) ([]models.ReputationReview, error) {
+		var reviews []models.ReputationReview
+		err := r.reviews(tx, sellerID).Scan(&reviews).Error
+		return reviews, err
+	}, apply)
+}
+
+func (r *postgresReputation) Update(sellerID uint, apply func(rep *models.SellerReputation) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var rep models.SellerReputation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("account_id = ?", sellerID).First(&rep).Error; err != nil {
+			return notFound(err)
+		}
+		if err := apply(&rep); err != nil {
+			return err
+		}
+		return r.save(tx, &rep)
+	})
+}
+
+func (r *postgresReputation) Sellers(afterID uint, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.SellerReputation{}).
+		Where("account_id > ?", afterID).
+		Order("account_id").
+		Limit(limit).
+		Pluck("account_id", &ids).Error
+	return ids, err
+}
+
+func (r *postgresReputation) Unbuilt(limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Table("accounts").
+		Distinct("accounts.id").
+		Joins("JOIN feedback_ads ON feedback_ads.ad_owner_nickname = accounts.nickname AND feedback_ads.confirm_feedback = ?", true).
+		Where("NOT EXISTS (SELECT 1 FROM seller_reputation WHERE seller_reputation.account_id = accounts.id)").
+		Order("accounts.id").
+		Limit(limit).
+		Pluck("accounts.id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RotateFunc решает, что делать с предъявленным refresh токеном. Вернет токен -
+// он добавится, а предъявленный будет помечен замененным. Вернет nil - ничего не меняем.
+// Ошибка откатывает ротацию и возвращается из Rotate как есть
+type RotateFunc func(stored *models.RefreshToken) (*models.RefreshToken, error)
+
+// TokenRepository - refresh токены и сессии, которые из них складываются
+type TokenRepository interface {
+	Create(token *models.RefreshToken) error
+	FindByToken(token string) (*models.RefreshToken, error)
+	// Rotate блокирует неистекший токен аккаунта и отдает его в next.
+	// ErrNotFound, если такого токена нет или он истек
+	Rotate(token string, accountID uint, now time.Time, next RotateFunc) error
+	// ListActive - незамененные и неистекшие токены, сверху последние использованные
+	ListActive(accountID uint, now time.Time) ([]models.RefreshToken, error)
+	// DeleteFamily удаляет все токены сессии, возвращает сколько удалено
+	DeleteFamily(accountID uint, familyID string) (int64, error)
+	// DeleteAllExcept удаляет все токены аккаунта, кроме семьи exceptFamily (пустая - без исключений).
+	// Возвращает, сколько среди них было активных сессий
+	DeleteAllExcept(accountID uint, exceptFamily string, now time.Time) (int64, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type postgresTokens struct {
+	db *gorm.DB
+}
+
+func (r *postgresTokens) Create(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *postgresTokens) FindByToken(token string) (*models.RefreshToken, error) {
+	var stored models.RefreshToken
+	if err := r.db.Where("token = ?", token).First(&stored).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &stored, nil
+}
+
+func (r *postgresTokens) Rotate(token string, accountID uint, now time.Time, next RotateFunc) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var stored models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token = ? AND account_id = ? AND expires_at > ?", token, accountID, now).
+			First(&stored).Error; err != nil {
+			return notFound(err)
+		}
+
+		replacement, err := next(&stored)
+		if err != nil || replacement == nil {
+			return err
+		}
+
+		if err := tx.Model(&stored).Update("rotated_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(replacement).Error
+	})
+}
+
+func (r *postgresTokens) ListActive(accountID uint, now time.Time) ([]models.RefreshToken, error) {
+	var tokens []models.RefreshToken
+	err := r.db.Where("account_id = ? AND rotated_at IS NULL AND expires_at > ?", accountID, now).
+		Order("last_used_at DESC, id DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *postgresTokens) DeleteFamily(accountID uint, familyID string) (int64, error) {
+	result := r.db.Where("account_id = ? AND family_id = ?", accountID, familyID).Delete(&models.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *postgresTokens) DeleteAllExcept(accountID uint, exceptFamily string, now time.Time) (int64, error) {
+	var sessions int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		scope := func(db *gorm.DB) *gorm.DB {
+			db = db.Where("account_id = ?", accountID)
+			if exceptFamily != "" {
+				db = db.Where("family_id <> ?", exceptFamily)
+			}
+			return db
+		}
+
+		if err := tx.Model(&models.RefreshToken{}).Scopes(scope).
+			Where("rotated_at IS NULL AND expires_at > ?", now).
+			Count(&sessions).Error; err != nil {
+			return err
+		}
+		return tx.Scopes(scope).Delete(&models.RefreshToken{}).Error
+	})
+	return sessions, err
+}
+
+func (r *postgresTokens) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"arizonagamesstore/backend/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TwoFactorRepository - настройки 2FA аккаунтов и их резервные коды
+type TwoFactorRepository interface {
+	// Find - ErrNotFound, если 2FA не включали и не начинали подключать
+	Find(accountID uint) (*models.TwoFactor, error)
+	Enabled(accountID uint) (bool, error)
+	// CountBackupCodes - сколько резервных кодов еще не использовано
+	CountBackupCodes(accountID uint) (int64, error)
+	// SaveSecret начинает подключение или заменяет секрет неподтвержденного.
+	// ErrNotFound, если 2FA уже включена
+	SaveSecret(accountID uint, secret string) error
+	// Enable включает 2FA с этим секретом и сохраняет резервные коды.
+	// ErrNotFound, если 2FA уже включена или секрет успели заменить
+	Enable(accountID uint, secret string, step int64, at time.Time, codes []models.TwoFactorBackupCode) error
+	// UseStep запоминает окно TOTP, по которому вошли. ErrNotFound, если 2FA выключена
+	// или окно не новее уже использованного
+	UseStep(accountID uint, step int64) error
+	// UnusedBackupCodes - резервные коды, по которым еще не входили
+	UnusedBackupCodes(accountID uint) ([]models.TwoFactorBackupCode, error)
+	// UseBackupCode гасит резервный код. ErrNotFound, если его уже использовали
+	UseBackupCode(id uint, at time.Time) error
+	// ReplaceBackupCodes удаляет прежние резервные коды аккаунта и сохраняет codes
+	ReplaceBackupCodes(accountID uint, codes []models.TwoFactorBackupCode) error
+	// Delete выключает 2FA: удаляет настройку вместе с резервными кодами
+	Delete(accountID uint) error
+
+	// ReserveAttempt засчитывает попытку ввести код по challenge.JTI и возвращает, сколько их стало.
+	// Заодно удаляет истекшие к now счетчики этого аккаунта
+	ReserveAttempt(challenge models.TwoFactorChallenge, now time.Time) (int, error)
+	// DeleteChallenge сбрасывает счетчик попыток challenge токена
+	DeleteChallenge(jti string) error
+}
+
+type postgresTwoFactor struct {
+	db *gorm.DB
+}
+
+func (r *postgresTwoFactor) Find(accountID uint) (*models.TwoFactor, error) {
+	var tf models.TwoFactor
+	if err := r.db.Where("account_id = ?", accountID).First(&tf).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &tf, nil
+}
+
+func (r *postgresTwoFactor) Enabled(accountID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.TwoFactor{}).
+		Where("account_id = ? AND enabled = ?", accountID, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *postgresTwoFactor) CountBackupCodes(accountID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.TwoFactorBackupCode{}).
+		Where("account_id = ? AND used_at IS NULL", accountID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *postgresTwoFactor) SaveSecret(accountID uint, secret string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.TwoFactor
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("account_id = ?", accountID).
+			First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(&models.TwoFactor{AccountID: accountID, Secret: secret}).Error
+		}
+		if err != nil {
+			return err
+		}
+		return affected(tx.Model(&existing).Where("enabled = ?", false).Update("secret", secret))
+	})
+}
+
+func (r *postgresTwoFactor) Enable(accountID uint, secret string, step int64, at time.Time, codes []models.TwoFactorBackupCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := affected(tx.Model(&models.TwoFactor{}).
+			Where("account_id = ? AND secret = ? AND enabled = ?", accountID, secret, false).
+			Updates(map[string]interface{}{
+				"enabled":        true,
+				"enabled_at":     &at,
+				"last_used_step": step,
+			})); err != nil {
+			return err
+		}
+		return replaceBackupCodes(tx, accountID, codes)
+	})
+}
+
+func (r *postgresTwoFactor) UseStep(accountID uint, step int64) error {
+	// Окно сравнивается в самом UPDATE, так что один код не пройдет дважды и при параллельных входах
+	return affected(r.db.Model(&models.TwoFactor{}).
+		Where("account_id = ? AND enabled = ? AND last_used_step < ?", accountID, true, step).
+		Update("last_used_step", step))
+}
+
+func (r *postgresTwoFactor) UnusedBackupCodes(accountID uint) ([]models.TwoFactorBackupCode, error) {
+	var codes []models.TwoFactorBackupCode
+	err := r.db.Where("account_id = ? AND used_at IS NULL", accountID).Find(&codes).Error
+	return codes, err
+}
+
+func (r *postgresTwoFactor) UseBackupCode(id uint, at time.Time) error {
+	return affected(r.db.Model(&models.TwoFactorBackupCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at))
+}
+
+func (r *postgresTwoFactor) ReplaceBackupCodes(accountID uint, codes []models.TwoFactorBackupCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceBackupCodes(tx, accountID, codes)
+	})
+}
+
+func replaceBackupCodes(tx *gorm.DB, accountID uint, codes []models.TwoFactorBackupCode) error {
+	if err := tx.Where("account_id = ?", accountID).Delete(&models.TwoFactorBackupCode{}).Error; err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
+
+func (r *postgresTwoFactor) Delete(accountID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", accountID).Delete(&models.TwoFactorBackupCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("account_id = ?", accountID).Delete(&models.TwoFactor{}).Error
+	})
+}
+
+func (r *postgresTwoFactor) ReserveAttempt(challenge models.TwoFactorChallenge, now time.Time) (int, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ? AND expires_at <= ?", challenge.AccountID, now).
+			Delete(&models.TwoFactorChallenge{}).Error; err != nil {
+			return err
+		}
+
+		// Счетчик растет в самой базе одним запросом, так что параллельные попытки по одному токену считаются все
+		challenge.Attempts = 1
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "jti"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"attempts": gorm.Expr("two_factor_challenges.attempts + 1")}),
+		}, clause.Returning{Columns: []clause.Column{{Name: "attempts"}}}).Create(&challenge).Error
+	})
+	return challenge.Attempts, err
+}
+
+func (r *postgresTwoFactor) DeleteChallenge(jti string) error {
+	return r.db.Where("jti = ?", jti).Delete(&models.TwoFactorChallenge{}).Error
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"arizonagamesstore/backend/services"
+	"context"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type reputationResponse struct {
+	Rating              float64 `json:"rating"`
+	SuccessTransactions int     `json:"success_transactions"`
+	AllTime             struct {
+		Count   int            `json:"count"`
+		Average float64        `json:"average"`
+		Stars   map[string]int `json:"stars"`
+	} `json:"all_time"`
+	Last30Days struct {
+		Count int `json:"count"`
+	} `json:"last_30_days"`
+}
+
+func (s *testServer) reputation(t *testing.T, nickname string) reputationResponse {
+	t.Helper()
+	var rep reputationResponse
+	decode(t, s.newClient(t).do(http.MethodGet, "/api/feedback/"+nickname+"/reputation", nil, ""), &rep)
+	return rep
+}
+
+func TestReputationBreakdown(t *testing.T) {
+	srv := newTestServer(t)
+	seller := srv.signUp(t, "Trader")
+	fan := srv.signUp(t, "Fan")
+	critic := srv.signUp(t, "Critic")
+	adID := seller.createAd("Особняк на Рублевке")
+
+	for _, id := range []uint{fan.review(adID, 5), critic.review(adID, 1)} {
+		expectStatus(t, seller.confirmFeedback(id), http.StatusOK)
+	}
+
+	rep := srv.reputation(t, "Trader")
+	if rep.SuccessTransactions != 2 || rep.AllTime.Average != 3 ||
+		rep.AllTime.Stars["5"] != 1 || rep.AllTime.Stars["1"] != 1 || rep.AllTime.Stars["3"] != 0 {
+		t.Fatalf("breakdown = %+v, want one 5, one 1 and 2 deals", rep)
+	}
+
+	// Оба автора - новички с весом 0.5: (3*4 + 0.5*5 + 0.5*1) / (3 + 0.5 + 0.5)
+	tests := []struct {
+		name       string
+		after      time.Duration
+		rating     float64
+		recentDeal int
+	}{
+		{"right after confirmation", 0, 3.75, 2},
+		{"after a month", 31 * 24 * time.Hour, 3.77, 0},
+		// Через период полураспада отзывы весят вдвое меньше: (12 + 1.5) / 3.5
+		{"after half-life", 180 * 24 * time.Hour, 3.86, 0},
+	}
+
+	start := srv.clock.Now()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv.clock.Advance(start.Add(tt.after).Sub(srv.clock.Now()))
+
+			rep := srv.reputation(t, "Trader")
+			if rep.Rating != tt.rating || rep.Last30Days.Count != tt.recentDeal {
+				t.Fatalf("rating %v with %d recent reviews, want %v with %d", rep.Rating, rep.Last30Days.Count, tt.rating, tt.recentDeal)
+			}
+		})
+	}
+
+	// В профиле рейтинг на момент последнего пересчета, задача refresh-reputation сдвигает его на сегодня
+	if err := services.Reputation.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var me struct {
+		Rating       float64 `json:"rating"`
+		ReviewsCount int     `json:"reviews_count"`
+	}
+	decode(t, seller.do(http.MethodGet, "/api/me", nil, ""), &me)
+	if math.Abs(me.Rating-3.86) > 0.001 || me.ReviewsCount != 2 {
+		t.Fatalf("profile rating %v from %d reviews, want 3.86 from 2", me.Rating, me.ReviewsCount)
+	}
+}
+
+func TestReviewerReputationWeighsIn(t *testing.T) {
+	srv := newTestServer(t)
+	veteran := srv.signUp(t, "Veteran")
+	newbie := srv.signUp(t, "Newbie")
+	first := srv.signUp(t, "FirstSeller")
+	second := srv.signUp(t, "SecondSeller")
+
+	// У ветерана одна подтвержденная сделка с пятеркой, у новичка ни одной
+	veteranAd := veteran.createAd("Лавка ветерана")
+	expectStatus(t, veteran.confirmFeedback(newbie.review(veteranAd, 5)), http.StatusOK)
+
+	firstAd := first.createAd("Первый дом")
+	secondAd := second.createAd("Второй дом")
+	for seller, id := range map[*client]uint{first: newbie.review(firstAd, 1), second: veteran.review(secondAd, 1)} {
+		expectStatus(t, seller.confirmFeedback(id), http.StatusOK)
+	}
+
+	fromNewbie := srv.reputation(t, "FirstSeller").Rating
+	fromVeteran := srv.reputation(t, "SecondSeller").Rating
+	if fromVeteran >= fromNewbie {
+		t.Fatalf("one star from veteran gave %v, from newbie %v; veteran's review should weigh more", fromVeteran, fromNewbie)
+	}
+}
+
+func TestConfirmFeedbackTwice(t *testing.T) {
+	srv := newTestServer(t)
+	seller := srv.signUp(t, "Twice")
+	buyer := srv.signUp(t, "Buyer")
+	id := buyer.review(seller.createAd("Гараж"), 4)
+
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"first confirmation", http.StatusOK},
+		{"second confirmation", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, seller.confirmFeedback(id), tt.want)
+		})
+	}
+
+	if rep := srv.reputation(t, "Twice"); rep.SuccessTransactions != 1 {
+		t.Fatalf("success transactions = %d, want 1", rep.SuccessTransactions)
+	}
+}
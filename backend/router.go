@@ -0,0 +1,207 @@
+package main
+
+import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/config"
+	"arizonagamesstore/backend/handlers"
+	"arizonagamesstore/backend/mail"
+	"arizonagamesstore/backend/metrics"
+	"arizonagamesstore/backend/middleware"
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"arizonagamesstore/backend/scheduler"
+	"arizonagamesstore/backend/services"
+	"arizonagamesstore/backend/storage"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+)
+
+// Deps - все, что сервер берет снаружи. main собирает настоящие зависимости,
+// тесты подставляют репозитории в памяти, временную папку и часы на месте
+type Deps struct {
+	Repos repository.Repositories
+	Files storage.Backend
+	// Mail доставляет письма из очереди email_outbox
+	Mail  mail.Sender
+	Clock clock.Clock
+	// Jobs нужен только для /api/admin/jobs, задачи в нем регистрирует main
+	Jobs *scheduler.Scheduler
+}
+
+// NewRouter подключает зависимости к сервисам и собирает все маршруты API.
+// Конфиг уже должен быть передан в utils.Init и services.Init
+func NewRouter(cfg *config.Config, deps Deps) *gin.Engine {
+	services.Setup(deps.Repos, deps.Clock, deps.Mail)
+	storage.Files = deps.Files
+
+	// Вместо gin.Default: свой логгер запросов и recovery, чтобы все писалось через slog с request_id
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.RequestLogger(), middleware.Recovery(), metrics.Middleware())
+
+	router.Use(func(c *gin.Context) {
+		if c.Request.Method == "OPTIONS" {
+			c.Header("Access-Control-Allow-Origin", c.GetHeader("Origin"))
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
+			c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Total-Count, Range, Content-Range, Accept")
+			c.Header("Access-Control-Allow-Credentials", "true")
+			c.Header("Access-Control-Max-Age", "43200")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	})
+
+	corsConfig := cors.Config{
+		AllowOrigins:     cfg.Server.CORSOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Total-Count", "Range", "Content-Range", "Accept"},
+		ExposeHeaders:    []string{"X-Total-Count", "Content-Range", "X-Request-ID"},
+		AllowCredentials: true,
+		AllowWildcard:    false,
+		MaxAge:           12 * 3600,
+	}
+
+	router.Use(cors.New(corsConfig))
+	router.Use(middleware.RequestTimeout(30*time.Second, "/api/events"))
+
+	// Локальное хранилище раздаем как статику. Старые файлы из ./uploads
+	// тоже остаются доступны, даже если сейчас настроен S3
+	uploadsDir := "./uploads"
+	if local, ok := storage.Files.(*storage.Local); ok {
+		uploadsDir = local.Dir()
+	}
+	router.Static("/uploads", uploadsDir)
+
+	router.GET("/healthz", handlers.Healthz)
+	router.GET("/readyz", handlers.Readyz)
+	router.GET("/metrics", metrics.Handler(cfg.Server.MetricsToken))
+
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	router.POST("/api/register", middleware.RateLimitRegister(), services.RegisterAccount)
+	router.POST("/api/login", middleware.RateLimitLogin(), services.Login)
+	router.POST("/api/login/2fa", middleware.RateLimitLogin(), services.LoginTwoFactor)
+	router.POST("/api/refresh", services.RefreshAccessToken)
+	router.POST("/api/logout", services.Logout)
+
+	router.POST("/api/verify-email", middleware.RateLimitVerify(), services.VerifyEmail)
+	router.POST("/api/resend-code", middleware.RateLimitVerify(), services.ResendVerificationCode)
+	router.POST("/api/password-reset/request", middleware.RateLimitVerify(), services.RequestPasswordReset)
+	router.POST("/api/password-reset/confirm", middleware.RateLimitVerify(), services.ConfirmPasswordReset)
+	router.GET("/api/email-change/undo", middleware.RateLimitVerify(), handlers.UndoEmailChange)
+
+	router.POST("/api/createnewads", handlers.CreateNewAds)
+	router.GET("/api/ads", handlers.GetAdsByCategory)
+	router.GET("/api/ads/random", handlers.GetRandomAds)
+	router.GET("/api/ads/my", middleware.AuthRequired(), handlers.GetMyAds)
+	router.GET("/api/ads/search", handlers.SearchAds)
+	router.GET("/api/listings/user/:nickname", handlers.GetAdsByNickname)
+	router.GET("/api/getadcount", handlers.GetAdCount)
+	router.POST("/api/ads/:id/view", handlers.IncrementAdViews)
+	router.PUT("/api/ads/:id", middleware.AuthRequired(), handlers.UpdateAd)
+	router.DELETE("/api/ads/:id", middleware.AuthRequired(), handlers.DeleteAd)
+	router.POST("/api/ads/:id/renew", middleware.AuthRequired(), handlers.RenewAd)
+	router.POST("/api/ads/:id/sold", middleware.AuthRequired(), handlers.MarkAdSold)
+	router.POST("/api/reports", middleware.AuthRequired(), handlers.CreateReport)
+
+	router.POST("/api/feedback", middleware.AuthRequired(), handlers.CreateFeedback)
+	router.GET("/api/feedback/:nickname", handlers.GetFeedbacksByOwner)
+	router.GET("/api/feedback/:nickname/reputation", handlers.GetSellerReputation)
+	router.PUT("/api/feedback/:id/confirm", middleware.AuthRequired(), handlers.ConfirmFeedback)
+
+	admin := router.Group("/api/admin", middleware.AuthRequired(), middleware.RequireRole(models.RoleModerator, models.RoleAdmin))
+	admin.GET("/reports", middleware.RequirePermission(models.PermissionViewReports), handlers.GetReportQueue)
+	admin.POST("/reports/:id/dismiss", middleware.RequirePermission(models.PermissionResolveReports), handlers.DismissReport)
+	admin.GET("/ads/:id/reports", middleware.RequirePermission(models.PermissionViewReports), handlers.GetAdReports)
+	admin.POST("/ads/:id/hide", middleware.RequirePermission(models.PermissionResolveReports, models.PermissionModerateAds), handlers.HideReportedAd)
+	admin.POST("/ads/:id/warn", middleware.RequirePermission(models.PermissionResolveReports, models.PermissionWarnUsers), handlers.WarnAdAuthor)
+	admin.DELETE("/ads/:id", middleware.RequirePermission(models.PermissionResolveReports, models.PermissionModerateAds), handlers.DeleteReportedAd)
+	admin.GET("/reports/:id/conversations", middleware.RequirePermission(models.PermissionViewReports, models.PermissionReadMessages), handlers.GetReportConversations)
+	admin.GET("/decisions", middleware.RequirePermission(models.PermissionViewModLog), handlers.GetModerationDecisions)
+	admin.PUT("/users/:nickname/role", middleware.RequirePermission(models.PermissionManageRoles), handlers.UpdateUserRole)
+	admin.GET("/jobs", middleware.RequireRole(models.RoleAdmin), handlers.GetJobsStatus(deps.Jobs))
+
+	router.POST("/api/ads/:id/conversations", middleware.AuthRequired(), middleware.RateLimitMessages(), handlers.StartConversation)
+	router.GET("/api/conversations", middleware.AuthRequired(), handlers.GetConversations)
+	router.GET("/api/conversations/:id/messages", middleware.AuthRequired(), handlers.GetConversationMessages)
+	router.POST("/api/conversations/:id/messages", middleware.AuthRequired(), middleware.RateLimitMessages(), handlers.SendMessage)
+	router.POST("/api/conversations/:id/read", middleware.AuthRequired(), handlers.MarkConversationRead)
+	router.GET("/api/blocks", middleware.AuthRequired(), handlers.GetBlockedUsers)
+	router.POST("/api/blocks/:nickname", middleware.AuthRequired(), handlers.BlockUser)
+	router.DELETE("/api/blocks/:nickname", middleware.AuthRequired(), handlers.UnblockUser)
+
+	router.POST("/api/favorites", middleware.AuthRequired(), handlers.AddFavoriteAd)
+	router.GET("/api/favorites", middleware.AuthRequired(), handlers.GetFavoriteAds)
+	router.DELETE("/api/favorites/:ad_id", middleware.AuthRequired(), handlers.RemoveFavoriteAd)
+
+	router.POST("/api/viewed-ads", middleware.AuthRequired(), handlers.AddViewedAd)
+	router.GET("/api/viewed-ads", middleware.AuthRequired(), handlers.GetViewedAds)
+
+	router.POST("/api/profile/update-background", middleware.AuthRequired(), handlers.UpdateProfileBackground)
+	router.DELETE("/api/profile/delete-background", middleware.AuthRequired(), handlers.DeleteProfileBackground)
+
+	router.POST("/api/profile/update-avatar", middleware.AuthRequired(), handlers.UpdateProfileAvatar)
+	router.PUT("/api/profile/update-nickname", middleware.AuthRequired(), handlers.UpdateNickname)
+	router.PUT("/api/profile/update-email", middleware.AuthRequired(), handlers.UpdateEmail)
+	router.POST("/api/profile/confirm-email", middleware.AuthRequired(), middleware.RateLimitVerify(), handlers.ConfirmEmailChange)
+	router.PUT("/api/profile/update-password", middleware.AuthRequired(), handlers.UpdatePassword)
+	router.PUT("/api/profile/update-theme", middleware.AuthRequired(), handlers.UpdateTheme)
+	router.PUT("/api/profile/update-description", middleware.AuthRequired(), handlers.UpdateDescription)
+	router.PUT("/api/profile/update-telegram", middleware.AuthRequired(), handlers.UpdateTelegram)
+
+	router.GET("/api/events", middleware.AuthRequired(), handlers.StreamEvents)
+
+	router.GET("/api/notifications", middleware.AuthRequired(), handlers.GetNotifications)
+	router.GET("/api/notifications/unread-count", middleware.AuthRequired(), handlers.GetUnreadNotificationsCount)
+	router.POST("/api/notifications/read-all", middleware.AuthRequired(), handlers.MarkAllNotificationsRead)
+	router.POST("/api/notifications/:id/read", middleware.AuthRequired(), handlers.MarkNotificationRead)
+
+	router.GET("/api/sessions", middleware.AuthRequired(), handlers.GetSessions)
+	router.POST("/api/sessions/revoke-all", middleware.AuthRequired(), handlers.RevokeAllSessions)
+	router.DELETE("/api/sessions/:id", middleware.AuthRequired(), handlers.RevokeSession)
+
+	router.GET("/api/2fa/status", middleware.AuthRequired(), handlers.GetTwoFactorStatus)
+	router.POST("/api/2fa/setup", middleware.AuthRequired(), handlers.SetupTwoFactor)
+	router.POST("/api/2fa/confirm", middleware.AuthRequired(), middleware.RateLimitVerify(), handlers.ConfirmTwoFactor)
+	router.POST("/api/2fa/disable", middleware.AuthRequired(), middleware.RateLimitVerify(), handlers.DisableTwoFactor)
+	router.POST("/api/2fa/backup-codes", middleware.AuthRequired(), middleware.RateLimitVerify(), handlers.RegenerateBackupCodes)
+
+	router.GET("/api/me", middleware.AuthRequired(), func(c *gin.Context) {
+		nickname, exists := c.Get("nickname")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Не авторизован"})
+			return
+		}
+
+		user, err := services.Accounts.GetByNickname(nickname.(string))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения данных пользователя"})
+			return
+		}
+
+		services.Accounts.UpdateLastSeen(nickname.(string))
+
+		c.JSON(http.StatusOK, gin.H{
+			"user_id":                   user.ID,
+			"nickname":                  user.Nickname,
+			"email":                     user.Email,
+			"telegram":                  user.Telegram,
+			"avatar":                    user.Avatar,
+			"background_avatar_profile": user.BackgroundAvatarProfile,
+			"rating":                    user.Rating,
+			"reviews_count":             user.SuccessTransactions,
+			"user_role":                 user.UserRole,
+			"permissions":               models.PermissionsForRole(user.UserRole),
+			"user_description":          user.UserDescription,
+			"theme":                     user.Theme,
+			"last_seen_at":              user.LastSeenAt,
+		})
+	})
+
+	return router
+}
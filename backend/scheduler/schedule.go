@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule говорит, когда запускать задачу в следующий раз
+type Schedule interface {
+	Next(after time.Time) time.Time
+	String() string
+}
+
+type interval time.Duration
+
+// Every - запуск через равные промежутки, считая от предыдущего запуска
+func Every(d time.Duration) Schedule {
+	if d <= 0 {
+		panic("scheduler: interval must be positive")
+	}
+	return interval(d)
+}
+
+func (i interval) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(i))
+}
+
+func (i interval) String() string {
+	return "every " + time.Duration(i).String()
+}
+
+// cronSchedule - классический cron из 5 полей: минута, час, день месяца, месяц, день недели.
+// Поддерживаются *, списки (1,15), диапазоны (1-5) и шаги (*/10, 0-30/5). Время локальное
+type cronSchedule struct {
+	expr                          string
+	minute, hour, dom, month, dow uint64
+	domRestricted, dowRestricted  bool
+}
+
+// Cron разбирает cron выражение, например "0 4 * * *" - каждый день в 04:00
+func Cron(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	s := &cronSchedule{expr: expr}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cron %q: minute: %w", expr, err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cron %q: hour: %w", expr, err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cron %q: day of month: %w", expr, err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cron %q: month: %w", expr, err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cron %q: day of week: %w", expr, err)
+	}
+	// 7 - тоже воскресенье
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domRestricted = fields[2] != "*"
+	s.dowRestricted = fields[4] != "*"
+
+	return s, nil
+}
+
+// MustCron - Cron для выражений, зашитых в код
+func MustCron(expr string) Schedule {
+	s, err := Cron(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *cronSchedule) String() string {
+	return "cron " + s.expr
+}
+
+// Next перебирает минуты вперед. Пропускаем целые дни и часы, когда они не подходят,
+// так что даже редкое выражение находится быстро
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	// Выражение вроде "0 0 31 2 *" никогда не сработает
+	return time.Time{}
+}
+
+// dayMatches - как в cron: если ограничены и день месяца, и день недели, хватает любого из них
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step, hasStep := part, 1, false
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart, hasStep = part[:i], true
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			lo = n
+			// "5/10" значит "с 5 до конца с шагом 10"
+			if !hasStep {
+				hi = n
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
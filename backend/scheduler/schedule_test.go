@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	// 1 июня 2025 - воскресенье
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"daily, already passed today", "0 4 * * *", at(time.June, 1, 12, 0), at(time.June, 2, 4, 0)},
+		{"daily, later today", "0 4 * * *", at(time.June, 1, 3, 59).Add(30 * time.Second), at(time.June, 1, 4, 0)},
+		{"daily, exactly at run time", "0 4 * * *", at(time.June, 1, 4, 0), at(time.June, 2, 4, 0)},
+		{"every 15 minutes", "*/15 * * * *", at(time.June, 1, 12, 7), at(time.June, 1, 12, 15)},
+		{"every 15 minutes, next hour", "*/15 * * * *", at(time.June, 1, 12, 45), at(time.June, 1, 13, 0)},
+		{"step from value", "5/10 * * * *", at(time.June, 1, 12, 6), at(time.June, 1, 12, 15)},
+		{"step from value wraps", "5/10 * * * *", at(time.June, 1, 12, 55), at(time.June, 1, 13, 5)},
+		{"range with step", "0-30/10 9 * * *", at(time.June, 1, 9, 31), at(time.June, 2, 9, 0)},
+		{"list", "0 8,20 * * *", at(time.June, 1, 9, 0), at(time.June, 1, 20, 0)},
+		{"dom or dow: friday comes first", "0 0 13 * 5", at(time.June, 1, 12, 0), at(time.June, 6, 0, 0)},
+		{"dom or dow: day of month comes first", "0 0 2 * 5", at(time.June, 1, 12, 0), at(time.June, 2, 0, 0)},
+		{"dow only", "0 9 * * 1-5", at(time.June, 6, 10, 0), at(time.June, 9, 9, 0)},
+		{"7 is sunday", "0 9 * * 7", at(time.June, 1, 12, 0), at(time.June, 8, 9, 0)},
+		{"month step", "0 0 1 */3 *", at(time.June, 1, 12, 0), at(time.July, 1, 0, 0)},
+		{"leap day", "30 2 29 2 *", at(time.June, 1, 12, 0), time.Date(2028, time.February, 29, 2, 30, 0, 0, time.UTC)},
+		{"never fires", "0 0 31 2 *", at(time.June, 1, 12, 0), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Cron(tt.expr)
+			if err != nil {
+				t.Fatalf("Cron(%q): %v", tt.expr, err)
+			}
+			if got := s.Next(tt.after); !got.Equal(tt.want) {
+				t.Fatalf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronRejectsInvalid(t *testing.T) {
+	tests := []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"30-10 * * * *",
+		"a * * * *",
+		"1- * * * *",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := Cron(expr); err == nil {
+				t.Fatalf("Cron(%q) accepted an invalid expression", expr)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job - фоновая задача. Run должен уважать ctx: после отмены контекста задачу ждут,
+// пока она сама не вернется
+type Job struct {
+	Name       string
+	Schedule   Schedule
+	Run        func(ctx context.Context) error
+	RunOnStart bool          // запустить сразу при старте, не дожидаясь расписания
+	Timeout    time.Duration // 0 - без ограничения
+}
+
+// Status - последний запуск задачи, отдается в админку
+type Status struct {
+	Name         string     `json:"name"`
+	Schedule     string     `json:"schedule"`
+	Running      bool       `json:"running"`
+	Runs         int64      `json:"runs"`
+	Failures     int64      `json:"failures"`
+	LastStart    *time.Time `json:"last_start,omitempty"`
+	LastDuration string     `json:"last_duration,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	NextRun      *time.Time `json:"next_run,omitempty"`
+}
+
+// Scheduler запускает задачи по расписанию. Каждая задача крутится в своей горутине,
+// поэтому один запуск не может наложиться на следующий, а паника не роняет процесс
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    []*entry
+	started bool
+	wg      sync.WaitGroup
+}
+
+type entry struct {
+	job    Job
+	status Status
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add регистрирует задачу. Добавлять можно только до Start
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Run == nil {
+		return errors.New("scheduler: job needs a name, a schedule and a run func")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return fmt.Errorf("scheduler: cannot add job %q after start", job.Name)
+	}
+	for _, e := range s.jobs {
+		if e.job.Name == job.Name {
+			return fmt.Errorf("scheduler: job %q already registered", job.Name)
+		}
+	}
+
+	s.jobs = append(s.jobs, &entry{
+		job:    job,
+		status: Status{Name: job.Name, Schedule: job.Schedule.String()},
+	})
+	return nil
+}
+
+// Start запускает все задачи. Они работают, пока не отменят ctx
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+
+	for _, e := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, e)
+	}
+	slog.Info("Планировщик запущен", "jobs", len(s.jobs))
+}
+
+// Wait ждет, пока все задачи завершатся после отмены контекста Start.
+// Если ctx закончится раньше, возвращает его ошибку
+func (s *Scheduler) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Status возвращает состояние всех задач, отсортированное по имени
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]Status, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		result = append(result, e.status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	defer s.wg.Done()
+
+	if e.job.RunOnStart {
+		s.run(ctx, e)
+	}
+
+	for {
+		next := e.job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			slog.Warn("Расписание задачи больше не сработает, задача остановлена", "job", e.job.Name)
+			return
+		}
+		s.setNextRun(e, next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.run(ctx, e)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, e *entry) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	runCtx := ctx
+	if e.job.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, e.job.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	s.mu.Lock()
+	e.status.Running = true
+	e.status.LastStart = &start
+	e.status.NextRun = nil
+	s.mu.Unlock()
+
+	err := safeRun(runCtx, e.job)
+	duration := time.Since(start)
+
+	s.mu.Lock()
+	e.status.Running = false
+	e.status.Runs++
+	e.status.LastDuration = duration.Round(time.Millisecond).String()
+	e.status.LastError = ""
+	if err != nil {
+		e.status.Failures++
+		e.status.LastError = err.Error()
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		slog.Error("Задача завершилась с ошибкой", "job", e.job.Name, "duration", duration.Round(time.Millisecond), "error", err)
+	}
+}
+
+// safeRun превращает панику задачи в обычную ошибку
+func safeRun(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Паника в задаче", "job", job.Name, "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+func (s *Scheduler) setNextRun(e *entry, next time.Time) {
+	s.mu.Lock()
+	e.status.NextRun = &next
+	s.mu.Unlock()
+}
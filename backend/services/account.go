@@ -1,20 +1,20 @@
 package services
 
 import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/mail"
 	"errors"
 	"net/http"
 	"regexp"
 	"strings"
-	"time"
 	"unicode"
 
-	"arizonagamesstore/backend/database"
 	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
 	"arizonagamesstore/backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
 type RegisterRequest struct {
@@ -195,7 +195,7 @@ func RegisterAccount(c *gin.Context) {
 
 	clientIP := c.ClientIP()
 	if clientIP != "" && clientIP != "::1" && clientIP != "127.0.0.1" {
-		count, err := CountAccountsByIP(clientIP)
+		count, err := Accounts.CountByIP(clientIP)
 		if err == nil && count >= 3 {
 			c.SetCookie(
 				"reg_blocked",
@@ -233,14 +233,13 @@ func RegisterAccount(c *gin.Context) {
 		return
 	}
 
-	var existingAccount models.Account
-	err := database.DB.Where("email = ?", req.Email).First(&existingAccount).Error
-	if err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email уже используется"})
+	emailTaken, err := Accounts.EmailExists(req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при проверке email"})
 		return
 	}
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при проверке email"})
+	if emailTaken {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email уже используется"})
 		return
 	}
 
@@ -250,34 +249,23 @@ func RegisterAccount(c *gin.Context) {
 		return
 	}
 
-	var existingNickname models.Account
-	err = database.DB.Where("nickname = ?", req.Nickname).First(&existingNickname).Error
-	if err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Аккаунт с таким никнеймом уже существует"})
-		return
-	}
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+	nicknameTaken, err := Accounts.NicknameExists(req.Nickname)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при проверке никнейма"})
 		return
 	}
-
-	database.DB.Where("email = ? OR nickname = ?", req.Email, req.Nickname).Delete(&models.EmailVerification{})
-
-	code := utils.GenerateVerificationCode()
-	verification := models.EmailVerification{
-		Email:        req.Email,
-		Nickname:     req.Nickname,
-		PasswordHash: string(hashedPassword),
-		Code:         code,
-		ExpiresAt:    time.Now().Add(10 * time.Minute),
+	if nicknameTaken {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Аккаунт с таким никнеймом уже существует"})
+		return
 	}
 
-	if err := database.DB.Create(&verification).Error; err != nil {
+	code, err := Accounts.StartVerification(req.Email, req.Nickname, string(hashedPassword))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании кода подтверждения"})
 		return
 	}
 
-	if err := utils.SendVerificationEmail(req.Email, code); err != nil {
+	if err := Mail.Queue(mail.Verification, req.Email, mail.CodeData{Code: code, Lifetime: verificationCodeLifetime}); err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"message":         req.Nickname + " успешно зарегистрирован!",
 			"nickname":        req.Nickname,
@@ -298,134 +286,92 @@ func RegisterAccount(c *gin.Context) {
 	})
 }
 
-func GetUserByNickname(nickname string) (*models.Account, error) {
-	var account models.Account
-	err := database.DB.Where("nickname = ?", nickname).First(&account).Error
-	if err != nil {
-		return nil, err
-	}
-	return &account, nil
+// AccountService - аккаунты пользователей: поиск, регистрация и правки профиля
+type AccountService struct {
+	accounts repository.AccountRepository
+	clock    clock.Clock
 }
 
-func UpdateLastSeen(nickname string) error {
-	return database.DB.Model(&models.Account{}).
-		Where("nickname = ?", nickname).
-		Update("last_seen_at", time.Now()).Error
+func NewAccountService(accounts repository.AccountRepository, clk clock.Clock) *AccountService {
+	return &AccountService{accounts: accounts, clock: clk}
 }
 
-func UpdateUserAvatar(nickname string, avatarURL string) error {
-	return database.DB.Model(&models.Account{}).
-		Where("nickname = ?", nickname).
-		Update("avatar", avatarURL).Error
+func (s *AccountService) GetByNickname(nickname string) (*models.Account, error) {
+	return s.accounts.FindByNickname(nickname)
 }
 
-// UpdateUserNickname обновляет никнейм пользователя и все его объявления
-func UpdateUserNickname(oldNickname string, newNickname string) error {
-	now := time.Now()
+func (s *AccountService) GetByID(id uint) (*models.Account, error) {
+	return s.accounts.FindByID(id)
+}
 
-	tx := database.DB.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
+func (s *AccountService) GetByEmail(email string) (*models.Account, error) {
+	return s.accounts.FindByEmail(email)
+}
 
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	if err := tx.Model(&models.Account{}).
-		Where("nickname = ?", oldNickname).
-		Updates(map[string]interface{}{
-			"nickname":             newNickname,
-			"last_nickname_change": &now,
-			"last_settings_change": &now,
-		}).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	return tx.Commit().Error
-}
-
-func UpdateUserEmail(nickname string, newEmail string) error {
-	now := time.Now()
-	return database.DB.Model(&models.Account{}).
-		Where("nickname = ?", nickname).
-		Updates(map[string]interface{}{
-			"email":                newEmail,
-			"last_email_change":    &now,
-			"last_settings_change": &now,
-		}).Error
-}
-
-func UpdateUserPassword(nickname string, newPasswordHash string) error {
-	now := time.Now()
-	return database.DB.Model(&models.Account{}).
-		Where("nickname = ?", nickname).
-		Updates(map[string]interface{}{
-			"password_hash":        newPasswordHash,
-			"last_settings_change": &now,
-		}).Error
-}
-
-func UpdateUserTheme(nickname string, theme string) error {
-	return database.DB.Model(&models.Account{}).
-		Where("nickname = ?", nickname).
-		Update("theme", theme).Error
-}
-
-func UpdateUserDescription(nickname string, description string) error {
-	now := time.Now()
-	return database.DB.Model(&models.Account{}).
-		Where("nickname = ?", nickname).
-		Updates(map[string]interface{}{
-			"user_description":     description,
-			"last_settings_change": &now,
-		}).Error
-}
-
-func CheckNicknameExists(nickname string) (bool, error) {
-	var account models.Account
-	err := database.DB.Where("nickname = ?", nickname).First(&account).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return false, nil
-		}
-		return false, err
-	}
-	return true, nil
+func (s *AccountService) UpdateLastSeen(nickname string) error {
+	return s.accounts.Update(nickname, map[string]interface{}{"last_seen_at": s.clock.Now()})
 }
 
-func CheckEmailExists(email string) (bool, error) {
-	var account models.Account
-	err := database.DB.Where("email = ?", email).First(&account).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return false, nil
-		}
-		return false, err
-	}
-	return true, nil
+func (s *AccountService) UpdateAvatar(nickname string, avatarURL string) error {
+	return s.accounts.Update(nickname, map[string]interface{}{"avatar": avatarURL})
 }
 
-func CreateAccount(nickname string, passwordHash string, regIP string, lastIP string) error {
-	account := models.Account{
-		Nickname:      nickname,
-		PasswordHash:  passwordHash,
-		Theme:         "dark",
-		RegIP:         regIP,
-		LastIP:        lastIP,
-		UserRole:      "User",
-		EmailVerified: false,
-		Avatar:        "https://storage.yandexcloud.net/fotora.ru/uploads/2b0c131e8cfe54b1.jpeg",
-	}
+// UpdateNickname меняет никнейм и запоминает, когда это было (менять можно не чаще раза в месяц)
+func (s *AccountService) UpdateNickname(oldNickname string, newNickname string) error {
+	now := s.clock.Now()
+	return s.accounts.Update(oldNickname, map[string]interface{}{
+		"nickname":             newNickname,
+		"last_nickname_change": &now,
+		"last_settings_change": &now,
+	})
+}
+
+func (s *AccountService) UpdateEmail(nickname string, newEmail string) error {
+	now := s.clock.Now()
+	return s.accounts.Update(nickname, map[string]interface{}{
+		"email":                newEmail,
+		"last_email_change":    &now,
+		"last_settings_change": &now,
+	})
+}
+
+func (s *AccountService) UpdatePassword(nickname string, newPasswordHash string) error {
+	now := s.clock.Now()
+	return s.accounts.Update(nickname, map[string]interface{}{
+		"password_hash":        newPasswordHash,
+		"last_settings_change": &now,
+	})
+}
 
-	result := database.DB.Create(&account)
-	return result.Error
+func (s *AccountService) UpdateTheme(nickname string, theme string) error {
+	return s.accounts.Update(nickname, map[string]interface{}{"theme": theme})
+}
+
+func (s *AccountService) UpdateDescription(nickname string, description string) error {
+	now := s.clock.Now()
+	return s.accounts.Update(nickname, map[string]interface{}{
+		"user_description":     description,
+		"last_settings_change": &now,
+	})
 }
 
-func CreateAccountWithEmail(nickname string, email string, passwordHash string, regIP string, lastIP string, emailVerified bool) error {
+func (s *AccountService) NicknameExists(nickname string) (bool, error) {
+	return accountExists(s.accounts.FindByNickname(nickname))
+}
+
+func (s *AccountService) EmailExists(email string) (bool, error) {
+	return accountExists(s.accounts.FindByEmail(email))
+}
+
+func accountExists(account *models.Account, err error) (bool, error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Create заводит аккаунт с настройками по умолчанию и возвращает его вместе с ID
+func (s *AccountService) Create(nickname string, email string, passwordHash string, regIP string, lastIP string, emailVerified bool) (*models.Account, error) {
 	account := models.Account{
 		Nickname:      nickname,
 		Email:         email,
@@ -434,46 +380,79 @@ func CreateAccountWithEmail(nickname string, email string, passwordHash string,
 		Theme:         "dark",
 		RegIP:         regIP,
 		LastIP:        lastIP,
-		UserRole:      "User",
+		UserRole:      models.RoleUser,
 		Avatar:        "https://storage.yandexcloud.net/fotora.ru/uploads/2b0c131e8cfe54b1.jpeg",
 	}
 
-	result := database.DB.Create(&account)
-	return result.Error
+	if err := s.accounts.Create(&account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (s *AccountService) UpdateProfileBackground(nickname string, backgroundURL string) error {
+	return s.accounts.Update(nickname, map[string]interface{}{"background_avatar_profile": backgroundURL})
+}
+
+func (s *AccountService) DeleteProfileBackground(nickname string) error {
+	return s.accounts.Update(nickname, map[string]interface{}{"background_avatar_profile": ""})
 }
 
-func UpdateProfileBackground(nickname string, backgroundURL string) error {
-	return database.DB.Model(&models.Account{}).
-		Where("nickname = ?", nickname).
-		Update("background_avatar_profile", backgroundURL).Error
+func (s *AccountService) CountByIP(ip string) (int64, error) {
+	return s.accounts.CountByRegIP(ip)
 }
 
-func DeleteProfileBackground(nickname string) error {
-	return database.DB.Model(&models.Account{}).
-		Where("nickname = ?", nickname).
-		Update("background_avatar_profile", "").Error
+func (s *AccountService) UpdateLastIP(nickname string, ip string) error {
+	return s.accounts.Update(nickname, map[string]interface{}{"last_ip": ip})
 }
 
-func CountAccountsByIP(ip string) (int64, error) {
-	var count int64
-	err := database.DB.Model(&models.Account{}).
-		Where("reg_ip = ?", ip).
-		Count(&count).Error
-	return count, err
+func (s *AccountService) UpdateTelegram(nickname string, telegram string) error {
+	now := s.clock.Now()
+	return s.accounts.Update(nickname, map[string]interface{}{
+		"telegram":             telegram,
+		"last_settings_change": &now,
+	})
+}
+
+// UpdateRole меняет роль. repository.ErrNotFound, если такого пользователя нет
+func (s *AccountService) UpdateRole(nickname string, role string) error {
+	return s.accounts.Update(nickname, map[string]interface{}{"user_role": role})
 }
 
-func UpdateLastIP(nickname string, ip string) error {
-	return database.DB.Model(&models.Account{}).
-		Where("nickname = ?", nickname).
-		Update("last_ip", ip).Error
+// StartVerification запоминает регистрацию до подтверждения email. Прежние коды
+// для этого email и ника сгорают. Возвращает новый код для письма
+func (s *AccountService) StartVerification(email string, nickname string, passwordHash string) (string, error) {
+	verification := models.EmailVerification{
+		Email:        email,
+		Nickname:     nickname,
+		PasswordHash: passwordHash,
+		Code:         utils.GenerateVerificationCode(),
+		ExpiresAt:    s.clock.Now().Add(verificationCodeLifetime),
+	}
+	if err := s.accounts.SaveVerification(&verification); err != nil {
+		return "", err
+	}
+	return verification.Code, nil
+}
+
+// FindVerification - ожидающая подтверждения регистрация по email
+func (s *AccountService) FindVerification(email string) (*models.EmailVerification, error) {
+	return s.accounts.FindVerification(email)
+}
+
+// CheckVerification - ожидающая подтверждения регистрация по email, если код
+// совпал и еще не истек. Иначе ошибка, без уточнений что именно не так
+func (s *AccountService) CheckVerification(email string, code string) (*models.EmailVerification, error) {
+	verification, err := s.accounts.FindVerification(email)
+	if err != nil {
+		return nil, err
+	}
+	if verification.Code != code || !verification.ExpiresAt.After(s.clock.Now()) {
+		return nil, repository.ErrNotFound
+	}
+	return verification, nil
 }
 
-func UpdateTelegram(nickname string, telegram string) error {
-	now := time.Now()
-	return database.DB.Model(&models.Account{}).
-		Where("nickname = ?", nickname).
-		Updates(map[string]interface{}{
-			"telegram":             telegram,
-			"last_settings_change": &now,
-		}).Error
+func (s *AccountService) DeleteVerification(email string) error {
+	return s.accounts.DeleteVerification(email)
 }
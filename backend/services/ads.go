@@ -1,15 +1,52 @@
 package services
 
 import (
-	"arizonagamesstore/backend/database"
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/events"
+	"arizonagamesstore/backend/metrics"
 	"arizonagamesstore/backend/models"
-	"fmt"
-	"log"
+	"arizonagamesstore/backend/repository"
+	"context"
+	"errors"
+	"log/slog"
 	"time"
 )
 
-func CreateNewAd(dto models.Ad, filePathS3 string) (bool, string) {
-	createAd := models.Ad{
+var (
+	ErrAdNotOwned         = errors.New("ad belongs to another user")
+	ErrAdStatusTransition = errors.New("ad status transition is not allowed")
+	ErrAdChanged          = errors.New("ad status changed while editing")
+)
+
+// sellerTransitions - в какой статус продавец может перевести свое объявление и из каких.
+// hidden выставляет и снимает только модерация
+var sellerTransitions = map[string][]string{
+	models.AdStatusActive:   {models.AdStatusDraft, models.AdStatusExpired},
+	models.AdStatusSold:     {models.AdStatusActive, models.AdStatusExpired},
+	models.AdStatusArchived: {models.AdStatusDraft, models.AdStatusActive, models.AdStatusExpired, models.AdStatusSold},
+}
+
+// AdService - объявления, которые продавец ведет сам: создание, правка, смена статуса
+type AdService struct {
+	ads   repository.AdRepository
+	clock clock.Clock
+}
+
+func NewAdService(ads repository.AdRepository, clk clock.Clock) *AdService {
+	return &AdService{ads: ads, clock: clk}
+}
+
+// Create публикует объявление (или сохраняет черновик) на AdLifetime и учитывает его в счетчике категории
+func (s *AdService) Create(dto models.Ad, imageURL string) (*models.Ad, error) {
+	now := s.clock.Now()
+	expiresAt := now.Add(models.AdLifetime)
+
+	status := models.AdStatusActive
+	if dto.Status == models.AdStatusDraft {
+		status = models.AdStatusDraft
+	}
+
+	ad := models.Ad{
 		ServerName:       dto.ServerName,
 		Title:            dto.Title,
 		Description:      dto.Description,
@@ -20,23 +57,96 @@ func CreateNewAd(dto models.Ad, filePathS3 string) (bool, string) {
 		RentalHoursLimit: dto.RentalHoursLimit,
 		Category:         dto.Category,
 		Nickname:         dto.Nickname,
-		Image:            filePathS3,
+		Image:            imageURL,
+		ImageThumb:       dto.ImageThumb,
+		ImageMedium:      dto.ImageMedium,
+		Status:           status,
+		ExpiresAt:        &expiresAt,
+		BumpedAt:         &now,
+		StatusChangedAt:  &now,
+	}
+
+	if err := s.ads.Create(&ad); err != nil {
+		return nil, err
+	}
+	metrics.AdsCreated.WithLabelValues(status).Inc()
+
+	s.adjustCount(ad.Category, "", status)
+	return &ad, nil
+}
+
+func (s *AdService) Get(id uint) (*models.Ad, error) {
+	ad, err := s.ads.FindByID(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrAdNotFound
+	}
+	return ad, err
+}
+
+// GetOwned возвращает объявление, только если оно принадлежит nickname
+func (s *AdService) GetOwned(id uint, nickname string) (*models.Ad, error) {
+	ad, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if ad.Nickname != nickname {
+		return nil, ErrAdNotOwned
+	}
+	return ad, nil
+}
+
+// Edit сохраняет правки объявления, полученного через GetOwned. Пишутся только поля,
+// которые продавец меняет в форме: статус, сроки и просмотры не трогаем, их параллельно
+// меняют задача истечения и модерация. ErrAdChanged, если статус успели поменять
+func (s *AdService) Edit(ad *models.Ad) error {
+	err := s.ads.UpdateIfStatus(ad.ID, ad.Status, map[string]interface{}{
+		"title":        ad.Title,
+		"description":  ad.Description,
+		"type":         ad.Type,
+		"price":        ad.Price,
+		"image":        ad.Image,
+		"image_thumb":  ad.ImageThumb,
+		"image_medium": ad.ImageMedium,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrAdChanged
 	}
+	return err
+}
 
-	result := database.DB.Create(&createAd)
-	if result.Error != nil {
-		err := fmt.Sprint(result.Error)
-		return false, err
+func (s *AdService) IncrementViews(id uint) error {
+	err := s.ads.IncrementViews(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrAdNotFound
 	}
+	return err
+}
+
+// RecordView добавляет объявление в историю просмотров пользователя
+func (s *AdService) RecordView(nickname string, adID uint) error {
+	return s.ads.RecordView(nickname, adID, s.clock.Now())
+}
 
-	return true, ""
+// WithAuthor - объявления с данными автора по списку id, порядок не гарантируется
+func (s *AdService) WithAuthor(ids []uint) ([]models.AdWithAuthor, error) {
+	return s.ads.FindWithAuthor(ids)
 }
 
-type AdWithAuthor struct {
-	models.Ad
-	AuthorAvatar   string  `json:"author_avatar"`
-	AuthorRating   float32 `json:"author_rating"`
-	OwnerTelegram  string  `json:"owner_telegram"`
+// ListMine - объявления продавца для его кабинета. Без status показывается все, кроме архива
+func (s *AdService) ListMine(nickname string, status string) ([]models.AdWithAuthor, error) {
+	return s.ads.ListByOwner(nickname, status)
+}
+
+func (s *AdService) RenameOwner(oldNickname string, newNickname string) error {
+	return s.ads.RenameOwner(oldNickname, newNickname)
+}
+
+type ViewedAdResponse struct {
+	ID           uint                 `json:"id"`
+	UserNickname string               `json:"user_nickname"`
+	AdID         int                  `json:"ad_id"`
+	ViewedAt     time.Time            `json:"viewed_at"`
+	Ad           *models.AdWithAuthor `json:"Ad"`
 }
 
 type AdFilters struct {
@@ -47,183 +157,254 @@ type AdFilters struct {
 	Currency string
 }
 
-func GetAdsByCategory(category string, server string, limit int, offset int, filters *AdFilters) ([]AdWithAuthor, error) {
-	var ads []AdWithAuthor
-
-	query := database.DB.Table("ads").
-		Select("ads.*, accounts.avatar as author_avatar, accounts.rating as author_rating, accounts.telegram as owner_telegram").
-		Joins("LEFT JOIN accounts ON ads.nickname = accounts.nickname").
-		Where("ads.category = ?", category)
+// adFilter переводит фильтры из запроса в фильтр репозитория
+func adFilter(server string, filters *AdFilters) (repository.AdFilter, string) {
+	filter := repository.AdFilter{Server: server}
+	if filters == nil {
+		return filter, ""
+	}
+	filter.Type = filters.Type
+	filter.PriceMin = filters.PriceMin
+	filter.PriceMax = filters.PriceMax
+	filter.Currency = filters.Currency
+	return filter, filters.Sort
+}
 
-	if server != "" && server != "all" {
-		query = query.Where("ads.server_name = ?", server)
+// ListByCategory - активные объявления категории для ленты
+func (s *AdService) ListByCategory(category string, server string, req PageRequest, filters *AdFilters) ([]models.AdWithAuthor, *Page, error) {
+	filter, sort := adFilter(server, filters)
+	filter.Category = category
+	if sort == "random" {
+		sort = ""
 	}
+	return s.ads.List(filter, sort, req)
+}
 
-	if filters != nil {
-		if filters.Type != "" {
-			query = query.Where("ads.type = ?", filters.Type)
-		}
-		if filters.PriceMin != nil {
-			query = query.Where("ads.price >= ?", *filters.PriceMin)
-		}
-		if filters.PriceMax != nil {
-			query = query.Where("ads.price <= ?", *filters.PriceMax)
-		}
-		if filters.Currency != "" {
-			query = query.Where("ads.currency = ?", filters.Currency)
-		}
+// ListByNickname - объявления продавца в выбранных статусах для его публичной страницы
+func (s *AdService) ListByNickname(nickname string, statuses []string, req PageRequest) ([]models.AdWithAuthor, *Page, error) {
+	return s.ads.List(repository.AdFilter{Nickname: nickname, Statuses: statuses}, "", req)
+}
 
-		switch filters.Sort {
-		case "date_asc":
-			query = query.Order("ads.created_at ASC")
-		case "price_desc":
-			query = query.Order("ads.price DESC")
-		case "price_asc":
-			query = query.Order("ads.price ASC")
-		case "views_desc":
-			query = query.Order("ads.views DESC")
-		default:
-			query = query.Order("ads.created_at DESC")
-		}
-	} else {
-		query = query.Order("ads.created_at DESC")
+// ListRandom отдает активные объявления в случайном порядке. Порядок задает seed:
+// он берется из курсора или запроса, а если его нет - генерируется новый
+func (s *AdService) ListRandom(req PageRequest) ([]models.AdWithAuthor, *Page, error) {
+	seed := repository.CursorSeed(req.Cursor)
+	if seed == "" {
+		seed = req.Seed
 	}
-
-	result := query.Limit(limit).Offset(offset).Find(&ads)
-	if result.Error != nil {
-		return nil, result.Error
+	if !repository.IsShuffleSeed(seed) {
+		seed = repository.NewShuffleSeed()
 	}
+	req.Seed = seed
 
-	return ads, nil
+	return s.ads.List(repository.AdFilter{}, "random", req)
 }
 
-func GetAdsByNickname(nickname string) ([]AdWithAuthor, error) {
-	var ads []AdWithAuthor
+// Search ищет активные объявления по словам в названии и описании.
+// Пустая сортировка или "relevance" сортирует по релевантности
+func (s *AdService) Search(text string, category string, server string, limit int, offset int, filters *AdFilters) ([]models.AdSearchResult, int64, error) {
+	filter, sort := adFilter(server, filters)
+	filter.Category = category
+	if sort == "random" {
+		sort = ""
+	}
 
-	result := database.DB.Table("ads").
-		Select("ads.*, accounts.avatar as author_avatar, accounts.rating as author_rating, accounts.telegram as owner_telegram").
-		Joins("LEFT JOIN accounts ON ads.nickname = accounts.nickname").
-		Where("ads.nickname = ?", nickname).
-		Order("ads.created_at DESC").
-		Find(&ads)
+	results, total, err := s.ads.Search(text, filter, sort, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	if result.Error != nil {
-		return nil, result.Error
+	for i := range results {
+		results[i].TitleHighlight = renderHighlight(results[i].TitleHighlight)
+		results[i].DescriptionHighlight = renderHighlight(results[i].DescriptionHighlight)
 	}
+	return results, total, nil
+}
+
+// CategoryCount - сколько активных объявлений в категории по счетчику statistics
+func (s *AdService) CategoryCount(category string) (int64, error) {
+	return s.ads.CategoryCount(category)
+}
 
-	return ads, nil
+// Unlisted уменьшает счетчик категории, когда активное объявление убрала модерация
+func (s *AdService) Unlisted(category string) error {
+	return s.ads.AdjustCategoryCount(category, -1)
 }
 
-func UpdateNickNameAds(oldNickname string, newNickname string) error {
-	tx := database.DB.Begin()
-	if tx.Error != nil {
-		return tx.Error
+// ExpireOld переводит старые объявления в expired, а давно просроченные - в archived.
+// Объявления больше не удаляются, отзывы на них сохраняются. Запускается планировщиком раз в час
+func (s *AdService) ExpireOld(ctx context.Context) error {
+	now := s.clock.Now()
+
+	if err := s.warnExpiringSoon(ctx, now); err != nil {
+		slog.ErrorContext(ctx, "Ошибка предупреждения об истечении объявлений", "error", err)
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	expired, err := s.ads.Expire(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	if len(expired) > 0 {
+		slog.InfoContext(ctx, "Объявления истекли", "count", len(expired))
+		metrics.AdsExpired.Add(float64(len(expired)))
+
+		categoryCount := make(map[string]int)
+		for _, ad := range expired {
+			categoryCount[ad.Category]++
+			Notify(ad.Nickname, events.TypeAdExpired, models.NotificationPayload{
+				"ad_id": ad.ID,
+				"title": ad.Title,
+			})
+		}
+
+		for category, count := range categoryCount {
+			if err := s.ads.AdjustCategoryCount(category, -count); err != nil {
+				slog.ErrorContext(ctx, "Ошибка обновления счетчика", "category", category, "error", err)
+				continue
+			}
+			slog.DebugContext(ctx, "Уменьшен счетчик", "category", category, "by", count)
 		}
-	}()
-
-	if err := tx.Model(&models.Ad{}).
-		Where("nickname = ?", oldNickname).
-		Updates(map[string]interface{}{
-			"nickname": newNickname,
-		}).Error; err != nil {
-		tx.Rollback()
+	}
+
+	archived, err := s.ads.Archive(ctx, now.Add(-models.AdArchiveAfter), now)
+	if err != nil {
 		return err
 	}
+	if archived > 0 {
+		slog.InfoContext(ctx, "Просроченные объявления ушли в архив", "count", archived)
+	}
 
-	return tx.Commit().Error
+	return nil
 }
 
-func CreateReport(adID uint, reporterNickname string, reason string, description *string) error {
-	report := models.Report{
-		AdID:             adID,
-		ReporterNickname: reporterNickname,
-		Reason:           reason,
-		Description:      description,
+// warnExpiringSoon предупреждает авторов, что объявление скоро уйдет из ленты.
+// expiry_notified_at не дает предупредить дважды, при продлении он сбрасывается
+func (s *AdService) warnExpiringSoon(ctx context.Context, now time.Time) error {
+	expiring, err := s.ads.ExpiringSoon(ctx, now, now.Add(models.AdExpiryWarning))
+	if err != nil {
+		return err
 	}
 
-	result := database.DB.Create(&report)
-	if result.Error != nil {
-		return result.Error
+	for _, ad := range expiring {
+		err := s.ads.MarkExpiryNotified(ctx, ad.ID, now)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		Notify(ad.Nickname, events.TypeAdExpiring, models.NotificationPayload{
+			"ad_id":      ad.ID,
+			"title":      ad.Title,
+			"expires_at": ad.ExpiresAt,
+		})
 	}
 
 	return nil
 }
 
-func AutoDeleteOldAds() {
-	ticker := time.NewTicker(1 * time.Hour)
-	defer ticker.Stop()
+// RecalculateStatistics сверяет счетчики категорий с таблицей ads
+func (s *AdService) RecalculateStatistics(ctx context.Context) error {
+	counts, err := s.ads.RecountCategories(ctx)
+	if err != nil {
+		return err
+	}
+	for category, count := range counts {
+		slog.DebugContext(ctx, "Пересчет счетчика", "category", category, "count", count)
+	}
+	return nil
+}
 
-	log.Println("Запущена служба автоудаления объявлений старше 48 часов")
+// ChangeStatusByOwner переводит объявление продавца в новый статус по правилам sellerTransitions.
+// Переход в active продлевает объявление на AdLifetime и поднимает его в ленте
+func (s *AdService) ChangeStatusByOwner(adID uint, nickname string, to string) (*models.Ad, error) {
+	ad, err := s.GetOwned(adID, nickname)
+	if err != nil {
+		return nil, err
+	}
 
-	for {
-		<-ticker.C
+	if !canSellerTransition(ad.Status, to) {
+		return nil, ErrAdStatusTransition
+	}
 
-		cutoffTime := time.Now().Add(-48 * time.Hour)
+	from := ad.Status
+	now := s.clock.Now()
+	updates := map[string]interface{}{
+		"status":            to,
+		"status_changed_at": now,
+	}
 
-		var adsToDelete []models.Ad
-		database.DB.Where("created_at < ?", cutoffTime).Find(&adsToDelete)
+	if to == models.AdStatusActive {
+		expiresAt := now.Add(models.AdLifetime)
+		updates["expires_at"] = expiresAt
+		updates["bumped_at"] = now
+		updates["expiry_notified_at"] = nil
+		ad.ExpiresAt = &expiresAt
+		ad.BumpedAt = &now
+	}
 
-		categoryCount := make(map[string]int)
-		for _, ad := range adsToDelete {
-			categoryCount[ad.Category]++
+	// Статус проверяется еще раз в самом UPDATE: если его успели поменять, переход не применится
+	if err := s.ads.UpdateIfStatus(ad.ID, from, updates); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAdStatusTransition
 		}
+		return nil, err
+	}
 
-		result := database.DB.Where("created_at < ?", cutoffTime).Delete(&models.Ad{})
+	ad.Status = to
+	ad.StatusChangedAt = &now
+	s.adjustCount(ad.Category, from, to)
 
-		if result.Error != nil {
-			log.Printf("Ошибка при удалении старых объявлений: %v", result.Error)
-		} else if result.RowsAffected > 0 {
-			log.Printf("Удалено %d объявлений старше 48 часов", result.RowsAffected)
+	return ad, nil
+}
 
-			for category, count := range categoryCount {
-				for i := 0; i < count; i++ {
-					DecreaseAdCount(category)
-				}
-				log.Printf("Уменьшен счетчик для категории %s на %d", category, count)
-			}
+func canSellerTransition(from string, to string) bool {
+	for _, allowed := range sellerTransitions[to] {
+		if allowed == from {
+			return true
 		}
 	}
+	return false
 }
 
-func RecalculateStatistics() error {
-	var stats []models.Statistic
-	if err := database.DB.Find(&stats).Error; err != nil {
-		return err
+// adjustCount поддерживает счетчик категории: в статистике учитываются только active объявления
+func (s *AdService) adjustCount(category string, from string, to string) {
+	delta := 0
+	switch {
+	case from == models.AdStatusActive && to != models.AdStatusActive:
+		delta = -1
+	case from != models.AdStatusActive && to == models.AdStatusActive:
+		delta = 1
 	}
-
-	for _, stat := range stats {
-		var count int64
-		database.DB.Table("ads").Where("category = ?", stat.CategoryName).Count(&count)
-
-		database.DB.Model(&models.Statistic{}).
-			Where("category_name = ?", stat.CategoryName).
-			Update("ad_count", count)
-
-		log.Printf("Пересчет счетчика для %s: %d объявлений", stat.CategoryName, count)
+	if delta == 0 {
+		return
 	}
 
-	return nil
+	if err := s.ads.AdjustCategoryCount(category, delta); err != nil {
+		slog.Error("Ошибка обновления счетчика", "category", category, "error", err)
+	}
 }
 
-func GetRandomAds(limit int, offset int) ([]AdWithAuthor, error) {
-	var ads []AdWithAuthor
-
-	result := database.DB.Table("ads").
-		Select("ads.*, accounts.avatar as author_avatar, accounts.rating as author_rating, accounts.telegram as owner_telegram").
-		Joins("LEFT JOIN accounts ON ads.nickname = accounts.nickname").
-		Order("RANDOM()").
-		Limit(limit).
-		Offset(offset).
-		Find(&ads)
+// Viewed возвращает историю просмотров пользователя, сверху последние.
+// Удаленные объявления в историю не попадают
+func (s *AdService) Viewed(nickname string, req PageRequest) ([]ViewedAdResponse, *Page, error) {
+	rows, page, err := s.ads.Viewed(nickname, req)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if result.Error != nil {
-		return nil, result.Error
+	viewedAds := make([]ViewedAdResponse, 0, len(rows))
+	for i := range rows {
+		viewedAds = append(viewedAds, ViewedAdResponse{
+			ID:           rows[i].ViewedID,
+			UserNickname: rows[i].UserNickname,
+			AdID:         int(rows[i].ID),
+			ViewedAt:     rows[i].ViewedAt,
+			Ad:           &rows[i].AdWithAuthor,
+		})
 	}
 
-	return ads, nil
+	return viewedAds, page, nil
 }
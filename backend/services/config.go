@@ -0,0 +1,11 @@
+package services
+
+import "arizonagamesstore/backend/config"
+
+// appURL - публичный адрес бэкенда для ссылок в письмах
+var appURL = "http://localhost:8080"
+
+// Init передает сервисам их часть конфигурации. Вызывается один раз при старте
+func Init(cfg *config.Config) {
+	appURL = cfg.Server.AppURL
+}
@@ -1,9 +1,10 @@
 package services
 
 import (
-	"arizonagamesstore/backend/database"
+	"arizonagamesstore/backend/mail"
 	"arizonagamesstore/backend/models"
 	"arizonagamesstore/backend/utils"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -20,6 +21,9 @@ type ResendCodeRequest struct {
 	Email string `json:"email" binding:"required,email"`
 }
 
+// verificationCodeLifetime - сколько живет код подтверждения из письма
+const verificationCodeLifetime = 10 * time.Minute
+
 // VerifyEmail godoc
 // @Summary Подтвердить email
 // @Description Подтверждает email пользователя после регистрации. Нужно ввести код который пришел на почту. После подтверждения сразу логинит пользователя и выдает токены
@@ -45,8 +49,8 @@ func VerifyEmail(c *gin.Context) {
 		return
 	}
 
-	var verification models.EmailVerification
-	if err := database.DB.Where("email = ? AND code = ? AND expires_at > ?", req.Email, req.Code, time.Now()).First(&verification).Error; err != nil {
+	verification, err := Accounts.CheckVerification(req.Email, req.Code)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный код подтверждения или срок его действия истёк."})
 		return
 	}
@@ -62,7 +66,7 @@ func VerifyEmail(c *gin.Context) {
 	}
 
 	if clientIP != "::1" && clientIP != "127.0.0.1" {
-		count, err := CountAccountsByIP(clientIP)
+		count, err := Accounts.CountByIP(clientIP)
 		if err == nil && count >= 3 {
 			c.SetCookie(
 				"reg_blocked",
@@ -78,7 +82,8 @@ func VerifyEmail(c *gin.Context) {
 		}
 	}
 
-	if err := CreateAccountWithEmail(verification.Nickname, verification.Email, verification.PasswordHash, clientIP, clientIP, true); err != nil {
+	account, err := Accounts.Create(verification.Nickname, verification.Email, verification.PasswordHash, clientIP, clientIP, true)
+	if err != nil {
 		if utils.IsDuplicateKeyError(err) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Email уже зарегистрирован или используется"})
 			return
@@ -87,40 +92,21 @@ func VerifyEmail(c *gin.Context) {
 		return
 	}
 
-	account, err := GetUserByNickname(verification.Nickname)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении данных аккаунта"})
-		return
+	if err := Accounts.DeleteVerification(req.Email); err != nil {
+		slog.WarnContext(c.Request.Context(), "Не удалось удалить код подтверждения", "user", account.Nickname, "error", err)
 	}
 
-	database.DB.Where("email = ?", req.Email).Delete(&models.EmailVerification{})
-
-	accessToken, err := utils.GenerateAccessToken(account.ID, account.Nickname)
+	accessToken, err := utils.GenerateAccessToken(account.ID, account.Nickname, models.NormalizeRole(account.UserRole))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании access токена"})
 		return
 	}
 
-	refreshToken, err := utils.GenerateRefreshToken(account.ID, account.Nickname)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании refresh токена"})
-		return
-	}
-
-	tokenRecord := models.RefreshToken{
-		AccountID: account.ID,
-		Token:     refreshToken,
-		ExpiresAt: time.Now().Add(30 * 24 * time.Hour),
-	}
-
-	if err := database.DB.Create(&tokenRecord).Error; err != nil {
+	if err := Sessions.Start(c, account, accessToken); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при сохранении refresh токена"})
 		return
 	}
 
-	utils.SetAuthCookie(c, "access_token", accessToken, 180)
-	utils.SetAuthCookie(c, "refresh_token", refreshToken, 30*24*60*60)
-
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "Email успешно подтверждён",
 		"nickname": account.Nickname,
@@ -130,7 +116,7 @@ func VerifyEmail(c *gin.Context) {
 
 // ResendVerificationCode godoc
 // @Summary Отправить код повторно
-// @Description Отправляет новый код подтверждения на email. Нужно если предыдущий код истек (они живут 10 минут) или потерялся. Генерирует новый код и сразу отправляет на почту
+// @Description Отправляет новый код подтверждения на email. Нужно если предыдущий код истек (они живут 10 минут) или потерялся. Генерирует новый код, письмо уходит в течение нескольких секунд
 // @Tags Аутентификация
 // @Accept json
 // @Produce json
@@ -147,21 +133,19 @@ func ResendVerificationCode(c *gin.Context) {
 		return
 	}
 
-	var verification models.EmailVerification
-	if err := database.DB.Where("email = ?", req.Email).First(&verification).Error; err != nil {
+	verification, err := Accounts.FindVerification(req.Email)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Email не найден или уже подтверждён"})
 		return
 	}
 
-	verification.Code = utils.GenerateVerificationCode()
-	verification.ExpiresAt = time.Now().Add(10 * time.Minute)
-
-	if err := database.DB.Save(&verification).Error; err != nil {
+	code, err := Accounts.StartVerification(verification.Email, verification.Nickname, verification.PasswordHash)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при обновлении кода подтверждения"})
 		return
 	}
 
-	if err := utils.SendVerificationEmail(req.Email, verification.Code); err != nil {
+	if err := Mail.Queue(mail.Verification, req.Email, mail.CodeData{Code: code, Lifetime: verificationCodeLifetime}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при отправке повторного email"})
 		return
 	}
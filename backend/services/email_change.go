@@ -0,0 +1,198 @@
+package services
+
+import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/mail"
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"arizonagamesstore/backend/utils"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrEmailTaken              = errors.New("email is already in use")
+	ErrEmailChangeNotFound     = errors.New("email change request not found or expired")
+	ErrEmailChangeCodeInvalid  = errors.New("email change code does not match")
+	ErrEmailChangeTooManyTries = errors.New("too many email change attempts")
+	ErrEmailUndoInvalid        = errors.New("email undo link is invalid or expired")
+)
+
+// EmailChangeService - смена email с подтверждением кодом на новый адрес и ссылкой отмены на старый
+type EmailChangeService struct {
+	changes repository.EmailChangeRepository
+	clock   clock.Clock
+}
+
+func NewEmailChangeService(changes repository.EmailChangeRepository, clk clock.Clock) *EmailChangeService {
+	return &EmailChangeService{changes: changes, clock: clk}
+}
+
+// Request запоминает новый адрес и отправляет на него код.
+// Сам email аккаунта не меняется, пока код не подтвержден
+func (s *EmailChangeService) Request(account *models.Account, newEmail string) error {
+	taken, err := Accounts.EmailExists(newEmail)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrEmailTaken
+	}
+
+	code := utils.GenerateVerificationCode()
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	// Новый запрос заменяет прошлый вместе со счетчиком попыток
+	now := s.clock.Now()
+	if err := s.changes.Replace(&models.EmailChange{
+		AccountID:        account.ID,
+		OldEmail:         account.Email,
+		OldEmailVerified: account.EmailVerified,
+		NewEmail:         newEmail,
+		CodeHash:         string(codeHash),
+		ExpiresAt:        now.Add(models.EmailChangeCodeLifetime),
+		CreatedAt:        now,
+	}); err != nil {
+		return err
+	}
+
+	return Mail.Queue(mail.EmailChangeCode, newEmail, mail.CodeData{Code: code, Lifetime: models.EmailChangeCodeLifetime})
+}
+
+// Confirm проверяет код и меняет email аккаунта. На старый адрес уходит
+// письмо со ссылкой для отмены. При неверном коде возвращает, сколько попыток осталось
+func (s *EmailChangeService) Confirm(accountID uint, code string) (*models.EmailChange, int, error) {
+	change, err := s.changes.FindPending(accountID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, 0, ErrEmailChangeNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if !change.ExpiresAt.After(s.clock.Now()) {
+		return nil, 0, ErrEmailChangeNotFound
+	}
+
+	// Попытка засчитывается до сравнения, чтобы параллельные запросы не перебрали лишние коды
+	attempts, err := s.changes.AddAttempt(change.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, 0, ErrEmailChangeNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if attempts > models.EmailChangeMaxAttempts {
+		return nil, 0, ErrEmailChangeTooManyTries
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(change.CodeHash), []byte(code)) != nil {
+		attemptsLeft := models.EmailChangeMaxAttempts - attempts
+		if attemptsLeft > 0 {
+			return nil, attemptsLeft, ErrEmailChangeCodeInvalid
+		}
+		if err := s.changes.Delete(change.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, 0, err
+		}
+		return nil, 0, ErrEmailChangeTooManyTries
+	}
+
+	// Пока ждали код, адрес мог занять кто-то другой
+	taken, err := Accounts.EmailExists(change.NewEmail)
+	if err != nil {
+		return nil, 0, err
+	}
+	if taken {
+		if err := s.changes.Delete(change.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, 0, err
+		}
+		return nil, 0, ErrEmailTaken
+	}
+
+	undoToken, err := generateUndoToken()
+	if err != nil {
+		return nil, 0, err
+	}
+	now := s.clock.Now()
+	undoExpiresAt := now.Add(models.EmailChangeUndoLifetime)
+
+	change.ConfirmedAt = &now
+	change.UndoTokenHash = hashUndoToken(undoToken)
+	change.UndoExpiresAt = &undoExpiresAt
+	if err := s.changes.Confirm(change, now); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, 0, ErrEmailChangeNotFound
+		case utils.IsDuplicateKeyError(err):
+			return nil, 0, ErrEmailTaken
+		}
+		return nil, 0, err
+	}
+
+	if change.OldEmail != "" {
+		notice := mail.EmailChangedData{NewEmail: change.NewEmail, UndoURL: emailUndoURL(undoToken)}
+		if err := Mail.Queue(mail.EmailChanged, change.OldEmail, notice); err != nil {
+			slog.Error("Не удалось поставить в очередь письмо о смене email на старый адрес", "account_id", accountID, "error", err)
+		}
+	}
+
+	return change, 0, nil
+}
+
+// Undo возвращает старый email по ссылке из письма и завершает все сессии:
+// раз смену отменяют, скорее всего аккаунт угнали
+func (s *EmailChangeService) Undo(token string) (*models.EmailChange, error) {
+	change, err := s.changes.FindByUndoToken(hashUndoToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrEmailUndoInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if change.UndoExpiresAt == nil || !change.UndoExpiresAt.After(s.clock.Now()) {
+		return nil, ErrEmailUndoInvalid
+	}
+
+	if err := s.changes.Undo(change); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, ErrEmailUndoInvalid
+		case utils.IsDuplicateKeyError(err):
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+
+	if _, err := Sessions.RevokeAll(change.AccountID, ""); err != nil {
+		slog.Error("Ошибка завершения сессий после отмены смены email", "account_id", change.AccountID, "error", err)
+	}
+
+	return change, nil
+}
+
+func generateUndoToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Токен длинный и случайный, так что хватает sha256 без соли
+func hashUndoToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func emailUndoURL(token string) string {
+	return strings.TrimRight(appURL, "/") + "/api/email-change/undo?token=" + url.QueryEscape(token)
+}
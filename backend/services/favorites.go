@@ -0,0 +1,157 @@
+package services
+
+import (
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"arizonagamesstore/backend/utils"
+	"errors"
+	"time"
+)
+
+var ErrAlreadyFavorite = errors.New("ad is already in favorites")
+
+// PriceChange - изменение цены объявления с момента добавления в избранное
+type PriceChange struct {
+	OldPrice  *int64    `json:"old_price"`
+	NewPrice  *int64    `json:"new_price"`
+	ChangedAt time.Time `json:"changed_at"`
+	Dropped   bool      `json:"dropped"`
+}
+
+type FavoriteAdResponse struct {
+	ID          uint                 `json:"id"`
+	AdID        int                  `json:"ad_id"`
+	CreatedAt   time.Time            `json:"created_at"`
+	PriceAtAdd  *int64               `json:"price_at_add,omitempty"`
+	PriceChange *PriceChange         `json:"price_change,omitempty"`
+	Ad          *models.AdWithAuthor `json:"Ad"`
+}
+
+// FavoriteService - избранные объявления пользователей
+type FavoriteService struct {
+	favorites repository.FavoriteRepository
+}
+
+func NewFavoriteService(favorites repository.FavoriteRepository) *FavoriteService {
+	return &FavoriteService{favorites: favorites}
+}
+
+func (s *FavoriteService) Add(nickname string, ad *models.Ad) (*models.FavoriteAd, error) {
+	favorite := models.FavoriteAd{
+		UserNickname: nickname,
+		AdID:         int(ad.ID),
+		PriceAtAdd:   ad.Price,
+	}
+
+	if err := s.favorites.Create(&favorite); err != nil {
+		if utils.IsDuplicateKeyError(err) {
+			return nil, ErrAlreadyFavorite
+		}
+		return nil, err
+	}
+
+	return &favorite, nil
+}
+
+func (s *FavoriteService) Remove(nickname string, adID int) (bool, error) {
+	return s.favorites.Delete(nickname, adID)
+}
+
+// List возвращает избранное пользователя вместе с объявлениями.
+// Если после добавления в избранное цена менялась, в ответе будет price_change
+func (s *FavoriteService) List(nickname string) ([]FavoriteAdResponse, error) {
+	favorites, err := s.favorites.List(nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]FavoriteAdResponse, 0, len(favorites))
+	if len(favorites) == 0 {
+		return response, nil
+	}
+
+	adIDs := make([]int, 0, len(favorites))
+	for _, favorite := range favorites {
+		adIDs = append(adIDs, favorite.AdID)
+	}
+
+	ids := make([]uint, 0, len(adIDs))
+	for _, id := range adIDs {
+		ids = append(ids, uint(id))
+	}
+	ads, err := Ads.WithAuthor(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	adsByID := make(map[int]*models.AdWithAuthor, len(ads))
+	for i := range ads {
+		adsByID[int(ads[i].ID)] = &ads[i]
+	}
+
+	lastChanges, err := s.favorites.LastPriceChanges(adIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	lastChangeByAd := make(map[int]models.AdPriceChange, len(lastChanges))
+	for _, change := range lastChanges {
+		lastChangeByAd[change.AdID] = change
+	}
+
+	for _, favorite := range favorites {
+		ad, ok := adsByID[favorite.AdID]
+		if !ok {
+			continue
+		}
+
+		item := FavoriteAdResponse{
+			ID:         favorite.ID,
+			AdID:       favorite.AdID,
+			CreatedAt:  favorite.CreatedAt,
+			PriceAtAdd: favorite.PriceAtAdd,
+			Ad:         ad,
+		}
+
+		if change, ok := lastChangeByAd[favorite.AdID]; ok && change.ChangedAt.After(favorite.CreatedAt) && !samePrice(favorite.PriceAtAdd, ad.Price) {
+			item.PriceChange = &PriceChange{
+				OldPrice:  favorite.PriceAtAdd,
+				NewPrice:  ad.Price,
+				ChangedAt: change.ChangedAt,
+				Dropped:   favorite.PriceAtAdd != nil && ad.Price != nil && *ad.Price < *favorite.PriceAtAdd,
+			}
+		}
+
+		response = append(response, item)
+	}
+
+	return response, nil
+}
+
+// RecordPriceChange сохраняет изменение цены, если объявление кто-то добавил в избранное
+func (s *FavoriteService) RecordPriceChange(adID uint, oldPrice *int64, newPrice *int64) error {
+	if samePrice(oldPrice, newPrice) {
+		return nil
+	}
+
+	favoritesCount, err := s.favorites.CountByAd(adID)
+	if err != nil {
+		return err
+	}
+	if favoritesCount == 0 {
+		return nil
+	}
+
+	return s.favorites.AddPriceChange(&models.AdPriceChange{
+		AdID:     int(adID),
+		OldPrice: oldPrice,
+		NewPrice: newPrice,
+	})
+}
+
+func samePrice(a *int64, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"errors"
+)
+
+var (
+	ErrFeedbackOwnAd    = errors.New("feedback on own ad")
+	ErrFeedbackExists   = errors.New("feedback already left")
+	ErrFeedbackNotFound = errors.New("feedback not found")
+	ErrFeedbackNotOwned = errors.New("feedback is about another seller")
+)
+
+// FeedbackService - отзывы о продавцах. Рейтинг из них считает ReputationService
+type FeedbackService struct {
+	feedback   repository.FeedbackRepository
+	ads        repository.AdRepository
+	reputation *ReputationService
+	clock      clock.Clock
+}
+
+func NewFeedbackService(feedback repository.FeedbackRepository, ads repository.AdRepository, reputation *ReputationService, clk clock.Clock) *FeedbackService {
+	return &FeedbackService{feedback: feedback, ads: ads, reputation: reputation, clock: clk}
+}
+
+// CheckCanReview проверяет, что reviewer может оставить отзыв на объявление,
+// и возвращает объявление. Вызывается до загрузки картинки-доказательства
+func (s *FeedbackService) CheckCanReview(adID uint, reviewer string) (*models.Ad, error) {
+	ad, err := s.ads.FindByID(adID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrAdNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ad.Nickname == reviewer {
+		return nil, ErrFeedbackOwnAd
+	}
+
+	exists, err := s.feedback.Exists(adID, reviewer)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrFeedbackExists
+	}
+
+	return ad, nil
+}
+
+// Create сохраняет отзыв неподтвержденным: в профиле он появится, когда продавец его подтвердит
+func (s *FeedbackService) Create(ad *models.Ad, reviewer string, rating int, text string, proofImage string) (*models.FeedbackAd, error) {
+	feedback := models.FeedbackAd{
+		AdID:             int(ad.ID),
+		ReviewerNickname: reviewer,
+		AdOwnerNickname:  ad.Nickname,
+		Rating:           rating,
+		ReviewText:       text,
+		ProofImage:       proofImage,
+		ConfirmFeedback:  false,
+		CreatedAt:        s.clock.Now(),
+	}
+
+	if err := s.feedback.Create(&feedback); err != nil {
+		return nil, err
+	}
+	return &feedback, nil
+}
+
+// Confirm подтверждает отзыв о продавце nickname. Отзыв и рейтинг меняются одной транзакцией:
+// если репутацию обновить не вышло, отзыв остается неподтвержденным
+func (s *FeedbackService) Confirm(id uint, nickname string) (*models.FeedbackAd, error) {
+	feedback, err := s.feedback.FindByID(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrFeedbackNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if feedback.AdOwnerNickname != nickname {
+		return nil, ErrFeedbackNotOwned
+	}
+
+	if feedback.ConfirmFeedback {
+		return nil, ErrFeedbackConfirmed
+	}
+
+	if err := s.reputation.Confirm(feedback); err != nil {
+		return nil, err
+	}
+	return feedback, nil
+}
+
+// ListByOwner возвращает подтвержденные отзывы о продавце, сверху новые
+func (s *FeedbackService) ListByOwner(ownerNickname string, req PageRequest) ([]models.FeedbackWithReviewer, *Page, error) {
+	return s.feedback.ListConfirmed(ownerNickname, req)
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"arizonagamesstore/backend/scheduler"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RegisterJobs ставит фоновые задачи сервисов в планировщик
+func RegisterJobs(s *scheduler.Scheduler) error {
+	jobs := []scheduler.Job{
+		{
+			Name:       "expire-ads",
+			Schedule:   scheduler.Every(time.Hour),
+			Run:        Ads.ExpireOld,
+			RunOnStart: true,
+			Timeout:    10 * time.Minute,
+		},
+		{
+			// Счетчики категорий меняются по ходу работы, а раз в сутки сверяем их с таблицей ads
+			Name:       "recalculate-statistics",
+			Schedule:   scheduler.MustCron("0 4 * * *"),
+			Run:        Ads.RecalculateStatistics,
+			RunOnStart: true,
+			Timeout:    5 * time.Minute,
+		},
+		{
+			Name:     "prune-refresh-tokens",
+			Schedule: scheduler.Every(6 * time.Hour),
+			Run: func(ctx context.Context) error {
+				deleted, err := Sessions.Prune(ctx)
+				if err == nil && deleted > 0 {
+					slog.InfoContext(ctx, "Удалены истекшие refresh токены", "count", deleted)
+				}
+				return err
+			},
+			Timeout: 5 * time.Minute,
+		},
+		{
+			// Старые отзывы теряют вес и без новых, поэтому рейтинги раз в сутки пересчитываются на сегодня
+			Name:       "refresh-reputation",
+			Schedule:   scheduler.MustCron("30 4 * * *"),
+			Run:        Reputation.Refresh,
+			RunOnStart: true,
+			Timeout:    30 * time.Minute,
+		},
+		{
+			// Коды из писем живут 10 минут, поэтому очередь разбираем часто
+			Name:       "send-emails",
+			Schedule:   scheduler.Every(10 * time.Second),
+			Run:        Mail.ProcessOutbox,
+			RunOnStart: true,
+			Timeout:    2 * time.Minute,
+		},
+		{
+			Name:     "prune-email-outbox",
+			Schedule: scheduler.MustCron("15 5 * * *"),
+			Run: func(ctx context.Context) error {
+				deleted, err := Mail.Prune(ctx)
+				if err == nil && deleted > 0 {
+					slog.InfoContext(ctx, "Удалены старые письма из очереди", "count", deleted)
+				}
+				return err
+			},
+			Timeout: 10 * time.Minute,
+		},
+	}
+
+	for _, job := range jobs {
+		if err := s.Add(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,9 +1,11 @@
 package services
 
 import (
-	"arizonagamesstore/backend/database"
 	"arizonagamesstore/backend/models"
 	"arizonagamesstore/backend/utils"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -18,9 +20,15 @@ type LoginRequest struct {
 	ClientIP       string `json:"client_ip"`
 }
 
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+	ClientIP       string `json:"client_ip"`
+}
+
 // Login godoc
 // @Summary Вход в аккаунт
-// @Description Авторизация пользователя. Возвращает JWT токены (access для запросов + refresh для продления сессии). Токены сохраняются в HTTP-only cookies для безопасности
+// @Description Авторизация пользователя. Возвращает JWT токены (access для запросов + refresh для продления сессии). Токены сохраняются в HTTP-only cookies для безопасности. Если у аккаунта включена 2FA, cookies не ставятся: в ответе status=2fa_required и challenge_token для /login/2fa
 // @Tags Аутентификация
 // @Accept json
 // @Produce json
@@ -51,54 +59,119 @@ func Login(c *gin.Context) {
 		}
 	}
 
-	var account models.Account
-	result := database.DB.Where("nickname = ?", req.Nickname).First(&account)
-
-	if result.Error != nil {
+	account, err := Accounts.GetByNickname(req.Nickname)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный никнейм или пароль"})
 		return
 	}
 
-	err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(req.Password))
+	err = bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(req.Password))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный никнейм или пароль"})
 		return
 	}
 
-	accessToken, err := utils.GenerateAccessToken(account.ID, account.Nickname)
+	twoFactor, err := TwoFactor.Enabled(account.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка генерации токена"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки 2FA"})
+		return
+	}
+
+	// С включенной 2FA cookies не ставим, пока не введут код через /login/2fa
+	if twoFactor {
+		challenge, err := utils.GenerateTwoFactorChallenge(account.ID, models.TwoFactorChallengeLifetime)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка генерации токена"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":          "2fa_required",
+			"message":         "Введите код из приложения-аутентификатора или резервный код",
+			"challenge_token": challenge,
+			"expires_in":      int(models.TwoFactorChallengeLifetime / time.Second),
+		})
+		return
+	}
+
+	completeLogin(c, account, req.ClientIP)
+}
+
+// LoginTwoFactor godoc
+// @Summary Второй шаг входа (2FA)
+// @Description Если /login ответил status=2fa_required, сюда отправляешь challenge_token оттуда и код из приложения (6 цифр) или один из резервных кодов. Challenge живет 5 минут, после 5 неверных кодов сгорает и нужно снова войти с паролем. После успеха ставятся cookies, как при обычном входе
+// @Tags Аутентификация
+// @Accept json
+// @Produce json
+// @Param request body LoginTwoFactorRequest true "Challenge токен и код"
+// @Success 200 {object} map[string]interface{} "Авторизация успешна"
+// @Failure 400 {object} map[string]string "Не хватает данных"
+// @Failure 401 {object} map[string]interface{} "Неверный код (attempts_left - сколько попыток осталось), challenge истек или сгорел"
+// @Failure 429 {object} map[string]string "Слишком много попыток"
+// @Failure 500 {object} map[string]string "Ошибка сервера"
+// @Router /login/2fa [post]
+func LoginTwoFactor(c *gin.Context) {
+	var req LoginTwoFactorRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат. Укажите challenge_token и код."})
 		return
 	}
 
-	refreshToken, err := utils.GenerateRefreshToken(account.ID, account.Nickname)
+	claims, err := utils.ValidateTwoFactorChallenge(req.ChallengeToken)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка генерации токена"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Время на ввод кода истекло, войдите заново"})
 		return
 	}
 
-	tokenRecord := models.RefreshToken{
-		AccountID: account.ID,
-		Token:     refreshToken,
-		ExpiresAt: time.Now().Add(30 * 24 * time.Hour),
+	account, err := Accounts.GetByID(claims.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Время на ввод кода истекло, войдите заново"})
+		return
 	}
 
-	if err := database.DB.Create(&tokenRecord).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения токена"})
+	// Без лимита на challenge код можно было бы подбирать все 5 минут его жизни
+	attemptsLeft, err := TwoFactor.VerifyChallenge(account.ID, claims.ID, claims.ExpiresAt.Time, req.Code)
+	switch {
+	case errors.Is(err, ErrTwoFactorChallengeSpent):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Слишком много неверных кодов, войдите заново"})
+		return
+	case errors.Is(err, ErrTwoFactorCodeInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         fmt.Sprintf("Неверный код. Осталось попыток: %d", attemptsLeft),
+			"attempts_left": attemptsLeft,
+		})
+		return
+	case errors.Is(err, ErrTwoFactorNotEnabled):
+		// 2FA успели выключить, пока шел вход. Пароль уже проверен, пускаем
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки кода"})
+		return
+	}
+
+	completeLogin(c, account, req.ClientIP)
+}
+
+// completeLogin выдает токены, ставит cookies и запоминает IP входа
+func completeLogin(c *gin.Context, account *models.Account, clientIP string) {
+	accessToken, err := utils.GenerateAccessToken(account.ID, account.Nickname, models.NormalizeRole(account.UserRole))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка генерации токена"})
 		return
 	}
 
-	utils.SetAuthCookie(c, "access_token", accessToken, 180)
-	utils.SetAuthCookie(c, "refresh_token", refreshToken, 30*24*60*60)
+	if err := Sessions.Start(c, account, accessToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения токена"})
+		return
+	}
 
-	clientIP := req.ClientIP
 	if clientIP == "" {
 		clientIP = c.ClientIP()
 	}
 
 	if clientIP != "" {
-		if err := UpdateLastIP(account.Nickname, clientIP); err != nil {
-			println("Предупреждение: не удалось обновить last_ip:", err.Error())
+		if err := Accounts.UpdateLastIP(account.Nickname, clientIP); err != nil {
+			slog.WarnContext(c.Request.Context(), "Не удалось обновить last_ip", "user", account.Nickname, "error", err)
 		}
 	}
 
@@ -111,7 +184,7 @@ func Login(c *gin.Context) {
 
 // RefreshAccessToken godoc
 // @Summary Обновить токен
-// @Description Обновляет access токен используя refresh токен. Вызывай этот эндпоинт когда access токен истек (обычно через 3 минуты). Refresh токен живет 30 дней
+// @Description Обновляет access токен используя refresh токен. Вызывай этот эндпоинт когда access токен истек (обычно через 3 минуты). Refresh токен каждый раз меняется на новый и живет 30 дней с последнего обновления. Если предъявить уже замененный токен, сессия считается украденной и завершается целиком
 // @Tags Аутентификация
 // @Produce json
 // @Success 200 {object} map[string]string "Токен обновлен! Можешь продолжать работать"
@@ -125,35 +198,35 @@ func RefreshAccessToken(c *gin.Context) {
 		return
 	}
 
-	claims, err := utils.ValidateRefreshToken(refreshToken)
+	result, err := Sessions.Rotate(refreshToken, c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Невалидный refresh токен"})
-		return
-	}
-
-	var storedToken models.RefreshToken
-	result := database.DB.Where("token = ? AND account_id = ? AND expires_at > ?",
-		refreshToken, claims.UserID, time.Now()).First(&storedToken)
-
-	if result.Error != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh токен не найден или истек"})
+		switch {
+		case errors.Is(err, ErrRefreshTokenReused):
+			utils.SetAuthCookie(c, "access_token", "", -1)
+			utils.SetAuthCookie(c, "refresh_token", "", -1)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Этот refresh токен уже использовался. Сессия завершена, войди заново"})
+		case errors.Is(err, ErrRefreshTokenInvalid):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh токен не найден или истек"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления токена"})
+		}
 		return
 	}
 
-	newAccessToken, err := utils.GenerateAccessToken(claims.UserID, claims.Nickname)
+	newAccessToken, err := utils.GenerateAccessToken(result.AccountID, result.Nickname, result.Role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка генерации токена"})
 		return
 	}
 
-	utils.SetAuthCookie(c, "access_token", newAccessToken, 180)
+	SetRefreshedCookies(c, newAccessToken, result)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Токен обновлен"})
 }
 
 // Logout godoc
 // @Summary Выход
-// @Description Выход из аккаунта. Удаляет refresh токен из БД и чистит cookies. После этого все запросы будут отклонены, пока не залогинишься заново
+// @Description Выход из аккаунта. Завершает текущую сессию (все ее refresh токены) и чистит cookies. После этого все запросы будут отклонены, пока не залогинишься заново
 // @Tags Аутентификация
 // @Produce json
 // @Success 200 {object} map[string]string "Успешный выход! До встречи"
@@ -162,7 +235,9 @@ func Logout(c *gin.Context) {
 	refreshToken, _ := c.Cookie("refresh_token")
 
 	if refreshToken != "" {
-		database.DB.Where("token = ?", refreshToken).Delete(&models.RefreshToken{})
+		if err := Sessions.RevokeByToken(refreshToken); err != nil {
+			slog.WarnContext(c.Request.Context(), "Не удалось завершить сессию", "error", err)
+		}
 	}
 
 	utils.SetAuthCookie(c, "access_token", "", -1)
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/mail"
+	"arizonagamesstore/backend/metrics"
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	outboxBatch = 20
+	// outboxLease - на сколько письмо пропадает из выборки, пока его отправляют
+	outboxLease = 5 * time.Minute
+	// После outboxMaxAttempts неудач письмо уходит в dead. Задержки от outboxRetryBase
+	// удваиваются, так что письмо пытаемся отправить часа четыре
+	outboxMaxAttempts = 10
+	outboxRetryBase   = 30 * time.Second
+	outboxRetryMax    = 3 * time.Hour
+
+	// outboxRetention - сколько держим отправленные и dead письма. Тело у них уже стерто,
+	// остаются адрес, тема и last_error - этого хватает, чтобы разобраться с жалобой
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// MailService ставит письма в очередь email_outbox и разбирает ее.
+// В запросе письмо только рендерится и сохраняется, до SMTP дело доходит в фоне
+type MailService struct {
+	outbox repository.OutboxRepository
+	sender mail.Sender
+	clock  clock.Clock
+}
+
+func NewMailService(outbox repository.OutboxRepository, sender mail.Sender, clk clock.Clock) *MailService {
+	return &MailService{outbox: outbox, sender: sender, clock: clk}
+}
+
+// Queue рендерит письмо по шаблону и кладет в очередь. Ошибка - только если
+// письмо не удалось собрать или сохранить, про доставку Queue ничего не знает
+func (s *MailService) Queue(template mail.Template, to string, data interface{}) error {
+	msg, err := mail.Render(template, to, data)
+	if err != nil {
+		return err
+	}
+
+	return s.outbox.Enqueue(&models.OutboxEmail{
+		Template:      string(template),
+		Recipient:     msg.To,
+		Subject:       msg.Subject,
+		HTML:          msg.HTML,
+		Text:          msg.Text,
+		Status:        models.OutboxPending,
+		NextAttemptAt: s.clock.Now(),
+		CreatedAt:     s.clock.Now(),
+	})
+}
+
+// Prune удаляет из очереди письма, которые ушли или умерли больше outboxRetention назад
+func (s *MailService) Prune(ctx context.Context) (int64, error) {
+	return s.outbox.DeleteFinished(ctx, s.clock.Now().Add(-outboxRetention))
+}
+
+// ProcessOutbox отправляет письма, которым подошло время. Неудачная отправка
+// откладывается с растущей задержкой, после outboxMaxAttempts письмо уходит в dead
+func (s *MailService) ProcessOutbox(ctx context.Context) error {
+	for {
+		emails, err := s.outbox.ClaimDue(ctx, s.clock.Now(), outboxLease, outboxBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, email := range emails {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := s.deliver(ctx, email); err != nil {
+				return err
+			}
+		}
+
+		if len(emails) < outboxBatch {
+			return nil
+		}
+	}
+}
+
+// deliver пробует отправить одно письмо. Возвращает только ошибки БД:
+// ошибка SMTP - обычное дело, она записывается в само письмо
+func (s *MailService) deliver(ctx context.Context, email models.OutboxEmail) error {
+	sendErr := s.sender.Send(ctx, mail.Message{
+		To:      email.Recipient,
+		Subject: email.Subject,
+		HTML:    email.HTML,
+		Text:    email.Text,
+	})
+	if sendErr == nil {
+		metrics.EmailsProcessed.WithLabelValues(email.Template, "sent").Inc()
+		return s.outbox.MarkSent(email.ID, s.clock.Now())
+	}
+
+	attempts := email.Attempts + 1
+	if attempts >= outboxMaxAttempts {
+		metrics.EmailsProcessed.WithLabelValues(email.Template, "dead").Inc()
+		slog.ErrorContext(ctx, "Письмо не отправлено за все попытки",
+			"outbox_id", email.ID, "template", email.Template, "attempts", attempts, "error", sendErr)
+		return s.outbox.MarkDead(email.ID, attempts, sendErr.Error())
+	}
+
+	delay := outboxRetryDelay(attempts)
+	metrics.EmailsProcessed.WithLabelValues(email.Template, "retry").Inc()
+	slog.WarnContext(ctx, "Ошибка отправки письма, повторим позже",
+		"outbox_id", email.ID, "template", email.Template, "attempts", attempts, "retry_in", delay, "error", sendErr)
+	return s.outbox.MarkFailed(email.ID, attempts, s.clock.Now().Add(delay), sendErr.Error())
+}
+
+// outboxRetryDelay - задержка перед попыткой номер attempts+1: 30s, 1m, 2m, 4m... но не больше outboxRetryMax
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := outboxRetryBase
+	for i := 1; i < attempts && delay < outboxRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > outboxRetryMax {
+		delay = outboxRetryMax
+	}
+	return delay
+}
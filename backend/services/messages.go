@@ -0,0 +1,247 @@
+package services
+
+import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/events"
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"arizonagamesstore/backend/utils"
+	"errors"
+)
+
+var (
+	ErrConversationNotFound  = errors.New("conversation not found")
+	ErrNotConversationMember = errors.New("user is not a member of the conversation")
+	ErrMessageOwnAd          = errors.New("cannot start a conversation on own ad")
+	ErrAdNotAvailable        = errors.New("ad is not available for messaging")
+	ErrUserBlocked           = errors.New("user is blocked")
+	ErrAlreadyBlocked        = errors.New("user is already blocked")
+)
+
+// ConversationWithMessages - переписка целиком, для модераторов
+type ConversationWithMessages struct {
+	Conversation models.Conversation `json:"conversation"`
+	Messages     []models.Message    `json:"messages"`
+}
+
+// MessageService - переписка покупателей с продавцами по объявлениям
+type MessageService struct {
+	messages repository.MessageRepository
+	ads      repository.AdRepository
+	reports  repository.ReportRepository
+	clock    clock.Clock
+}
+
+func NewMessageService(messages repository.MessageRepository, ads repository.AdRepository, reports repository.ReportRepository, clk clock.Clock) *MessageService {
+	return &MessageService{messages: messages, ads: ads, reports: reports, clock: clk}
+}
+
+// Start открывает переписку по объявлению или возвращает уже существующую.
+// Второй результат true, если переписка создана только что
+func (s *MessageService) Start(adID uint, buyer string) (*models.Conversation, bool, error) {
+	ad, err := s.ads.FindByID(adID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, false, ErrAdNotFound
+		}
+		return nil, false, err
+	}
+
+	if ad.Nickname == buyer {
+		return nil, false, ErrMessageOwnAd
+	}
+
+	conversation, err := s.messages.FindConversationByAd(ad.ID, buyer)
+	if err == nil {
+		return conversation, false, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, false, err
+	}
+
+	// Новую переписку можно начать только по активному объявлению
+	if ad.Status != models.AdStatusActive {
+		return nil, false, ErrAdNotAvailable
+	}
+
+	blocked, err := s.messages.Blocked(ad.Nickname, buyer)
+	if err != nil {
+		return nil, false, err
+	}
+	if blocked {
+		return nil, false, ErrUserBlocked
+	}
+
+	conversation = &models.Conversation{
+		AdID:           ad.ID,
+		SellerNickname: ad.Nickname,
+		BuyerNickname:  buyer,
+		LastMessageAt:  s.clock.Now(),
+	}
+
+	if err := s.messages.CreateConversation(conversation); err != nil {
+		// Две вкладки нажали "Написать" одновременно
+		if utils.IsDuplicateKeyError(err) {
+			conversation, err := s.messages.FindConversationByAd(ad.ID, buyer)
+			if err != nil {
+				return nil, false, err
+			}
+			return conversation, false, nil
+		}
+		return nil, false, err
+	}
+
+	return conversation, true, nil
+}
+
+// GetForMember возвращает переписку, если пользователь в ней участвует
+func (s *MessageService) GetForMember(conversationID uint, nickname string) (*models.Conversation, error) {
+	conversation, err := s.messages.FindConversation(conversationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrConversationNotFound
+		}
+		return nil, err
+	}
+
+	if !conversation.HasMember(nickname) {
+		return nil, ErrNotConversationMember
+	}
+
+	return conversation, nil
+}
+
+// Send добавляет сообщение в переписку. Если кто-то из участников
+// заблокировал другого, сообщение не отправляется
+func (s *MessageService) Send(conversationID uint, sender string, body string) (*models.Message, error) {
+	conversation, err := s.GetForMember(conversationID, sender)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := s.messages.Blocked(conversation.SellerNickname, conversation.BuyerNickname)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrUserBlocked
+	}
+
+	message := models.Message{
+		ConversationID: conversation.ID,
+		SenderNickname: sender,
+		Body:           body,
+		CreatedAt:      s.clock.Now(),
+	}
+	if err := s.messages.AddMessage(&message); err != nil {
+		return nil, err
+	}
+
+	events.Publish(conversation.Companion(sender), events.TypeMessageNew, map[string]interface{}{
+		"conversation_id": conversation.ID,
+		"ad_id":           conversation.AdID,
+		"message":         message,
+	})
+
+	return &message, nil
+}
+
+// List возвращает все переписки пользователя, сверху самые свежие
+func (s *MessageService) List(nickname string) ([]models.ConversationSummary, error) {
+	return s.messages.ListConversations(nickname)
+}
+
+// CountUnread считает непрочитанные сообщения во всех переписках пользователя
+func (s *MessageService) CountUnread(nickname string) (int64, error) {
+	return s.messages.CountUnread(nickname)
+}
+
+// History возвращает сообщения переписки от старых к новым.
+// beforeID позволяет подгружать историю порциями (0 - последние сообщения)
+func (s *MessageService) History(conversationID uint, limit int, beforeID uint) ([]models.Message, error) {
+	return s.messages.ListMessages(conversationID, limit, beforeID)
+}
+
+// MarkRead помечает прочитанными все входящие сообщения переписки
+func (s *MessageService) MarkRead(conversationID uint, nickname string) (int64, error) {
+	conversation, err := s.GetForMember(conversationID, nickname)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.messages.MarkRead(conversation.ID, nickname, s.clock.Now())
+}
+
+func (s *MessageService) Block(blocker string, blocked string) error {
+	err := s.messages.Block(&models.UserBlock{
+		BlockerNickname: blocker,
+		BlockedNickname: blocked,
+	})
+	if utils.IsDuplicateKeyError(err) {
+		return ErrAlreadyBlocked
+	}
+	return err
+}
+
+func (s *MessageService) Unblock(blocker string, blocked string) (bool, error) {
+	return s.messages.Unblock(blocker, blocked)
+}
+
+func (s *MessageService) BlockedUsers(blocker string) ([]models.UserBlock, error) {
+	return s.messages.ListBlocked(blocker)
+}
+
+// ReportConversations возвращает всю переписку по объявлению, на которое
+// пожаловались. Модератор видит сообщения только в рамках разбора жалобы
+func (s *MessageService) ReportConversations(reportID uint) (*models.Report, []ConversationWithMessages, error) {
+	report, err := s.reports.FindByID(reportID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil, ErrReportNotFound
+		}
+		return nil, nil, err
+	}
+
+	conversations, err := s.messages.ListConversationsByAd(report.AdID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := make([]ConversationWithMessages, 0, len(conversations))
+	if len(conversations) == 0 {
+		return report, result, nil
+	}
+
+	conversationIDs := make([]uint, 0, len(conversations))
+	for _, conversation := range conversations {
+		conversationIDs = append(conversationIDs, conversation.ID)
+	}
+
+	messages, err := s.messages.MessagesOf(conversationIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	messagesByConversation := make(map[uint][]models.Message)
+	for _, message := range messages {
+		messagesByConversation[message.ConversationID] = append(messagesByConversation[message.ConversationID], message)
+	}
+
+	for _, conversation := range conversations {
+		conversationMessages := messagesByConversation[conversation.ID]
+		if conversationMessages == nil {
+			conversationMessages = []models.Message{}
+		}
+		result = append(result, ConversationWithMessages{
+			Conversation: conversation,
+			Messages:     conversationMessages,
+		})
+	}
+
+	return report, result, nil
+}
+
+// RenameUser переносит переписки и блокировки на новый никнейм
+func (s *MessageService) RenameUser(oldNickname string, newNickname string) error {
+	return s.messages.RenameUser(oldNickname, newNickname)
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"arizonagamesstore/backend/events"
+	"arizonagamesstore/backend/mail"
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+var (
+	ErrReportNotFound        = errors.New("report not found")
+	ErrReportAlreadyResolved = errors.New("report already resolved")
+	ErrAdNotFound            = errors.New("ad not found")
+)
+
+// GetQueue возвращает жалобы, сгруппированные по объявлениям.
+// Сверху объявления с наибольшим количеством жалоб
+func (s *ReportService) GetQueue(status string, limit int, offset int) ([]models.ReportedAd, error) {
+	queue, err := s.reports.Queue(status, limit, offset)
+	if err != nil || len(queue) == 0 {
+		return queue, err
+	}
+
+	adIDs := make([]uint, 0, len(queue))
+	for _, item := range queue {
+		adIDs = append(adIDs, item.AdID)
+	}
+
+	ads, err := s.ads.FindWithAuthor(adIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	adsByID := make(map[uint]*models.AdWithAuthor, len(ads))
+	for i := range ads {
+		adsByID[ads[i].ID] = &ads[i]
+	}
+	for i := range queue {
+		queue[i].Ad = adsByID[queue[i].AdID]
+	}
+
+	return queue, nil
+}
+
+func (s *ReportService) GetDecisions(adID uint, moderator string, limit int, offset int) ([]models.ModerationDecision, error) {
+	return s.reports.Decisions(adID, moderator, limit, offset)
+}
+
+// Dismiss отклоняет одну жалобу, объявление остается как есть
+func (s *ReportService) Dismiss(reportID uint, moderator string, comment *string) (*models.ModerationDecision, error) {
+	report, err := s.reports.FindByID(reportID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrReportNotFound
+		}
+		return nil, err
+	}
+
+	if report.Status != models.ReportStatusPending {
+		return nil, ErrReportAlreadyResolved
+	}
+
+	// Объявление могли уже удалить, тогда решение запишется без владельца
+	ownerNickname := ""
+	ad, err := s.ads.FindByID(report.AdID)
+	if err == nil {
+		ownerNickname = ad.Nickname
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	decision := models.ModerationDecision{
+		AdID:              report.AdID,
+		ReportID:          &report.ID,
+		AdOwnerNickname:   ownerNickname,
+		ModeratorNickname: moderator,
+		Action:            models.ModerationActionDismiss,
+		Comment:           comment,
+	}
+	if err := s.reports.Dismiss(&decision, s.clock.Now()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrReportAlreadyResolved
+		}
+		return nil, err
+	}
+
+	Notify(report.ReporterNickname, events.TypeReportResolved, models.NotificationPayload{
+		"report_id": report.ID,
+		"ad_id":     report.AdID,
+		"status":    models.ReportStatusDismissed,
+	})
+
+	return &decision, nil
+}
+
+// ApplyAdDecision применяет решение (hide/delete/warn) к объявлению и закрывает
+// все открытые жалобы на него. Возвращает объявление в состоянии до решения,
+// чтобы вызывающий код мог поправить статистику.
+// Удаленное модератором объявление уходит в архив, а не из базы: иначе каскадом
+// пропадут отзывы о продавце, которые уже учтены в его рейтинге
+func (s *ReportService) ApplyAdDecision(adID uint, action string, moderator string, comment *string) (*models.Ad, *models.ModerationDecision, error) {
+	ad, err := s.ads.FindByID(adID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil, ErrAdNotFound
+		}
+		return nil, nil, err
+	}
+
+	now := s.clock.Now()
+	var adFields map[string]interface{}
+	if status, ok := decisionStatuses[action]; ok {
+		adFields = map[string]interface{}{
+			"status":            status,
+			"status_changed_at": now,
+		}
+	}
+
+	decision := models.ModerationDecision{
+		AdID:              ad.ID,
+		AdOwnerNickname:   ad.Nickname,
+		ModeratorNickname: moderator,
+		Action:            action,
+		Comment:           comment,
+	}
+	resolvedReports, err := s.reports.Resolve(&decision, adFields, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	Notify(ad.Nickname, events.TypeReportVerdict, models.NotificationPayload{
+		"ad_id":       ad.ID,
+		"title":       ad.Title,
+		"action":      decision.Action,
+		"comment":     decision.Comment,
+		"decision_id": decision.ID,
+	})
+	mailModerationVerdict(*ad, decision)
+
+	for _, report := range resolvedReports {
+		Notify(report.ReporterNickname, events.TypeReportResolved, models.NotificationPayload{
+			"report_id": report.ID,
+			"ad_id":     ad.ID,
+			"status":    models.ReportStatusResolved,
+			"action":    decision.Action,
+		})
+	}
+
+	return ad, &decision, nil
+}
+
+// decisionStatuses - в какой статус решение модерации переводит объявление.
+// Из hidden и archived продавец сам объявление уже не вернет
+var decisionStatuses = map[string]string{
+	models.ModerationActionHide:   models.AdStatusHidden,
+	models.ModerationActionDelete: models.AdStatusArchived,
+}
+
+// verdictTexts - что написать продавцу в письме о решении модерации
+var verdictTexts = map[string]string{
+	models.ModerationActionHide:   "Ваше объявление «%s» скрыто модератором по жалобам пользователей.",
+	models.ModerationActionDelete: "Ваше объявление «%s» удалено модератором по жалобам пользователей.",
+	models.ModerationActionWarn:   "На ваше объявление «%s» поступили жалобы, модератор вынес предупреждение.",
+}
+
+// mailModerationVerdict дублирует решение письмом: продавец может неделями не заходить
+// на сайт и не узнать, почему объявление пропало
+func mailModerationVerdict(ad models.Ad, decision models.ModerationDecision) {
+	text, ok := verdictTexts[decision.Action]
+	if !ok {
+		return
+	}
+	text = fmt.Sprintf(text, ad.Title)
+	if decision.Comment != nil && *decision.Comment != "" {
+		text += " Комментарий модератора: " + *decision.Comment
+	}
+
+	account, err := Accounts.GetByNickname(ad.Nickname)
+	if err != nil {
+		slog.Warn("Не нашли продавца для письма о решении модерации", "user", ad.Nickname, "error", err)
+		return
+	}
+	if err := Mail.Queue(mail.Notification, account.Email, mail.NotificationData{Title: "Решение модерации", Text: text}); err != nil {
+		slog.Error("Не удалось поставить в очередь письмо о решении модерации", "user", ad.Nickname, "error", err)
+	}
+}
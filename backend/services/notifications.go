@@ -0,0 +1,65 @@
+package services
+
+import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/events"
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"errors"
+	"log/slog"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// NotificationService - уведомления пользователя: запись, список и отметки о прочтении
+type NotificationService struct {
+	notifications repository.NotificationRepository
+	clock         clock.Clock
+}
+
+func NewNotificationService(notifications repository.NotificationRepository, clk clock.Clock) *NotificationService {
+	return &NotificationService{notifications: notifications, clock: clk}
+}
+
+// Notify сохраняет уведомление и сразу отправляет его в поток событий.
+// Ошибка записи только логируется: уведомление не должно ломать основное действие
+func Notify(nickname string, notificationType string, payload models.NotificationPayload) {
+	notification := models.Notification{
+		UserNickname: nickname,
+		Type:         notificationType,
+		Payload:      payload,
+	}
+
+	if err := Notifications.notifications.Create(&notification); err != nil {
+		slog.Error("Ошибка сохранения уведомления", "type", notificationType, "user", nickname, "error", err)
+		events.Publish(nickname, notificationType, payload)
+		return
+	}
+
+	events.Publish(nickname, notificationType, notification)
+}
+
+func (s *NotificationService) List(nickname string, unreadOnly bool, limit int, offset int) ([]models.Notification, int64, error) {
+	return s.notifications.List(nickname, unreadOnly, limit, offset)
+}
+
+func (s *NotificationService) CountUnread(nickname string) (int64, error) {
+	return s.notifications.CountUnread(nickname)
+}
+
+// MarkRead помечает одно уведомление прочитанным. Повторный вызов не ошибка
+func (s *NotificationService) MarkRead(notificationID uint, nickname string) error {
+	err := s.notifications.MarkRead(notificationID, nickname, s.clock.Now())
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrNotificationNotFound
+	}
+	return err
+}
+
+func (s *NotificationService) MarkAllRead(nickname string) (int64, error) {
+	return s.notifications.MarkAllRead(nickname, s.clock.Now())
+}
+
+func (s *NotificationService) RenameUser(oldNickname string, newNickname string) error {
+	return s.notifications.RenameUser(oldNickname, newNickname)
+}
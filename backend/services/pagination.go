@@ -0,0 +1,16 @@
+package services
+
+import "arizonagamesstore/backend/repository"
+
+// Пагинация по ключу живет в repository рядом с запросами, здесь только имена для handlers
+type (
+	PageRequest = repository.PageRequest
+	Page        = repository.Page
+)
+
+var ErrInvalidCursor = repository.ErrInvalidCursor
+
+const (
+	DefaultPageSize = repository.DefaultPageSize
+	MaxPageSize     = repository.MaxPageSize
+)
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/mail"
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"arizonagamesstore/backend/utils"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrResetCodeInvalid  = errors.New("reset code is invalid or expired")
+	ErrResetCodeMismatch = errors.New("reset code does not match")
+	ErrResetTooManyTries = errors.New("too many reset attempts")
+)
+
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Email           string `json:"email" binding:"required,email"`
+	Code            string `json:"code" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+	ConfirmPassword string `json:"confirm_password" binding:"required"`
+}
+
+// RequestPasswordReset godoc
+// @Summary Забыл пароль
+// @Description Отправляет на почту код для сброса пароля. Ответ всегда одинаковый, чтобы нельзя было проверить, есть ли такой email у нас. Код живет 10 минут, новый можно запросить раз в минуту
+// @Tags Аутентификация
+// @Accept json
+// @Produce json
+// @Param request body PasswordResetRequest true "Email аккаунта"
+// @Success 200 {object} map[string]string "Если такой email есть, код уже в пути"
+// @Failure 400 {object} map[string]string "Неверный формат email"
+// @Failure 429 {object} map[string]string "Слишком много попыток"
+// @Failure 500 {object} map[string]string "Ошибка отправки письма"
+// @Router /password-reset/request [post]
+func RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат. Укажите email."})
+		return
+	}
+
+	response := gin.H{"message": "Если аккаунт с таким email существует, мы отправили на него код для сброса пароля"}
+
+	account, err := Accounts.GetByEmail(req.Email)
+	if err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	code, err := PasswordResets.Start(account.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании кода"})
+		return
+	}
+	if code == "" {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if err := Mail.Queue(mail.PasswordReset, account.Email, mail.CodeData{Code: code, Lifetime: models.PasswordResetLifetime}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при отправке письма"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ConfirmPasswordReset godoc
+// @Summary Сбросить пароль по коду
+// @Description Меняет пароль по коду из письма. На один код дается 5 попыток, потом нужно запросить новый. После сброса все сессии завершаются, войти придется заново
+// @Tags Аутентификация
+// @Accept json
+// @Produce json
+// @Param request body PasswordResetConfirmRequest true "Email, код и новый пароль"
+// @Success 200 {object} map[string]string "Пароль изменен"
+// @Failure 400 {object} map[string]string "Неверный код, он истек или пароль не подходит"
+// @Failure 429 {object} map[string]string "Слишком много попыток"
+// @Failure 500 {object} map[string]string "Ошибка смены пароля"
+// @Router /password-reset/confirm [post]
+func ConfirmPasswordReset(c *gin.Context) {
+	var req PasswordResetConfirmRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат. Укажите email, код и новый пароль."})
+		return
+	}
+
+	if req.NewPassword != req.ConfirmPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Пароли не совпадают"})
+		return
+	}
+
+	if valid, msg := validatePassword(req.NewPassword); !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	account, err := Accounts.GetByEmail(req.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный код или срок его действия истёк"})
+		return
+	}
+
+	attemptsLeft, err := PasswordResets.Consume(account.ID, req.Code)
+	switch {
+	case errors.Is(err, ErrResetCodeMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         fmt.Sprintf("Неверный код. Осталось попыток: %d", attemptsLeft),
+			"attempts_left": attemptsLeft,
+		})
+		return
+	case errors.Is(err, ErrResetTooManyTries):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Слишком много неверных попыток. Запросите новый код"})
+		return
+	case errors.Is(err, ErrResetCodeInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный код или срок его действия истёк"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки кода"})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании нового пароля"})
+		return
+	}
+
+	if err := Accounts.UpdatePassword(account.Nickname, string(hashedPassword)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка смены пароля"})
+		return
+	}
+
+	if _, err := Sessions.RevokeAll(account.ID, ""); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Ошибка завершения сессий после сброса пароля", "user", account.Nickname, "error", err)
+	}
+
+	utils.SetAuthCookie(c, "access_token", "", -1)
+	utils.SetAuthCookie(c, "refresh_token", "", -1)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Пароль изменен. Войдите с новым паролем"})
+}
+
+// PasswordResetService - коды для сброса забытого пароля
+type PasswordResetService struct {
+	resets repository.PasswordResetRepository
+	clock  clock.Clock
+}
+
+func NewPasswordResetService(resets repository.PasswordResetRepository, clk clock.Clock) *PasswordResetService {
+	return &PasswordResetService{resets: resets, clock: clk}
+}
+
+// Start заводит новый код сброса и возвращает его для письма. Новый запрос заменяет старый:
+// старый код и счетчик попыток больше не действуют. Пустой код без ошибки - прошлый
+// запрос был меньше PasswordResetCooldown назад, письмо не отправляем
+func (s *PasswordResetService) Start(accountID uint) (string, error) {
+	now := s.clock.Now()
+	existing, err := s.resets.Find(accountID)
+	if err == nil && now.Sub(existing.CreatedAt) < models.PasswordResetCooldown {
+		return "", nil
+	}
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return "", err
+	}
+
+	code := utils.GenerateVerificationCode()
+	codeHash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.resets.Replace(&models.PasswordReset{
+		AccountID: accountID,
+		CodeHash:  string(codeHash),
+		ExpiresAt: now.Add(models.PasswordResetLifetime),
+		CreatedAt: now,
+	}); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// Consume проверяет код и при успехе удаляет запрос на сброс.
+// При неверном коде возвращает, сколько попыток осталось.
+// После последней неудачной попытки запрос удаляется, нужен новый код
+func (s *PasswordResetService) Consume(accountID uint, code string) (int, error) {
+	reset, err := s.resets.Find(accountID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, ErrResetCodeInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !reset.ExpiresAt.After(s.clock.Now()) {
+		return 0, ErrResetCodeInvalid
+	}
+
+	// Попытка засчитывается до сравнения: параллельные запросы не проверят больше
+	// PasswordResetMaxAttempts кодов, даже если придут одновременно
+	attempts, err := s.resets.AddAttempt(reset.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, ErrResetCodeInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+	if attempts > models.PasswordResetMaxAttempts {
+		return 0, ErrResetTooManyTries
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(reset.CodeHash), []byte(code)) == nil {
+		// Удаление и есть использование кода: второй запрос с тем же кодом сюда уже не пройдет
+		if err := s.resets.Delete(reset.ID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return 0, ErrResetCodeInvalid
+			}
+			return 0, err
+		}
+		return 0, nil
+	}
+
+	attemptsLeft := models.PasswordResetMaxAttempts - attempts
+	if attemptsLeft > 0 {
+		return attemptsLeft, ErrResetCodeMismatch
+	}
+	if err := s.resets.Delete(reset.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return 0, err
+	}
+	return 0, ErrResetTooManyTries
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"errors"
+)
+
+// ReportService - жалобы пользователей на объявления и их разбор модераторами
+type ReportService struct {
+	reports repository.ReportRepository
+	ads     repository.AdRepository
+	clock   clock.Clock
+}
+
+func NewReportService(reports repository.ReportRepository, ads repository.AdRepository, clk clock.Clock) *ReportService {
+	return &ReportService{reports: reports, ads: ads, clock: clk}
+}
+
+// Create принимает жалобу на существующее объявление
+func (s *ReportService) Create(adID uint, reporterNickname string, reason string, description *string) error {
+	if _, err := s.ads.FindByID(adID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrAdNotFound
+		}
+		return err
+	}
+
+	return s.reports.Create(&models.Report{
+		AdID:             adID,
+		ReporterNickname: reporterNickname,
+		Reason:           reason,
+		Description:      description,
+	})
+}
+
+// ListByAd - все жалобы на объявление, сверху новые
+func (s *ReportService) ListByAd(adID uint) ([]models.Report, error) {
+	return s.reports.ListByAd(adID)
+}
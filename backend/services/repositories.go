@@ -0,0 +1,44 @@
+package services
+
+import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/mail"
+	"arizonagamesstore/backend/repository"
+)
+
+// Сервисы поверх репозиториев. Handlers обращаются к ним через эти переменные,
+// а собирает их Setup: в main - на PostgreSQL, в тестах - на репозиториях в памяти
+var (
+	Accounts   *AccountService
+	Sessions   *SessionService
+	Ads        *AdService
+	Feedback   *FeedbackService
+	Reputation *ReputationService
+	Reports    *ReportService
+	Mail       *MailService
+	Messages   *MessageService
+	Favorites  *FavoriteService
+	TwoFactor  *TwoFactorService
+
+	Notifications  *NotificationService
+	PasswordResets *PasswordResetService
+	EmailChanges   *EmailChangeService
+)
+
+// Setup создает сервисы из набора репозиториев. Вызывается один раз после подключения к БД.
+// clk - часы для сроков кодов, сессий, объявлений и затухания отзывов, sender доставляет письма из очереди
+func Setup(repos repository.Repositories, clk clock.Clock, sender mail.Sender) {
+	Accounts = NewAccountService(repos.Accounts, clk)
+	Sessions = NewSessionService(repos.Tokens, repos.Accounts, clk)
+	Ads = NewAdService(repos.Ads, clk)
+	Reputation = NewReputationService(repos.Reputation, repos.Accounts, clk)
+	Feedback = NewFeedbackService(repos.Feedback, repos.Ads, Reputation, clk)
+	Reports = NewReportService(repos.Reports, repos.Ads, clk)
+	Mail = NewMailService(repos.Outbox, sender, clk)
+	Messages = NewMessageService(repos.Messages, repos.Ads, repos.Reports, clk)
+	Favorites = NewFavoriteService(repos.Favorites)
+	TwoFactor = NewTwoFactorService(repos.TwoFactor, clk)
+	Notifications = NewNotificationService(repos.Notifications, clk)
+	PasswordResets = NewPasswordResetService(repos.PasswordResets, clk)
+	EmailChanges = NewEmailChangeService(repos.EmailChanges, clk)
+}
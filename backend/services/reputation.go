@@ -0,0 +1,241 @@
+package services
+
+import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+)
+
+var (
+	ErrSellerNotFound    = errors.New("seller not found")
+	ErrFeedbackConfirmed = errors.New("feedback already confirmed")
+)
+
+// Рейтинг продавца - не простое среднее оценок:
+//   - к отзывам подмешиваются reputationPriorWeight виртуальных оценок reputationPrior
+//     (байесовское среднее), так что одна пятерка не ставит новичка выше продавца с сотней сделок;
+//   - вес отзыва падает вдвое за каждые reputationHalfLife, свежие сделки говорят о продавце больше;
+//   - отзыв новичка весит reviewerMinWeight, отзыв опытного продавца с хорошим рейтингом - 1.
+//
+// Все это пересчитывается по одному отзыву в момент подтверждения, без перечитывания старых
+const (
+	reputationPrior       = 4.0
+	reputationPriorWeight = 3.0
+	reputationHalfLife    = 180 * 24 * time.Hour
+
+	reviewerMinWeight = 0.5
+	// С этого числа успешных сделок автор отзыва считается опытным
+	reviewerTrustedDeals = 10
+
+	// ReputationRecentPeriod - окно для раздела "за последние 30 дней"
+	ReputationRecentPeriod = 30 * 24 * time.Hour
+)
+
+// ReputationService - рейтинг продавцов и число их успешных сделок
+type ReputationService struct {
+	reputation repository.ReputationRepository
+	accounts   repository.AccountRepository
+	clock      clock.Clock
+}
+
+func NewReputationService(reputation repository.ReputationRepository, accounts repository.AccountRepository, clk clock.Clock) *ReputationService {
+	return &ReputationService{reputation: reputation, accounts: accounts, clock: clk}
+}
+
+// decayFactor - во сколько раз вес отзыва упал за age
+func decayFactor(age time.Duration) float64 {
+	if age <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(age)/float64(reputationHalfLife))
+}
+
+// reviewerWeight - вес отзыва по репутации автора: опыт (число сделок) умножается на качество (рейтинг)
+func reviewerWeight(review models.ReputationReview) float64 {
+	experience := math.Min(float64(review.ReviewerDeals)/reviewerTrustedDeals, 1)
+	quality := float64(review.ReviewerRating) / 5
+	return reviewerMinWeight + (1-reviewerMinWeight)*experience*quality
+}
+
+// decayTo переносит взвешенные суммы на момент now
+func decayTo(rep *models.SellerReputation, now time.Time) {
+	factor := decayFactor(now.Sub(rep.DecayedAt))
+	rep.WeightedSum *= factor
+	rep.WeightTotal *= factor
+	rep.DecayedAt = now
+}
+
+// bayesianRating - рейтинг на момент now с точностью до сотых. Без отзывов 0, как было всегда
+func bayesianRating(rep *models.SellerReputation, now time.Time) float64 {
+	if rep.Count() == 0 {
+		return 0
+	}
+	factor := decayFactor(now.Sub(rep.DecayedAt))
+	rating := (reputationPrior*reputationPriorWeight + rep.WeightedSum*factor) /
+		(reputationPriorWeight + rep.WeightTotal*factor)
+	return math.Round(rating*100) / 100
+}
+
+// addReviews - apply для репозитория: дописывает отзывы и обновляет рейтинг
+func addReviews(now time.Time) repository.ReputationApply {
+	return func(rep *models.SellerReputation, reviews []models.ReputationReview) error {
+		decayTo(rep, now)
+		for _, review := range reviews {
+			weight := reviewerWeight(review) * decayFactor(now.Sub(review.CreatedAt))
+			rep.WeightedSum += weight * float64(review.Rating)
+			rep.WeightTotal += weight
+			rep.AddStar(review.Rating)
+		}
+		rep.Rating = bayesianRating(rep, now)
+		return nil
+	}
+}
+
+// Confirm подтверждает отзыв и в той же транзакции добавляет его в репутацию продавца
+func (s *ReputationService) Confirm(feedback *models.FeedbackAd) error {
+	seller, err := s.accounts.FindByNickname(feedback.AdOwnerNickname)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrSellerNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	now := s.clock.Now()
+	err = s.reputation.ConfirmFeedback(feedback.ID, seller.ID, now, addReviews(now))
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrFeedbackConfirmed
+	}
+	if err != nil {
+		return err
+	}
+
+	feedback.ConfirmFeedback = true
+	feedback.ConfirmedAt = &now
+	return nil
+}
+
+// Rebuild собирает репутацию продавца заново по всем подтвержденным отзывам
+func (s *ReputationService) Rebuild(sellerID uint) error {
+	now := s.clock.Now()
+	add := addReviews(now)
+	return s.reputation.Rebuild(sellerID, func(rep *models.SellerReputation, reviews []models.ReputationReview) error {
+		*rep = models.SellerReputation{AccountID: rep.AccountID, DecayedAt: now}
+		return add(rep, reviews)
+	})
+}
+
+// Refresh - ежедневная задача. Собирает репутацию продавцам, чьи отзывы подтверждены
+// до ее появления, и пересчитывает остальным рейтинг на сегодня: без новых отзывов
+// старые продолжают терять вес, и рейтинг сползает к reputationPrior
+func (s *ReputationService) Refresh(ctx context.Context) error {
+	const batch = 200
+
+	for {
+		ids, err := s.reputation.Unbuilt(batch)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := s.Rebuild(id); err != nil {
+				return err
+			}
+		}
+		if len(ids) > 0 {
+			slog.InfoContext(ctx, "Собрана репутация продавцов", "count", len(ids))
+		}
+		if len(ids) < batch {
+			break
+		}
+	}
+
+	now := s.clock.Now()
+	var lastID uint
+	for ctx.Err() == nil {
+		ids, err := s.reputation.Sellers(lastID, batch)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			err := s.reputation.Update(id, func(rep *models.SellerReputation) error {
+				decayTo(rep, now)
+				rep.Rating = bayesianRating(rep, now)
+				return nil
+			})
+			if err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			lastID = id
+		}
+		if len(ids) < batch {
+			break
+		}
+	}
+	return ctx.Err()
+}
+
+// ReputationStars - отзывы продавца по числу звезд и их простое среднее
+type ReputationStars struct {
+	Count   int         `json:"count"`
+	Average float64     `json:"average"`
+	Stars   map[int]int `json:"stars"`
+}
+
+// ReputationBreakdown - из чего складывается рейтинг продавца
+type ReputationBreakdown struct {
+	Nickname            string          `json:"nickname"`
+	Rating              float64         `json:"rating"`
+	SuccessTransactions int             `json:"success_transactions"`
+	AllTime             ReputationStars `json:"all_time"`
+	Last30Days          ReputationStars `json:"last_30_days"`
+}
+
+func reputationStars(rep *models.SellerReputation) ReputationStars {
+	stars := ReputationStars{Count: rep.Count(), Stars: rep.Stars()}
+	if stars.Count > 0 {
+		stars.Average = math.Round(float64(rep.RatingSum)/float64(stars.Count)*100) / 100
+	}
+	return stars
+}
+
+// Breakdown - рейтинг продавца на сейчас и разбивка его отзывов по звездам
+func (s *ReputationService) Breakdown(nickname string) (*ReputationBreakdown, error) {
+	seller, err := s.accounts.FindByNickname(nickname)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrSellerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	rep, err := s.reputation.Find(seller.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		rep = &models.SellerReputation{AccountID: seller.ID, DecayedAt: now}
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Окно в 30 дней сдвигается каждый день, поэтому его не копим, а выбираем заново
+	reviews, err := s.reputation.Reviews(seller.ID, now.Add(-ReputationRecentPeriod))
+	if err != nil {
+		return nil, err
+	}
+	recent := models.SellerReputation{}
+	for _, review := range reviews {
+		recent.AddStar(review.Rating)
+	}
+
+	return &ReputationBreakdown{
+		Nickname:            seller.Nickname,
+		Rating:              bayesianRating(rep, now),
+		SuccessTransactions: rep.Count(),
+		AllTime:             reputationStars(rep),
+		Last30Days:          reputationStars(&recent),
+	}, nil
+}
@@ -0,0 +1,17 @@
+package services
+
+import (
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"html"
+	"strings"
+)
+
+type AdSearchResult = models.AdSearchResult
+
+// renderHighlight экранирует фрагмент и только потом превращает маркеры репозитория в <mark>
+func renderHighlight(fragment string) string {
+	escaped := html.EscapeString(fragment)
+	escaped = strings.ReplaceAll(escaped, repository.HighlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, repository.HighlightStop, "</mark>")
+}
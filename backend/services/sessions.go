@@ -0,0 +1,249 @@
+package services
+
+import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"arizonagamesstore/backend/utils"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token was already rotated")
+	errRefreshTokenRevoked = errors.New("refresh token family revoked")
+)
+
+const (
+	maxUserAgentLength       = 512
+	refreshTokenCookieMaxAge = int(models.RefreshTokenLifetime / time.Second)
+)
+
+// SessionResponse - сессия для списка устройств. ID - это семья токенов,
+// он не меняется при обновлении токена
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// RefreshResult - кому принадлежал refresh токен и что ему выдать взамен
+type RefreshResult struct {
+	AccountID    uint
+	Nickname     string
+	Role         string
+	RefreshToken string // пустой, если токен предъявлен повторно в пределах RefreshReuseGrace
+}
+
+// SessionService - сессии пользователя: выдача, ротация и отзыв refresh токенов
+type SessionService struct {
+	tokens   repository.TokenRepository
+	accounts repository.AccountRepository
+	clock    clock.Clock
+}
+
+func NewSessionService(tokens repository.TokenRepository, accounts repository.AccountRepository, clk clock.Clock) *SessionService {
+	return &SessionService{tokens: tokens, accounts: accounts, clock: clk}
+}
+
+// Start выпускает refresh токен новой семьи, запоминает устройство и ставит обе cookie.
+// Используется при входе и после подтверждения email
+func (s *SessionService) Start(c *gin.Context, account *models.Account, accessToken string) error {
+	refreshToken, err := utils.GenerateRefreshToken(account.ID, account.Nickname)
+	if err != nil {
+		return err
+	}
+
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	now := s.clock.Now()
+	tokenRecord := models.RefreshToken{
+		AccountID:  account.ID,
+		FamilyID:   uuid.NewString(),
+		Token:      refreshToken,
+		UserAgent:  userAgent,
+		IP:         c.ClientIP(),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(models.RefreshTokenLifetime),
+	}
+
+	if err := s.tokens.Create(&tokenRecord); err != nil {
+		return err
+	}
+
+	utils.SetAuthCookie(c, "access_token", accessToken, 180)
+	utils.SetAuthCookie(c, "refresh_token", refreshToken, refreshTokenCookieMaxAge)
+
+	return nil
+}
+
+// Rotate меняет refresh токен на новый из той же семьи, старый помечается замененным.
+// Если предъявлен уже замененный токен (позже RefreshReuseGrace), считаем что его украли:
+// вся семья отзывается и возвращается ErrRefreshTokenReused
+func (s *SessionService) Rotate(refreshToken string, ip string) (*RefreshResult, error) {
+	claims, err := utils.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	result := &RefreshResult{AccountID: claims.UserID, Nickname: claims.Nickname}
+	now := s.clock.Now()
+	var reused *models.RefreshToken
+
+	err = s.tokens.Rotate(refreshToken, claims.UserID, now, func(stored *models.RefreshToken) (*models.RefreshToken, error) {
+		if stored.RotatedAt != nil {
+			if now.Sub(*stored.RotatedAt) <= models.RefreshReuseGrace {
+				return nil, nil
+			}
+			reused = stored
+			return nil, errRefreshTokenRevoked
+		}
+
+		newToken, err := utils.GenerateRefreshToken(claims.UserID, claims.Nickname)
+		if err != nil {
+			return nil, err
+		}
+		result.RefreshToken = newToken
+
+		// CreatedAt переносим, чтобы в списке устройств было видно, когда начата сессия
+		return &models.RefreshToken{
+			AccountID:  stored.AccountID,
+			FamilyID:   stored.FamilyID,
+			Token:      newToken,
+			UserAgent:  stored.UserAgent,
+			IP:         ip,
+			LastUsedAt: now,
+			ExpiresAt:  now.Add(models.RefreshTokenLifetime),
+			CreatedAt:  stored.CreatedAt,
+		}, nil
+	})
+
+	// Семью отзываем уже вне ротации: ее транзакция на этой ошибке откатывается
+	if errors.Is(err, errRefreshTokenRevoked) {
+		if _, err := s.tokens.DeleteFamily(reused.AccountID, reused.FamilyID); err != nil {
+			return nil, err
+		}
+		slog.Warn("Повторное использование refresh токена, сессия отозвана", "account_id", reused.AccountID, "family_id", reused.FamilyID)
+		return nil, ErrRefreshTokenReused
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Роль берем из БД, а не из refresh токена: она могла поменяться за 30 дней
+	account, err := s.accounts.FindByID(claims.UserID)
+	if err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	result.Role = models.NormalizeRole(account.UserRole)
+
+	return result, nil
+}
+
+// SetRefreshedCookies ставит новый access токен и, если он был выдан, новый refresh токен
+func SetRefreshedCookies(c *gin.Context, accessToken string, result *RefreshResult) {
+	utils.SetAuthCookie(c, "access_token", accessToken, 180)
+	if result.RefreshToken != "" {
+		utils.SetAuthCookie(c, "refresh_token", result.RefreshToken, refreshTokenCookieMaxAge)
+	}
+}
+
+// List возвращает активные сессии пользователя, сверху последние использованные.
+// currentToken - refresh токен из cookie, чтобы пометить текущее устройство
+func (s *SessionService) List(accountID uint, currentToken string) ([]SessionResponse, error) {
+	currentFamily := s.familyByToken(currentToken)
+
+	tokens, err := s.tokens.ListActive(accountID, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]SessionResponse, 0, len(tokens))
+	for _, token := range tokens {
+		sessions = append(sessions, SessionResponse{
+			ID:         token.FamilyID,
+			UserAgent:  token.UserAgent,
+			IP:         token.IP,
+			CreatedAt:  token.CreatedAt,
+			LastUsedAt: token.LastUsedAt,
+			ExpiresAt:  token.ExpiresAt,
+			Current:    currentFamily != "" && token.FamilyID == currentFamily,
+		})
+	}
+
+	return sessions, nil
+}
+
+// Revoke завершает одну сессию пользователя (всю семью токенов).
+// Возвращает true, если закрыли сессию, к которой относится currentToken
+func (s *SessionService) Revoke(accountID uint, familyID string, currentToken string) (bool, error) {
+	currentFamily := s.familyByToken(currentToken)
+
+	deleted, err := s.tokens.DeleteFamily(accountID, familyID)
+	if err != nil {
+		return false, err
+	}
+	if deleted == 0 {
+		return false, ErrSessionNotFound
+	}
+
+	return currentFamily == familyID, nil
+}
+
+// RevokeByToken завершает сессию, к которой относится refresh токен. Используется при выходе
+func (s *SessionService) RevokeByToken(refreshToken string) error {
+	if refreshToken == "" {
+		return nil
+	}
+
+	token, err := s.tokens.FindByToken(refreshToken)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = s.tokens.DeleteFamily(token.AccountID, token.FamilyID)
+	return err
+}
+
+// RevokeAll завершает все сессии пользователя, кроме той, к которой относится exceptToken.
+// Возвращает, сколько активных сессий было закрыто
+func (s *SessionService) RevokeAll(accountID uint, exceptToken string) (int64, error) {
+	return s.tokens.DeleteAllExcept(accountID, s.familyByToken(exceptToken), s.clock.Now())
+}
+
+func (s *SessionService) familyByToken(refreshToken string) string {
+	if refreshToken == "" {
+		return ""
+	}
+
+	token, err := s.tokens.FindByToken(refreshToken)
+	if err != nil {
+		return ""
+	}
+	return token.FamilyID
+}
+
+// Prune удаляет истекшие refresh токены, в том числе давно замененные
+func (s *SessionService) Prune(ctx context.Context) (int64, error) {
+	return s.tokens.DeleteExpired(ctx, s.clock.Now())
+}
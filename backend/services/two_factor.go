@@ -0,0 +1,305 @@
+package services
+
+import (
+	"arizonagamesstore/backend/clock"
+	"arizonagamesstore/backend/models"
+	"arizonagamesstore/backend/repository"
+	"arizonagamesstore/backend/utils"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorSetupNotFound  = errors.New("two-factor setup was not started")
+	ErrTwoFactorCodeInvalid    = errors.New("two-factor code is invalid")
+	ErrTwoFactorChallengeSpent = errors.New("two-factor challenge has no attempts left")
+)
+
+// Без похожих символов (0/o, 1/l/i), чтобы код можно было переписать с бумажки
+const backupCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// TwoFactorSetup - то, что показываем пользователю при подключении 2FA
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TwoFactorStatus - включена ли 2FA и сколько резервных кодов еще не использовано
+type TwoFactorStatus struct {
+	Enabled         bool       `json:"enabled"`
+	EnabledAt       *time.Time `json:"enabled_at,omitempty"`
+	BackupCodesLeft int64      `json:"backup_codes_left"`
+	SetupInProgress bool       `json:"setup_in_progress"`
+}
+
+// TwoFactorService - подключение 2FA, проверка кодов при входе и резервные коды
+type TwoFactorService struct {
+	twoFactor repository.TwoFactorRepository
+	clock     clock.Clock
+}
+
+func NewTwoFactorService(twoFactor repository.TwoFactorRepository, clk clock.Clock) *TwoFactorService {
+	return &TwoFactorService{twoFactor: twoFactor, clock: clk}
+}
+
+// Enabled проверяет, нужен ли аккаунту второй фактор при входе
+func (s *TwoFactorService) Enabled(accountID uint) (bool, error) {
+	return s.twoFactor.Enabled(accountID)
+}
+
+// Status возвращает состояние 2FA для страницы настроек
+func (s *TwoFactorService) Status(accountID uint) (*TwoFactorStatus, error) {
+	status := &TwoFactorStatus{}
+
+	tf, err := s.twoFactor.Find(accountID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !tf.Enabled {
+		status.SetupInProgress = true
+		return status, nil
+	}
+
+	status.Enabled = true
+	status.EnabledAt = tf.EnabledAt
+	if status.BackupCodesLeft, err = s.twoFactor.CountBackupCodes(accountID); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// BeginSetup выдает новый секрет. 2FA включится только после ConfirmSetup,
+// повторный вызов до подтверждения просто заменяет секрет
+func (s *TwoFactorService) BeginSetup(account *models.Account) (*TwoFactorSetup, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactor.SaveSecret(account.ID, secret); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret: secret,
+		URI:    utils.TOTPProvisioningURI(secret, account.Nickname),
+	}, nil
+}
+
+// ConfirmSetup включает 2FA, если код из приложения подошел к выданному секрету.
+// Возвращает резервные коды в открытом виде - это единственный раз, когда их можно показать
+func (s *TwoFactorService) ConfirmSetup(accountID uint, code string) ([]string, error) {
+	tf, err := s.twoFactor.Find(accountID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrTwoFactorSetupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	now := s.clock.Now()
+	step, ok := utils.ValidateTOTP(tf.Secret, normalizeTwoFactorCode(code), now)
+	if !ok {
+		return nil, ErrTwoFactorCodeInvalid
+	}
+
+	backupCodes, records, err := newBackupCodes(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Секрет сверяется еще раз при записи: если в другой вкладке начали подключение заново,
+	// код проверен по старому секрету и не годится
+	if err := s.twoFactor.Enable(accountID, tf.Secret, step, now, records); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTwoFactorCodeInvalid
+		}
+		return nil, err
+	}
+
+	return backupCodes, nil
+}
+
+// Verify принимает либо 6 цифр из приложения, либо резервный код.
+// TOTP код из уже использованного окна не принимается, резервный код сгорает после входа
+func (s *TwoFactorService) Verify(accountID uint, code string) error {
+	code = normalizeTwoFactorCode(code)
+	if code == "" {
+		return ErrTwoFactorCodeInvalid
+	}
+
+	tf, err := s.twoFactor.Find(accountID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !tf.Enabled) {
+		return ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+
+	if isTOTPCode(code) {
+		step, ok := utils.ValidateTOTP(tf.Secret, code, s.clock.Now())
+		if !ok || step <= tf.LastUsedStep {
+			return ErrTwoFactorCodeInvalid
+		}
+		// Окно сравнивается еще раз при записи, так что один код не пройдет в двух параллельных входах
+		if err := s.twoFactor.UseStep(accountID, step); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrTwoFactorCodeInvalid
+			}
+			return err
+		}
+		return nil
+	}
+
+	unused, err := s.twoFactor.UnusedBackupCodes(accountID)
+	if err != nil {
+		return err
+	}
+
+	for _, backup := range unused {
+		if bcrypt.CompareHashAndPassword([]byte(backup.CodeHash), []byte(code)) != nil {
+			continue
+		}
+		if err := s.twoFactor.UseBackupCode(backup.ID, s.clock.Now()); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrTwoFactorCodeInvalid
+			}
+			return err
+		}
+		return nil
+	}
+	return ErrTwoFactorCodeInvalid
+}
+
+// VerifyChallenge проверяет код второго шага входа по challenge токену с этим jti.
+// Попытка засчитывается до проверки кода, так что параллельные запросы по одному токену
+// не проверят больше TwoFactorMaxAttempts кодов. При неверном коде возвращает, сколько попыток
+// осталось; на нуле токен сгорает (ErrTwoFactorChallengeSpent) и нужно заново войти с паролем
+func (s *TwoFactorService) VerifyChallenge(accountID uint, jti string, expiresAt time.Time, code string) (int, error) {
+	attempts, err := s.twoFactor.ReserveAttempt(models.TwoFactorChallenge{
+		JTI:       jti,
+		AccountID: accountID,
+		ExpiresAt: expiresAt,
+	}, s.clock.Now())
+	if err != nil {
+		return 0, err
+	}
+	if attempts > models.TwoFactorMaxAttempts {
+		return 0, ErrTwoFactorChallengeSpent
+	}
+
+	verifyErr := s.Verify(accountID, code)
+	if errors.Is(verifyErr, ErrTwoFactorCodeInvalid) {
+		attemptsLeft := models.TwoFactorMaxAttempts - attempts
+		if attemptsLeft == 0 {
+			return 0, ErrTwoFactorChallengeSpent
+		}
+		return attemptsLeft, verifyErr
+	}
+	if verifyErr != nil && !errors.Is(verifyErr, ErrTwoFactorNotEnabled) {
+		return 0, verifyErr
+	}
+
+	// Вход состоялся (или 2FA успели выключить), счетчик этого токена больше не нужен
+	if err := s.twoFactor.DeleteChallenge(jti); err != nil {
+		return 0, err
+	}
+	return 0, verifyErr
+}
+
+// Disable выключает 2FA и удаляет резервные коды. Пароль проверяет вызывающий
+func (s *TwoFactorService) Disable(accountID uint, code string) error {
+	if err := s.Verify(accountID, code); err != nil {
+		return err
+	}
+	return s.twoFactor.Delete(accountID)
+}
+
+// RegenerateBackupCodes выдает новый набор резервных кодов, старые перестают работать
+func (s *TwoFactorService) RegenerateBackupCodes(accountID uint, code string) ([]string, error) {
+	if err := s.Verify(accountID, code); err != nil {
+		return nil, err
+	}
+
+	backupCodes, records, err := newBackupCodes(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactor.ReplaceBackupCodes(accountID, records); err != nil {
+		return nil, err
+	}
+	return backupCodes, nil
+}
+
+// newBackupCodes возвращает резервные коды для показа и их хеши для хранения
+func newBackupCodes(accountID uint) ([]string, []models.TwoFactorBackupCode, error) {
+	codes := make([]string, 0, models.TwoFactorBackupCodeCount)
+	records := make([]models.TwoFactorBackupCode, 0, models.TwoFactorBackupCodeCount)
+	for i := 0; i < models.TwoFactorBackupCodeCount; i++ {
+		code, err := generateBackupCode()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(normalizeTwoFactorCode(code)), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		codes = append(codes, code)
+		records = append(records, models.TwoFactorBackupCode{AccountID: accountID, CodeHash: string(hash)})
+	}
+	return codes, records, nil
+}
+
+// generateBackupCode возвращает код вида "k7m2p-x9qrt"
+func generateBackupCode() (string, error) {
+	var sb strings.Builder
+	alphabetSize := big.NewInt(int64(len(backupCodeAlphabet)))
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			sb.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(backupCodeAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// normalizeTwoFactorCode убирает пробелы и дефисы: коды часто вставляют как "123 456" или "K7M2P-X9QRT"
+func normalizeTwoFactorCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"arizonagamesstore/backend/models"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var undoTokenPattern = regexp.MustCompile(`token=([0-9a-f]+)`)
+
+func TestEmailChangeUndoExpires(t *testing.T) {
+	tests := []struct {
+		name    string
+		advance time.Duration
+		want    int
+	}{
+		{"undo in time", models.EmailChangeUndoLifetime - time.Hour, http.StatusOK},
+		{"undo too late", models.EmailChangeUndoLifetime + time.Hour, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			c := srv.signUp(t, "Mover")
+			const oldEmail = "Mover@example.com"
+			const newEmail = "mover.new@example.com"
+
+			expectStatus(t, c.sendJSON(http.MethodPut, "/api/profile/update-email", gin.H{"email": newEmail}), http.StatusOK)
+			expectStatus(t, c.postJSON("/api/profile/confirm-email", gin.H{"code": srv.lastCode(t, newEmail)}), http.StatusOK)
+
+			srv.flushMail(t)
+			token := ""
+			for _, message := range srv.mail.Messages() {
+				if match := undoTokenPattern.FindStringSubmatch(message.Text); message.To == oldEmail && match != nil {
+					token = match[1]
+				}
+			}
+			if token == "" {
+				t.Fatal("no undo link was mailed to the old address")
+			}
+
+			srv.clock.Advance(tt.advance)
+			expectStatus(t, srv.newClient(t).do(http.MethodGet, "/api/email-change/undo?token="+token, nil, ""), tt.want)
+		})
+	}
+}
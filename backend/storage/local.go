@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Local хранит файлы на диске, раздаются они через router.Static
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocal(dir string, baseURL string) *Local {
+	return &Local{
+		dir:     dir,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// Dir - папка, которую нужно раздавать как статику
+func (l *Local) Dir() string {
+	return l.dir
+}
+
+func (l *Local) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	fullPath := filepath.Join(l.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return "", err
+	}
+
+	start := time.Now()
+	if err := writeFile(fullPath, body); err != nil {
+		observeUpload("local", start, err)
+		return "", err
+	}
+	observeUpload("local", start, nil)
+
+	return l.PublicURL(key), nil
+}
+
+func writeFile(fullPath string, body io.Reader) error {
+	file, err := os.Create(fullPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		os.Remove(fullPath)
+		return err
+	}
+
+	return file.Close()
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(l.dir, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (l *Local) PublicURL(key string) string {
+	return l.baseURL + "/" + strings.TrimPrefix(key, "/")
+}
+
+// Presign для локального диска просто отдает публичный URL: статика открыта всем
+func (l *Local) Presign(ctx context.Context, key string, expires time.Duration) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return l.PublicURL(key), nil
+}
+
+// Check убеждается, что папка с файлами существует. До первой загрузки ее может не быть,
+// это не ошибка: Put создаст ее сам
+func (l *Local) Check(ctx context.Context) error {
+	info, err := os.Stat(l.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", l.dir)
+	}
+	return nil
+}
+
+func (l *Local) KeyFromURL(url string) (string, bool) {
+	return keyFromPublicURL(l.baseURL, url)
+}
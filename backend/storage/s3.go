@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type S3Config struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+	Bucket    string
+	// Endpoint для S3-совместимых хранилищ (VK Cloud, MinIO). Пустой - AWS
+	Endpoint string
+	// PublicURL - откуда файлы раздаются наружу. По умолчанию https://<bucket>.hb.vkcloud-storage.ru
+	PublicURL string
+}
+
+// S3 хранит файлы в S3-совместимом хранилище. Клиент создается один раз
+type S3 struct {
+	client    *s3.Client
+	presigner *s3.PresignClient
+	bucket    string
+	publicURL string
+}
+
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.AccessKey == "" || cfg.SecretKey == "" || cfg.Region == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("AWS credentials or bucket name not set in environment variables")
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(context.Background(),
+		config.WithRegion(cfg.Region),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, "")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SDK config, %v", err)
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+		o.UsePathStyle = true
+	})
+
+	publicURL := cfg.PublicURL
+	if publicURL == "" {
+		publicURL = fmt.Sprintf("https://%s.hb.vkcloud-storage.ru", cfg.Bucket)
+	}
+
+	return &S3{
+		client:    client,
+		presigner: s3.NewPresignClient(client),
+		bucket:    cfg.Bucket,
+		publicURL: strings.TrimRight(publicURL, "/"),
+	}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   body,
+		ACL:    "public-read",
+	}
+	if size >= 0 {
+		input.ContentLength = aws.Int64(size)
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	start := time.Now()
+	_, err = s.client.PutObject(ctx, input)
+	observeUpload("s3", start, err)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file, %v", err)
+	}
+
+	return s.PublicURL(key), nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete file from S3: %v", err)
+	}
+	return nil
+}
+
+func (s *S3) PublicURL(key string) string {
+	return s.publicURL + "/" + strings.TrimPrefix(key, "/")
+}
+
+func (s *S3) Presign(ctx context.Context, key string, expires time.Duration) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	request, err := s.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign file, %v", err)
+	}
+	return request.URL, nil
+}
+
+// Check проверяет, что бакет доступен с нашими ключами
+func (s *S3) Check(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.bucket)})
+	if err != nil {
+		return fmt.Errorf("bucket %s is not reachable: %v", s.bucket, err)
+	}
+	return nil
+}
+
+func (s *S3) KeyFromURL(url string) (string, bool) {
+	return keyFromPublicURL(s.publicURL, url)
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"arizonagamesstore/backend/config"
+	"arizonagamesstore/backend/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Backend - хранилище файлов (картинки объявлений, отзывов, аватары).
+// Ключ - относительный путь вида "avatars/nick/uuid.png", в БД хранится PublicURL
+type Backend interface {
+	// Put сохраняет файл и возвращает его публичный URL
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error)
+	Delete(ctx context.Context, key string) error
+	PublicURL(key string) string
+	// Presign возвращает временную ссылку на файл
+	Presign(ctx context.Context, key string, expires time.Duration) (string, error)
+	// KeyFromURL достает ключ из URL, который выдал этот же бэкенд.
+	// Для чужих URL (дефолтный аватар и т.п.) возвращает false
+	KeyFromURL(url string) (string, bool)
+}
+
+// Checker - необязательная проверка доступности хранилища для /readyz
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// Files - хранилище, выбранное в конфигурации. Инициализируется в Init
+var Files Backend
+
+// Init создает хранилище по конфигурации (STORAGE_BACKEND: local или s3)
+func Init(cfg config.StorageConfig) {
+	backend, err := New(cfg)
+	if err != nil {
+		slog.Error("Не удалось подключить хранилище файлов", "backend", cfg.Backend, "error", err)
+		os.Exit(1)
+	}
+	Files = backend
+}
+
+func New(cfg config.StorageConfig) (Backend, error) {
+	switch cfg.Backend {
+	case "local":
+		slog.Info("Файлы хранятся на диске", "dir", cfg.LocalDir)
+		return NewLocal(cfg.LocalDir, cfg.PublicURL), nil
+	case "s3":
+		backend, err := NewS3(S3Config{
+			AccessKey: cfg.S3.AccessKeyID,
+			SecretKey: cfg.S3.SecretAccessKey,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			Endpoint:  cfg.S3.Endpoint,
+			PublicURL: cfg.PublicURL,
+		})
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("Файлы хранятся в S3", "bucket", cfg.S3.Bucket)
+		return backend, nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", cfg.Backend)
+	}
+}
+
+// CleanKey проверяет ключ: без абсолютных путей и выходов через ".."
+func CleanKey(key string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(key, "/"))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
+
+// keyFromPublicURL - общая часть KeyFromURL: URL должен начинаться с baseURL
+func keyFromPublicURL(baseURL string, url string) (string, bool) {
+	prefix := strings.TrimRight(baseURL, "/") + "/"
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	key, err := CleanKey(strings.TrimPrefix(url, prefix))
+	if err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+// observeUpload пишет длительность загрузки в /metrics, отдельно удачные и упавшие
+func observeUpload(backend string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	metrics.ObserveSince(metrics.StorageUploadDuration.WithLabelValues(backend, result), start)
+}
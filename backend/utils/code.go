@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+func GenerateVerificationCode() string {
+	const chars = "0123456789"
+	code := make([]byte, 6)
+	for i := range code {
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+		code[i] = chars[n.Int64()]
+	}
+	return string(code)
+}
@@ -0,0 +1,17 @@
+package utils
+
+import "arizonagamesstore/backend/config"
+
+var (
+	jwtConfig       config.JWTConfig
+	recaptchaConfig config.RecaptchaConfig
+	secureCookies   bool
+)
+
+// Init передает утилитам их часть конфигурации. Вызывается один раз при старте,
+// до того как сервер начнет принимать запросы
+func Init(cfg *config.Config) {
+	jwtConfig = cfg.JWT
+	recaptchaConfig = cfg.Recaptcha
+	secureCookies = cfg.Server.SecureCookies != nil && *cfg.Server.SecureCookies
+}
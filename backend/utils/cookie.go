@@ -14,7 +14,7 @@ func SetAuthCookie(c *gin.Context, name, value string, maxAge int) {
 		Path:     "/",
 		Domain:   "",
 		SameSite: http.SameSiteLaxMode,
-		Secure:   false,
+		Secure:   secureCookies,
 		HttpOnly: true,
 	}
 	http.SetCookie(c.Writer, cookie)
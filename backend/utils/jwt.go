@@ -1,27 +1,26 @@
 package utils
 
 import (
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Nickname string `json:"nickname"`
+	Role     string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateAccessToken(userID uint, nickname string) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key-change-in-production"
-	}
+func GenerateAccessToken(userID uint, nickname string, role string) (string, error) {
+	secret := jwtConfig.Secret
 
 	claims := Claims{
 		UserID:   userID,
 		Nickname: nickname,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(3 * time.Minute)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -33,15 +32,14 @@ func GenerateAccessToken(userID uint, nickname string) (string, error) {
 }
 
 func GenerateRefreshToken(userID uint, nickname string) (string, error) {
-	secret := os.Getenv("JWT_REFRESH_SECRET")
-	if secret == "" {
-		secret = "your-refresh-secret-key-change-in-production"
-	}
+	secret := jwtConfig.RefreshSecret
 
 	claims := Claims{
 		UserID:   userID,
 		Nickname: nickname,
 		RegisteredClaims: jwt.RegisteredClaims{
+			// jti нужен, чтобы два токена, выданные в одну секунду, не совпали
+			ID:        uuid.NewString(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(30 * 24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
@@ -52,10 +50,7 @@ func GenerateRefreshToken(userID uint, nickname string) (string, error) {
 }
 
 func ValidateAccessToken(tokenString string) (*Claims, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key-change-in-production"
-	}
+	secret := jwtConfig.Secret
 
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secret), nil
@@ -73,10 +68,7 @@ func ValidateAccessToken(tokenString string) (*Claims, error) {
 }
 
 func ValidateRefreshToken(tokenString string) (*Claims, error) {
-	secret := os.Getenv("JWT_REFRESH_SECRET")
-	if secret == "" {
-		secret = "your-refresh-secret-key-change-in-production"
-	}
+	secret := jwtConfig.RefreshSecret
 
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secret), nil
@@ -92,3 +84,48 @@ func ValidateRefreshToken(tokenString string) (*Claims, error) {
 
 	return nil, jwt.ErrSignatureInvalid
 }
+
+// TwoFactorClaims - токен промежуточного шага входа: пароль уже проверен, ждем код 2FA
+type TwoFactorClaims struct {
+	UserID uint `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+const twoFactorAudience = "2fa_challenge"
+
+// Ключ отличается от ключа access токенов, иначе challenge токен можно было бы
+// подсунуть вместо access токена и пропустить второй фактор
+func twoFactorSecret() []byte {
+	return []byte(jwtConfig.Secret + ":" + twoFactorAudience)
+}
+
+func GenerateTwoFactorChallenge(userID uint, lifetime time.Duration) (string, error) {
+	claims := TwoFactorClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Audience:  jwt.ClaimStrings{twoFactorAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(lifetime)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(twoFactorSecret())
+}
+
+func ValidateTwoFactorChallenge(tokenString string) (*TwoFactorClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &TwoFactorClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return twoFactorSecret(), nil
+	}, jwt.WithAudience(twoFactorAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*TwoFactorClaims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, jwt.ErrSignatureInvalid
+}
@@ -6,7 +6,6 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 )
 
 type RecaptchaResponse struct {
@@ -19,7 +18,7 @@ type RecaptchaResponse struct {
 }
 
 func VerifyRecaptcha(token string) (bool, float64, error) {
-	secretKey := os.Getenv("RECAPTCHA_SECRET_KEY")
+	secretKey := recaptchaConfig.SecretKey
 	if secretKey == "" {
 		return true, 1.0, nil
 	}
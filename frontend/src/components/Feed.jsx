@@ -14,7 +14,7 @@ function Feed() {
 
   const [hotAds, setHotAds] = useState([]);
   const [hotAdsLoading, setHotAdsLoading] = useState(false);
-  const [hotAdsOffset, setHotAdsOffset] = useState(0);
+  const [hotAdsCursor, setHotAdsCursor] = useState('');
   const [hasMoreHotAds, setHasMoreHotAds] = useState(true);
   const hotSectionRef = useRef(null);
 
@@ -74,25 +74,26 @@ function Feed() {
   }, []);
 
 
-  const fetchHotAds = async (offset = 0) => {
-    if (hotAdsLoading || (!hasMoreHotAds && offset > 0)) return;
+  const fetchHotAds = async (cursor = '') => {
+    if (hotAdsLoading || (!hasMoreHotAds && cursor)) return;
 
     setHotAdsLoading(true);
     try {
-      const response = await fetch(`http://localhost:8080/api/ads/random?limit=15&offset=${offset}`);
+      const response = await fetch(`http://localhost:8080/api/ads/random?limit=15${cursor ? `&cursor=${encodeURIComponent(cursor)}` : ''}`);
       if (!response.ok) throw new Error('Ошибка загрузки объявлений');
 
       const data = await response.json();
       const newAds = data.ads || [];
 
-      if (offset === 0) {
+      if (!cursor) {
         setHotAds(newAds);
       } else {
         setHotAds(prev => [...prev, ...newAds]);
       }
 
-      setHotAdsOffset(offset + 15);
-      setHasMoreHotAds(newAds.length === 15);
+      const nextCursor = data.page?.next_cursor || '';
+      setHotAdsCursor(nextCursor);
+      setHasMoreHotAds(Boolean(nextCursor));
     } catch (error) {
       console.error('Ошибка загрузки горячих объявлений:', error);
     } finally {
@@ -102,7 +103,7 @@ function Feed() {
 
 
   useEffect(() => {
-    fetchHotAds(0);
+    fetchHotAds();
   }, []);
 
 
@@ -113,7 +114,7 @@ function Feed() {
       (entries) => {
         const lastEntry = entries[0];
         if (lastEntry.isIntersecting && hasMoreHotAds && !hotAdsLoading) {
-          fetchHotAds(hotAdsOffset);
+          fetchHotAds(hotAdsCursor);
         }
       },
       { threshold: 0.5 }
@@ -126,7 +127,7 @@ function Feed() {
     }
 
     return () => observer.disconnect();
-  }, [hotAds, hasMoreHotAds, hotAdsLoading, hotAdsOffset]);
+  }, [hotAds, hasMoreHotAds, hotAdsLoading, hotAdsCursor]);
 
   const handleLogout = async () => {
     await logout();
@@ -179,12 +179,33 @@ function Settings() {
 
       const data = await response.json();
 
-      if (response.ok) {
+      if (!response.ok) {
+        setToast({ message: data.error || 'Ошибка при обновлении email', type: 'error' });
+        return;
+      }
+
+      // Email поменяется только после кода, который пришел на новый адрес
+      const code = window.prompt(`Введите код, отправленный на ${email}`);
+      if (!code) {
+        setToast({ message: 'Смена email не подтверждена', type: 'error' });
+        return;
+      }
+
+      const confirmResponse = await fetch('http://localhost:8080/api/profile/confirm-email', {
+        method: 'POST',
+        credentials: 'include',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ code: code.trim() }),
+      });
+
+      const confirmData = await confirmResponse.json();
+
+      if (confirmResponse.ok) {
         setToast({ message: 'Email успешно обновлен!', type: 'success' });
         setIsEditingEmail(false);
         setTimeout(() => window.location.reload(), 1500);
       } else {
-        setToast({ message: data.error || 'Ошибка при обновлении email', type: 'error' });
+        setToast({ message: confirmData.error || 'Ошибка при подтверждении email', type: 'error' });
       }
     } catch (error) {
       console.error('Ошибка обновления email:', error);
@@ -73,6 +73,33 @@ export const AuthProvider = ({ children }) => {
 
     if (response.ok) {
       const data = await response.json();
+
+      // Включена 2FA: cookies появятся только после кода
+      if (data.status === '2fa_required') {
+        const code = window.prompt('Введите код из приложения-аутентификатора или резервный код');
+        if (!code) {
+          return { success: false, error: 'Вход не завершен: нужен код 2FA' };
+        }
+
+        const twoFactorResponse = await fetch('http://localhost:8080/api/login/2fa', {
+          method: 'POST',
+          headers: {
+            'Content-Type': 'application/json',
+          },
+          credentials: 'include',
+          body: JSON.stringify({ challenge_token: data.challenge_token, code: code.trim(), client_ip: clientIP }),
+        });
+
+        const twoFactorData = await twoFactorResponse.json();
+        if (!twoFactorResponse.ok) {
+          return { success: false, error: twoFactorData.error || 'Неверный код' };
+        }
+
+        clearVerificationFlow();
+        await checkAuth();
+        return { success: true, data: twoFactorData };
+      }
+
       clearVerificationFlow();
 
       await checkAuth();